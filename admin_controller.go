@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+)
+
+const attributeQueryPrefix = "attr."
+
+type AdminController struct {
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo           user.UserRepository
+	userService        *user.UserService
+	profileService     *user.ProfileService
+	authService        *auth.LoginService
+	mfaService         *mfa.MfaService
+	auditRepo          audit.AuditRepository
+}
+
+type adminUserResponse struct {
+	*profileResponse
+	Status                string     `json:"status"`
+	SuspensionReason      string     `json:"suspensionReason,omitempty"`
+	SuspendedUntil        *time.Time `json:"suspendedUntil,omitempty"`
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+}
+
+type auditEventResponse struct {
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	Outcome    string    `json:"outcome"`
+	Detail     string    `json:"detail,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+func (controller *AdminController) Init(
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	userRepo user.UserRepository,
+	userService *user.UserService,
+	profileService *user.ProfileService,
+	authService *auth.LoginService,
+	mfaService *mfa.MfaService,
+	auditRepo audit.AuditRepository,
+) {
+	controller.accessTokenService = accessTokenService
+	controller.userRepo = userRepo
+	controller.userService = userService
+	controller.profileService = profileService
+	controller.authService = authService
+	controller.mfaService = mfaService
+	controller.auditRepo = auditRepo
+}
+
+func (controller *AdminController) ListUsers(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	query, err := parseUserQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var page *user.Page
+	if text := c.Query("q"); text != "" {
+		page, err = controller.userRepo.Search(c.Request.Context(), text, query)
+	} else {
+		page, err = controller.userRepo.List(c.Request.Context(), query)
+	}
+	if controller.record(c, admin, "user.list", "", err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	users := make([]*adminUserResponse, len(page.Users))
+	for i, found := range page.Users {
+		users[i] = newAdminUserResponse(found)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":      users,
+		"nextCursor": page.NextCursor,
+	})
+}
+
+func (controller *AdminController) GetUser(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	identifier := c.Param("identifier")
+	found, err := controller.userRepo.FindByIdentifier(c.Request.Context(), identifier)
+	if controller.record(c, admin, "user.read", identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAdminUserResponse(found))
+}
+
+func (controller *AdminController) CreateUser(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	var request struct {
+		Identifier string `json:"identifier"`
+		Passkey    string `json:"passkey"`
+		Status     string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Identifier == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "identifier and passkey required",
+		})
+		return
+	}
+
+	newUser := &user.User{
+		Identifier: request.Identifier,
+		Passkey:    request.Passkey,
+		Status:     user.Active,
+	}
+
+	if request.Status != "" {
+		status, err := user.ParseStatus(request.Status)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		newUser.Status = status
+	}
+
+	err := controller.authService.Register(c.Request.Context(), newUser)
+	if controller.record(c, admin, "user.create", request.Identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newAdminUserResponse(newUser))
+}
+
+func (controller *AdminController) UpdateUser(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	update := new(user.ProfileUpdate)
+	if err := c.ShouldBindJSON(update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid profile update",
+		})
+		return
+	}
+
+	identifier := c.Param("identifier")
+	updated, err := controller.profileService.UpdateProfile(c.Request.Context(), identifier, update)
+	if controller.record(c, admin, "user.update", identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAdminUserResponse(updated))
+}
+
+func (controller *AdminController) ActivateUser(c *gin.Context) {
+	controller.perform(c, "user.activate", func(ctx context.Context, identifier string) error {
+		return controller.userService.Activate(ctx, identifier)
+	})
+}
+
+func (controller *AdminController) SuspendUser(c *gin.Context) {
+	var request struct {
+		Reason string    `json:"reason"`
+		Until  time.Time `json:"until"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid suspension",
+		})
+		return
+	}
+
+	controller.perform(c, "user.suspend", func(ctx context.Context, identifier string) error {
+		return controller.userService.Suspend(ctx, identifier, request.Reason, request.Until)
+	})
+}
+
+func (controller *AdminController) DeleteUser(c *gin.Context) {
+	controller.perform(c, "user.delete", func(ctx context.Context, identifier string) error {
+		return controller.userService.Delete(ctx, identifier)
+	})
+}
+
+func (controller *AdminController) ForcePasswordReset(c *gin.Context) {
+	controller.perform(c, "user.force_password_reset", func(ctx context.Context, identifier string) error {
+		return controller.userService.RequirePasswordReset(ctx, identifier)
+	})
+}
+
+func (controller *AdminController) RevokeSessions(c *gin.Context) {
+	controller.perform(c, "user.revoke_sessions", func(ctx context.Context, identifier string) error {
+		return controller.userService.RevokeSessions(ctx, identifier)
+	})
+}
+
+func (controller *AdminController) ResetMfa(c *gin.Context) {
+	controller.perform(c, "user.reset_mfa", func(ctx context.Context, identifier string) error {
+		if _, err := controller.userRepo.FindByIdentifier(ctx, identifier); err != nil {
+			return err
+		}
+		return controller.mfaService.Reset(identifier)
+	})
+}
+
+func (controller *AdminController) AuditTrail(c *gin.Context) {
+	if _, shouldReturn := authenticateAdmin(c, controller.accessTokenService); shouldReturn {
+		return
+	}
+
+	events, err := controller.auditRepo.FindByTarget(c.Request.Context(), c.Param("identifier"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "audit trail could not be loaded",
+		})
+		return
+	}
+
+	response := make([]*auditEventResponse, len(events))
+	for i, event := range events {
+		response[i] = &auditEventResponse{
+			Actor:      event.Actor,
+			Action:     event.Action,
+			Target:     event.Target,
+			Outcome:    string(event.Outcome),
+			Detail:     event.Detail,
+			OccurredAt: event.OccurredAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": response,
+	})
+}
+
+func (controller *AdminController) perform(c *gin.Context, action string, act func(ctx context.Context, identifier string) error) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	identifier := c.Param("identifier")
+	err := act(c.Request.Context(), identifier)
+	if controller.record(c, admin, action, identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (controller *AdminController) record(c *gin.Context, admin *auth.RefreshTokenPayload, action string, target string, err error) bool {
+	return recordAudit(c, controller.auditRepo, admin, action, target, err)
+}
+
+func recordAudit(c *gin.Context, auditRepo audit.AuditRepository, admin *auth.RefreshTokenPayload, action string, target string, err error) bool {
+	event := &audit.Event{
+		Actor:   admin.Sub,
+		Action:  action,
+		Target:  target,
+		Outcome: audit.Success,
+	}
+
+	if err != nil {
+		event.Outcome = audit.Failure
+		event.Detail = err.Error()
+	}
+
+	if err := auditRepo.Record(c.Request.Context(), event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "audit event could not be recorded",
+		})
+		return true
+	}
+	return false
+}
+
+func authenticateAdmin(c *gin.Context, tokenService auth.TokenService[*auth.RefreshTokenPayload]) (*auth.RefreshTokenPayload, bool) {
+	payload, shouldReturn := authenticateBearer(c, tokenService)
+	if shouldReturn {
+		return nil, true
+	}
+
+	if !payload.HasScope(auth.AdminScope) {
+		respondProblem(c, http.StatusForbidden, auth.ErrForbidden)
+		return nil, true
+	}
+
+	return payload, false
+}
+
+func parseUserQuery(c *gin.Context) (*user.Query, error) {
+	query := new(user.Query)
+
+	if value := c.Query("status"); value != "" {
+		status, err := user.ParseStatus(value)
+		if err != nil {
+			return nil, err
+		}
+		query.Status = status
+	}
+
+	for param, target := range map[string]*time.Time{
+		"createdAfter":  &query.CreatedAfter,
+		"createdBefore": &query.CreatedBefore,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errInvalidParam(param)
+		}
+		*target = parsed
+	}
+
+	for param, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(param, attributeQueryPrefix) || len(values) == 0 {
+			continue
+		}
+
+		if query.Attributes == nil {
+			query.Attributes = make(map[string]string)
+		}
+		query.Attributes[strings.TrimPrefix(param, attributeQueryPrefix)] = values[0]
+	}
+
+	switch c.DefaultQuery("sort", "identifier") {
+	case "identifier":
+		query.SortBy = user.SortByIdentifier
+	case "createdAt":
+		query.SortBy = user.SortByCreatedAt
+	default:
+		return nil, errInvalidParam("sort")
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return nil, errInvalidParam("order")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, errInvalidParam("limit")
+		}
+		query.Limit = limit
+	}
+
+	query.Cursor = c.Query("cursor")
+	return query, nil
+}
+
+func errInvalidParam(param string) error {
+	return fmt.Errorf("invalid query parameter %s", param)
+}
+
+func newAdminUserResponse(found *user.User) *adminUserResponse {
+	response := &adminUserResponse{
+		profileResponse:       newProfileResponse(found),
+		Status:                string(found.Status),
+		SuspensionReason:      found.SuspensionReason,
+		PasswordResetRequired: found.PasswordResetRequired,
+	}
+
+	if !found.SuspendedUntil.IsZero() {
+		response.SuspendedUntil = &found.SuspendedUntil
+	}
+	return response
+}
@@ -0,0 +1,411 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AdminControllerSuite struct {
+	suite.Suite
+
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo           user.UserRepository
+	mfaService         *mfa.MfaService
+	auditRepo          audit.AuditRepository
+	controller         *AdminController
+}
+
+type userListResponse struct {
+	Users []struct {
+		Identifier string `json:"identifier"`
+		Status     string `json:"status"`
+	} `json:"users"`
+	NextCursor string `json:"nextCursor"`
+}
+
+func (suite *AdminControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := new(user.MemoryUserRepository)
+	suite.userRepo = userRepo
+	userRepo.Create(context.Background(), &user.User{Identifier: "alice", Status: user.Active, Email: "alice@example.com", Attributes: map[string]interface{}{"department": "sales"}})
+	userRepo.Create(context.Background(), &user.User{Identifier: "bob", Status: user.Active, Email: "bob@example.com", Attributes: map[string]interface{}{"department": "it"}})
+	userRepo.Create(context.Background(), &user.User{Identifier: "carol", Status: user.Locked, Email: "carol@example.com"})
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	accessTokenService := new(auth.RefreshTokenService)
+	accessTokenService.Init(jwtService)
+	suite.accessTokenService = accessTokenService
+
+	userService := new(user.UserService)
+	userService.Init(userRepo)
+	profileService := new(user.ProfileService)
+	profileService.Init(userRepo, user.AttributeSchema{
+		"department": {Type: user.StringAttribute},
+	})
+	authService := new(auth.LoginService)
+	authService.Init(userRepo, auth.NewArgon2Encrypter())
+
+	otpService := new(totp.OtpService)
+	otpService.Init(30)
+	suite.mfaService = new(mfa.MfaService)
+	suite.mfaService.Init(new(mfa.MemoryFactorRepository), new(mfa.MemoryRecoveryCodeRepository), otpService, noopSender{})
+	suite.mfaService.Enroll(&mfa.Factor{
+		Identifier: "totp",
+		UserId:     "bob",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("secret"),
+		},
+	})
+
+	suite.auditRepo = new(audit.MemoryAuditRepository)
+
+	suite.controller = new(AdminController)
+	suite.controller.Init(accessTokenService, userRepo, userService, profileService, authService, suite.mfaService, suite.auditRepo)
+}
+
+func (suite *AdminControllerSuite) authorize(context *gin.Context, scope ...string) {
+	token, _ := suite.accessTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: "admin", Scope: scope})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *AdminControllerSuite) adminContext(identifier string, body string) (*httptest.ResponseRecorder, *gin.Context) {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{}
+	context.Request.Body = io.NopCloser(strings.NewReader(body))
+	context.Params = gin.Params{{Key: "identifier", Value: identifier}}
+	suite.authorize(context, auth.AdminScope)
+	return w, context
+}
+
+func (suite *AdminControllerSuite) find(identifier string) *user.User {
+	found, err := suite.userRepo.FindByIdentifier(context.Background(), identifier)
+	assert.Nil(suite.T(), err)
+	return found
+}
+
+func (suite *AdminControllerSuite) actions(target string) []string {
+	events, _ := suite.auditRepo.FindByTarget(context.Background(), target)
+	result := []string{}
+	for _, event := range events {
+		result = append(result, event.Action+":"+string(event.Outcome))
+	}
+	return result
+}
+
+func (suite *AdminControllerSuite) listUsers(query string) (int, *userListResponse) {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{RawQuery: query}
+	suite.authorize(context, auth.AdminScope)
+
+	suite.controller.ListUsers(context)
+
+	response := new(userListResponse)
+	json.NewDecoder(w.Result().Body).Decode(response)
+	return w.Result().StatusCode, response
+}
+
+func identifiers(response *userListResponse) []string {
+	result := []string{}
+	for _, found := range response.Users {
+		result = append(result, found.Identifier)
+	}
+	return result
+}
+
+func (suite *AdminControllerSuite) TestListUsers_ListAllUsers() {
+	status, response := suite.listUsers("")
+
+	assert.Equal(suite.T(), 200, status)
+	assert.Equal(suite.T(), []string{"alice", "bob", "carol"}, identifiers(response))
+	assert.Equal(suite.T(), "locked", response.Users[2].Status)
+	assert.Empty(suite.T(), response.NextCursor)
+}
+
+func (suite *AdminControllerSuite) TestListUsers_PaginateWithCursor() {
+	status, first := suite.listUsers("limit=2&order=desc")
+
+	assert.Equal(suite.T(), 200, status)
+	assert.Equal(suite.T(), []string{"carol", "bob"}, identifiers(first))
+	assert.NotEmpty(suite.T(), first.NextCursor)
+
+	status, second := suite.listUsers("limit=2&order=desc&cursor=" + first.NextCursor)
+
+	assert.Equal(suite.T(), 200, status)
+	assert.Equal(suite.T(), []string{"alice"}, identifiers(second))
+	assert.Empty(suite.T(), second.NextCursor)
+}
+
+func (suite *AdminControllerSuite) TestListUsers_FilterByStatusAndAttribute() {
+	_, byStatus := suite.listUsers("status=active")
+	_, byAttribute := suite.listUsers("attr.department=it")
+
+	assert.Equal(suite.T(), []string{"alice", "bob"}, identifiers(byStatus))
+	assert.Equal(suite.T(), []string{"bob"}, identifiers(byAttribute))
+}
+
+func (suite *AdminControllerSuite) TestListUsers_SearchByText() {
+	status, response := suite.listUsers("q=CAROL@example")
+
+	assert.Equal(suite.T(), 200, status)
+	assert.Equal(suite.T(), []string{"carol"}, identifiers(response))
+}
+
+func (suite *AdminControllerSuite) TestListUsers_FailWithInvalidParameter() {
+	for _, query := range []string{"status=unknown", "sort=email", "order=up", "limit=-1", "createdAfter=yesterday"} {
+		status, _ := suite.listUsers(query)
+
+		assert.Equal(suite.T(), 400, status, query)
+	}
+}
+
+func (suite *AdminControllerSuite) TestListUsers_FailWithInvalidCursor() {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{RawQuery: "cursor=invalid"}
+	suite.authorize(context, auth.AdminScope)
+
+	suite.controller.ListUsers(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	assert.Equal(suite.T(), ProblemContentType, w.Result().Header.Get("Content-Type"))
+}
+
+func (suite *AdminControllerSuite) TestListUsers_ForbiddenWithoutAdminScope() {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{}
+	suite.authorize(context)
+
+	suite.controller.ListUsers(context)
+
+	assert.Equal(suite.T(), 403, w.Result().StatusCode)
+	var problem Problem
+	json.NewDecoder(w.Result().Body).Decode(&problem)
+	assert.Equal(suite.T(), "/problems/forbidden", problem.Type)
+}
+
+func (suite *AdminControllerSuite) TestListUsers_FailWithoutBearerToken() {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{}
+
+	suite.controller.ListUsers(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *AdminControllerSuite) TestGetUser_ReturnUserWithStatus() {
+	w, context := suite.adminContext("carol", "")
+
+	suite.controller.GetUser(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	assert.Equal(suite.T(), "carol", body["identifier"])
+	assert.Equal(suite.T(), "locked", body["status"])
+	assert.NotContains(suite.T(), body, "passkey")
+	assert.Equal(suite.T(), []string{"user.read:success"}, suite.actions("carol"))
+}
+
+func (suite *AdminControllerSuite) TestGetUser_NotFoundForUnknownUser() {
+	w, context := suite.adminContext("unknown", "")
+
+	suite.controller.GetUser(context)
+
+	assert.Equal(suite.T(), 404, w.Result().StatusCode)
+	assert.Equal(suite.T(), []string{"user.read:failure"}, suite.actions("unknown"))
+}
+
+func (suite *AdminControllerSuite) TestCreateUser_CreateActiveUser() {
+	w, context := suite.adminContext("", `{"identifier":"dave","passkey":"Test1Test!"}`)
+
+	suite.controller.CreateUser(context)
+
+	assert.Equal(suite.T(), 201, w.Result().StatusCode)
+	assert.Equal(suite.T(), user.Active, suite.find("dave").Status)
+	assert.NotEqual(suite.T(), "Test1Test!", suite.find("dave").Passkey)
+	assert.Equal(suite.T(), []string{"user.create:success"}, suite.actions("dave"))
+}
+
+func (suite *AdminControllerSuite) TestCreateUser_FailWithPolicyViolation() {
+	w, context := suite.adminContext("", `{"identifier":"dave","passkey":"short"}`)
+
+	suite.controller.CreateUser(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	var problem Problem
+	json.NewDecoder(w.Result().Body).Decode(&problem)
+	assert.Equal(suite.T(), "/problems/policy-violation", problem.Type)
+	assert.Equal(suite.T(), []string{"user.create:failure"}, suite.actions("dave"))
+}
+
+func (suite *AdminControllerSuite) TestCreateUser_FailWithFederatedIdentifier() {
+	w, context := suite.adminContext("", `{"identifier":"google:123","passkey":"Test1Test!"}`)
+
+	suite.controller.CreateUser(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	var problem Problem
+	json.NewDecoder(w.Result().Body).Decode(&problem)
+	assert.Equal(suite.T(), "/problems/invalid-identifier", problem.Type)
+}
+
+func (suite *AdminControllerSuite) TestCreateUser_FailWhenUserExists() {
+	w, context := suite.adminContext("", `{"identifier":"alice","passkey":"Test1Test!"}`)
+
+	suite.controller.CreateUser(context)
+
+	assert.Equal(suite.T(), 409, w.Result().StatusCode)
+}
+
+func (suite *AdminControllerSuite) TestUpdateUser_UpdateProfile() {
+	w, context := suite.adminContext("alice", `{"displayName":"Alice","attributes":{"department":"it"}}`)
+
+	suite.controller.UpdateUser(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	assert.Equal(suite.T(), "Alice", suite.find("alice").DisplayName)
+	assert.Equal(suite.T(), []string{"user.update:success"}, suite.actions("alice"))
+}
+
+func (suite *AdminControllerSuite) TestSuspendUser_StoreReasonAndRecordEvent() {
+	w, context := suite.adminContext("alice", `{"reason":"abuse","until":"2030-01-01T00:00:00Z"}`)
+
+	suite.controller.SuspendUser(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	found := suite.find("alice")
+	assert.Equal(suite.T(), user.Suspended, found.Status)
+	assert.Equal(suite.T(), "abuse", found.SuspensionReason)
+	assert.Equal(suite.T(), time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), found.SuspendedUntil)
+	assert.Equal(suite.T(), []string{"user.suspend:success"}, suite.actions("alice"))
+}
+
+func (suite *AdminControllerSuite) TestActivateUser_UnlockUser() {
+	w, context := suite.adminContext("carol", "")
+
+	suite.controller.ActivateUser(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	assert.Equal(suite.T(), user.Active, suite.find("carol").Status)
+}
+
+func (suite *AdminControllerSuite) TestActivateUser_ConflictWhenAlreadyActive() {
+	w, context := suite.adminContext("alice", "")
+
+	suite.controller.ActivateUser(context)
+
+	assert.Equal(suite.T(), 409, w.Result().StatusCode)
+	events, _ := suite.auditRepo.FindByTarget(context.Request.Context(), "alice")
+	assert.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), audit.Failure, events[0].Outcome)
+	assert.Equal(suite.T(), "admin", events[0].Actor)
+	assert.Contains(suite.T(), events[0].Detail, "active")
+}
+
+func (suite *AdminControllerSuite) TestDeleteUser_MarkUserForPurge() {
+	w, context := suite.adminContext("bob", "")
+
+	suite.controller.DeleteUser(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	assert.Equal(suite.T(), user.DeletedPendingPurge, suite.find("bob").Status)
+}
+
+func (suite *AdminControllerSuite) TestForcePasswordReset_FlagUser() {
+	w, context := suite.adminContext("alice", "")
+
+	suite.controller.ForcePasswordReset(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	assert.True(suite.T(), suite.find("alice").PasswordResetRequired)
+	assert.Equal(suite.T(), []string{"user.force_password_reset:success"}, suite.actions("alice"))
+}
+
+func (suite *AdminControllerSuite) TestRevokeSessions_StampRevocation() {
+	w, context := suite.adminContext("alice", "")
+
+	suite.controller.RevokeSessions(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	assert.False(suite.T(), suite.find("alice").SessionsRevokedAt.IsZero())
+}
+
+func (suite *AdminControllerSuite) TestResetMfa_RemoveFactors() {
+	w, context := suite.adminContext("bob", "")
+
+	suite.controller.ResetMfa(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	enrolled, _ := suite.mfaService.IsEnrolled("bob")
+	assert.False(suite.T(), enrolled)
+	assert.Equal(suite.T(), []string{"user.reset_mfa:success"}, suite.actions("bob"))
+}
+
+func (suite *AdminControllerSuite) TestResetMfa_NotFoundForUnknownUser() {
+	w, context := suite.adminContext("unknown", "")
+
+	suite.controller.ResetMfa(context)
+
+	assert.Equal(suite.T(), 404, w.Result().StatusCode)
+}
+
+func (suite *AdminControllerSuite) TestAuditTrail_ListEventsForUser() {
+	_, context := suite.adminContext("alice", "")
+	suite.controller.ForcePasswordReset(context)
+	_, context = suite.adminContext("alice", "")
+	suite.controller.RevokeSessions(context)
+
+	w, context := suite.adminContext("alice", "")
+	suite.controller.AuditTrail(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body struct {
+		Events []struct {
+			Actor   string `json:"actor"`
+			Action  string `json:"action"`
+			Outcome string `json:"outcome"`
+		} `json:"events"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	assert.Len(suite.T(), body.Events, 2)
+	assert.Equal(suite.T(), "admin", body.Events[0].Actor)
+	assert.Equal(suite.T(), "user.force_password_reset", body.Events[0].Action)
+	assert.Equal(suite.T(), "user.revoke_sessions", body.Events[1].Action)
+}
+
+func (suite *AdminControllerSuite) TestSuspendUser_ForbiddenWithoutAdminScope() {
+	w, context := buildContext()
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"reason":"abuse"}`))
+	context.Params = gin.Params{{Key: "identifier", Value: "alice"}}
+	suite.authorize(context)
+
+	suite.controller.SuspendUser(context)
+
+	assert.Equal(suite.T(), 403, w.Result().StatusCode)
+	assert.Equal(suite.T(), user.Active, suite.find("alice").Status)
+	assert.Empty(suite.T(), suite.actions("alice"))
+}
+
+func TestAdminController(t *testing.T) {
+	suite.Run(t, new(AdminControllerSuite))
+}
@@ -0,0 +1,8 @@
+package audit
+
+import "context"
+
+type AuditRepository interface {
+	Record(ctx context.Context, event *Event) error
+	FindByTarget(ctx context.Context, target string) ([]*Event, error)
+}
@@ -0,0 +1,64 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditRepoTestSuite struct {
+	suite.Suite
+	repo AuditRepository
+}
+
+func (suite *AuditRepoTestSuite) SetupTest() {
+	suite.repo = new(MemoryAuditRepository)
+}
+
+func (suite *AuditRepoTestSuite) TestRecord_FindEventsInOrderByTarget() {
+	first := &Event{Actor: "admin", Action: "user.suspend", Target: "abc", Outcome: Success}
+	second := &Event{Actor: "admin", Action: "user.activate", Target: "abc", Outcome: Failure, Detail: "invalid transition"}
+	other := &Event{Actor: "admin", Action: "user.delete", Target: "xyz", Outcome: Success}
+
+	for _, event := range []*Event{first, other, second} {
+		assert.Nil(suite.T(), suite.repo.Record(context.Background(), event))
+	}
+
+	events, err := suite.repo.FindByTarget(context.Background(), "abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*Event{first, second}, events)
+}
+
+func (suite *AuditRepoTestSuite) TestRecord_StampOccurredAt() {
+	before := time.Now().UTC()
+	event := &Event{Actor: "admin", Action: "user.read", Target: "abc", Outcome: Success}
+
+	suite.repo.Record(context.Background(), event)
+
+	assert.False(suite.T(), event.OccurredAt.Before(before))
+}
+
+func (suite *AuditRepoTestSuite) TestFindByTarget_EmptyForUnknownTarget() {
+	events, err := suite.repo.FindByTarget(context.Background(), "unknown")
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), events)
+}
+
+func (suite *AuditRepoTestSuite) TestRecord_ErrorWhenContextCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := suite.repo.Record(ctx, &Event{Action: "user.read"})
+
+	assert.ErrorIs(suite.T(), err, context.Canceled)
+}
+
+func TestMemoryAuditRepository(t *testing.T) {
+	suite.Run(t, new(AuditRepoTestSuite))
+}
@@ -0,0 +1,19 @@
+package audit
+
+import "time"
+
+type outcome string
+
+const (
+	Success = outcome("success")
+	Failure = outcome("failure")
+)
+
+type Event struct {
+	Actor      string
+	Action     string
+	Target     string
+	Outcome    outcome
+	Detail     string
+	OccurredAt time.Time
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryAuditRepository struct {
+	mu     sync.RWMutex
+	events []*Event
+}
+
+func (repo *MemoryAuditRepository) Record(ctx context.Context, event *Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	recorded := *event
+	repo.events = append(repo.events, &recorded)
+	return nil
+}
+
+func (repo *MemoryAuditRepository) FindByTarget(ctx context.Context, target string) ([]*Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	events := []*Event{}
+	for _, event := range repo.events {
+		if event.Target == target {
+			found := *event
+			events = append(events, &found)
+		}
+	}
+	return events, nil
+}
@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	jwt "github.com/Untanky/go-id/jwt"
 	secret "github.com/Untanky/go-id/secret"
 )
 
+const AccessTokenJwtType = "at+jwt"
+
 type AccessTokenService struct {
 	jwtService *jwt.JwtService[secret.KeyPair]
 }
@@ -15,38 +19,54 @@ func (service *AccessTokenService) Init(jwtService *jwt.JwtService[secret.KeyPai
 	service.jwtService = jwtService
 }
 
-func (service *AccessTokenService) Create(payload *RefreshTokenPayload) (jwt.Jwt, error) {
+func (service *AccessTokenService) Create(ctx context.Context, payload *RefreshTokenPayload) (jwt.Jwt, error) {
 	accessTokenDuration, _ := time.ParseDuration("60m")
+	if payload.Lifetime > 0 {
+		accessTokenDuration = payload.Lifetime
+	}
 
 	payloadMap := make(map[string]interface{})
 	payloadMap["sid"] = payload.Sid
 	payloadMap["sub"] = payload.Sub
+	payloadMap["amr"] = payload.Amr
+	if payload.ClientId != "" {
+		payloadMap["client_id"] = payload.ClientId
+	}
+	if len(payload.Scope) > 0 {
+		payloadMap["scope"] = strings.Join(payload.Scope, " ")
+	}
+	if payload.Act != nil {
+		payloadMap["act"] = payload.Act.claim()
+	}
+	if payload.AuthTime > 0 {
+		payloadMap["auth_time"] = payload.AuthTime
+	}
+	if payload.Gid != "" {
+		payloadMap["gid"] = payload.Gid
+	}
 	payloadMap["iat"] = time.Now().Unix()
 	payloadMap["exp"] = time.Now().Add(accessTokenDuration).Unix()
 
-	token, err := service.jwtService.Create(payloadMap)
+	token, err := service.jwtService.CreateTyped(AccessTokenJwtType, payloadMap)
 
 	return token, err
 }
 
-func (service *AccessTokenService) Validate(token jwt.Jwt) (*RefreshTokenPayload, error) {
+func (service *AccessTokenService) Validate(ctx context.Context, token jwt.Jwt) (*RefreshTokenPayload, error) {
 	payload, err := token.Payload()
 	if err != nil {
 		return nil, err
 	}
 
-	err = token.Validate(string(service.jwtService.Secret.GetSecret().PublicKey))
+	err = service.jwtService.Validate(token)
 	if err != nil {
 		return nil, err
 	}
 
-	iat := int64(payload["iat"].(float64))
-	exp := int64(payload["exp"].(float64))
+	header, err := token.Header()
+	if err != nil || header.Typ != AccessTokenJwtType {
+		return nil, ErrInvalidTokenType
+	}
 
-	return &RefreshTokenPayload{
-		Sid: payload["sid"].(string),
-		Sub: payload["sub"].(string),
-		Iat: iat,
-		Exp: exp,
-	}, nil
+	return readPayload(payload)
 }
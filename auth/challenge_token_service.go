@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -8,12 +9,24 @@ import (
 	secret "github.com/Untanky/go-id/secret"
 )
 
+type challengePurpose string
+
+const (
+	VerifyEmailPurpose challengePurpose = "verify_email"
+	MfaPurpose         challengePurpose = "mfa"
+	MagicLinkPurpose   challengePurpose = "magic_link"
+)
+
 type ChallengeTokenPayload struct {
 	Sub      string
 	Iat      int64
 	Exp      int64
 	Duration time.Duration
 	Event    int64
+	Purpose  challengePurpose
+	Jti      string
+	Binding  string
+	Amr      []string
 }
 
 type ChallengeTokenService struct {
@@ -24,43 +37,71 @@ func (service *ChallengeTokenService) Init(jwtService *jwt.JwtService[secret.Sec
 	service.jwtService = jwtService
 }
 
-func (service *ChallengeTokenService) Create(payload *ChallengeTokenPayload) (jwt.Jwt, error) {
+func (service *ChallengeTokenService) Create(ctx context.Context, payload *ChallengeTokenPayload) (jwt.Jwt, error) {
 	payloadMap := make(map[string]interface{})
 	payloadMap["sub"] = payload.Sub
 	payloadMap["iat"] = time.Now().Unix()
 	payloadMap["exp"] = time.Now().Add(payload.Duration).Unix()
 	payloadMap["event"] = payload.Event
+	payloadMap["purpose"] = string(payload.Purpose)
+	payloadMap["jti"] = payload.Jti
+	payloadMap["bnd"] = payload.Binding
+	payloadMap["amr"] = payload.Amr
 
 	token, err := service.jwtService.Create(payloadMap)
 
 	return token, err
 }
 
-func (service *ChallengeTokenService) Validate(token jwt.Jwt) (*ChallengeTokenPayload, error) {
+func (service *ChallengeTokenService) Validate(ctx context.Context, token jwt.Jwt) (*ChallengeTokenPayload, error) {
 	payload, err := token.Payload()
 	if err != nil {
 		return nil, err
 	}
 
-	err = token.Validate(string(service.jwtService.Secret.GetSecret()))
+	err = service.jwtService.Validate(token)
 	if err != nil {
 		return nil, err
 	}
 
-	iat := int64(payload["iat"].(float64))
-	exp := int64(payload["exp"].(float64))
-	event := int64(payload["event"].(float64))
-	duration, err := time.ParseDuration(fmt.Sprintf("%ds", exp-iat))
+	sub, ok := payload["sub"].(string)
+	if !ok || sub == "" {
+		return nil, ErrInvalidPayload
+	}
+
+	iat, ok := payload["iat"].(float64)
+	if !ok {
+		return nil, ErrInvalidPayload
+	}
+
+	exp, ok := payload["exp"].(float64)
+	if !ok {
+		return nil, ErrInvalidPayload
+	}
+
+	event, ok := payload["event"].(float64)
+	if !ok {
+		return nil, ErrInvalidPayload
+	}
+
+	purpose, _ := payload["purpose"].(string)
+	jti, _ := payload["jti"].(string)
+	binding, _ := payload["bnd"].(string)
+	duration, err := time.ParseDuration(fmt.Sprintf("%ds", int64(exp-iat)))
 
 	if err != nil {
 		return nil, err
 	}
 
 	return &ChallengeTokenPayload{
-		Sub:      payload["sub"].(string),
-		Iat:      iat,
-		Exp:      exp,
+		Sub:      sub,
+		Iat:      int64(iat),
+		Exp:      int64(exp),
 		Duration: duration,
-		Event:    event,
+		Event:    int64(event),
+		Purpose:  challengePurpose(purpose),
+		Jti:      jti,
+		Binding:  binding,
+		Amr:      readAmr(payload["amr"]),
 	}, nil
 }
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnauthorized             = errors.New("unauthorized")
+	ErrForbidden                = errors.New("insufficient scope")
+	ErrPendingVerification      = errors.New("user is pending verification")
+	ErrSuspended                = errors.New("user is suspended")
+	ErrLocked                   = errors.New("user is locked")
+	ErrDeleted                  = errors.New("user is deleted")
+	ErrPolicyViolation          = errors.New("passkey violates policy")
+	ErrPasswordResetRequired    = errors.New("password reset required")
+	ErrSessionRevoked           = errors.New("session has been revoked")
+	ErrInvalidPayload           = errors.New("token payload is invalid")
+	ErrInvalidTokenType         = errors.New("token type is invalid")
+	ErrReauthenticationRequired = errors.New("recent authentication required")
+)
+
+type PolicyViolationError struct {
+	Violations []string
+}
+
+func (err *PolicyViolationError) Error() string {
+	return "Validation Error: " + strings.Join(err.Violations, ", ")
+}
+
+func (err *PolicyViolationError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+type SuspendedError struct {
+	Reason string
+	Until  time.Time
+}
+
+func (err *SuspendedError) Error() string {
+	message := ErrSuspended.Error()
+	if err.Reason != "" {
+		message += ": " + err.Reason
+	}
+	if !err.Until.IsZero() {
+		message += fmt.Sprintf(" (until %s)", err.Until.Format(time.RFC3339))
+	}
+	return message
+}
+
+func (err *SuspendedError) Is(target error) bool {
+	return target == ErrSuspended
+}
@@ -1,8 +1,10 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"strings"
+	"time"
 
 	. "github.com/Untanky/go-id/user"
 )
@@ -17,63 +19,148 @@ func (service *LoginService) Init(userRepo UserRepository, encrypter Encrypter)
 	service.encrypter = encrypter
 }
 
-func (service *LoginService) Register(user *User) error {
+func (service *LoginService) Register(ctx context.Context, user *User) error {
+	if strings.Contains(user.Identifier, ":") {
+		return ErrInvalidIdentifier
+	}
+
 	if err := service.validatePasskey(user.Passkey); err != nil {
 		return err
 	}
 
 	user.Passkey = string(service.encrypter.Encrypt([]byte(user.Passkey), []byte("salt")))
 
-	if user, _ := service.userRepo.FindByIdentifier(user.Identifier); user != nil {
-		return errors.New("Identifier already exists")
+	if user, _ := service.userRepo.FindByIdentifier(ctx, user.Identifier); user != nil {
+		return ErrUserExists
 	}
 
-	service.userRepo.Create(user)
-
-	return nil
+	return service.userRepo.Create(ctx, user)
 }
 
 func (service *LoginService) validatePasskey(passkey string) error {
+	violations := []string{}
+
 	if len(passkey) < 10 {
-		return errors.New("Validation Error: Passkey too short")
+		violations = append(violations, "Passkey too short")
 	}
 
 	if !strings.ContainsAny(passkey, "1234567890") {
-		return errors.New("Validation Error: Passkey missing number")
+		violations = append(violations, "Passkey missing number")
 	}
 
 	if !strings.ContainsAny(passkey, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
-		return errors.New("Validation Error: Passkey missing uppercase character")
+		violations = append(violations, "Passkey missing uppercase character")
 	}
 
 	if !strings.ContainsAny(passkey, "abcdefghijklmnopqrstuvwxyz") {
-		return errors.New("Validation Error: Passkey missing lowercase character")
+		violations = append(violations, "Passkey missing lowercase character")
 	}
 
 	if !strings.ContainsAny(passkey, "!@#$%^&**()_-+=[]{}\\|'\";:,.<>/?`~") {
-		return errors.New("Validation Error: Passkey missing special character")
+		violations = append(violations, "Passkey missing special character")
 	}
 
+	if len(violations) > 0 {
+		return &PolicyViolationError{Violations: violations}
+	}
 	return nil
 }
 
-func (service *LoginService) Login(identifier string, passkey string) (*User, error) {
-	user, foundErr := service.userRepo.FindByIdentifier(identifier)
+func (service *LoginService) Login(ctx context.Context, identifier string, passkey string) (*User, error) {
+	user, err := service.authenticate(ctx, identifier, passkey)
+	if err != nil {
+		return nil, err
+	}
 
-	if foundErr != nil {
-		return nil, errors.New("unauthorized")
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
+	}
+
+	return user, nil
+}
+
+func (service *LoginService) ChangePasskey(ctx context.Context, identifier string, passkey string, newPasskey string) error {
+	user, err := service.authenticate(ctx, identifier, passkey)
+	if err != nil {
+		return err
+	}
+
+	if err := service.validatePasskey(newPasskey); err != nil {
+		return err
 	}
 
-	if user.Status == Inactive {
-		return nil, errors.New("user is inactive")
+	user.Passkey = string(service.encrypter.Encrypt([]byte(newPasskey), []byte("salt")))
+	user.PasswordResetRequired = false
+
+	return service.userRepo.Update(ctx, user)
+}
+
+func (service *LoginService) authenticate(ctx context.Context, identifier string, passkey string) (*User, error) {
+	user, foundErr := service.userRepo.FindByIdentifier(ctx, identifier)
+
+	if foundErr != nil {
+		return nil, ErrUnauthorized
 	}
 
 	salt := service.encrypter.RetrieveSalt([]byte(user.Passkey))
 	encrptedPasskey := string(service.encrypter.Encrypt([]byte(passkey), salt))
 
 	if encrptedPasskey != user.Passkey {
-		return nil, errors.New("unauthorized")
+		return nil, ErrUnauthorized
+	}
+
+	if err := CheckStatus(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (service *LoginService) LoginPasswordless(ctx context.Context, identifier string) (*User, error) {
+	user, foundErr := service.userRepo.FindByIdentifier(ctx, identifier)
+
+	if foundErr != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if err := CheckStatus(user); err != nil {
+		return nil, err
 	}
 
 	return user, nil
 }
+
+func (service *LoginService) RecordLogin(ctx context.Context, identifier string) error {
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return err
+	}
+
+	user.LastLoginAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	err = service.userRepo.Update(ctx, user)
+	if errors.Is(err, ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+func CheckStatus(user *User) error {
+	switch user.Status {
+	case Active:
+		return nil
+	case PendingVerification:
+		return ErrPendingVerification
+	case Suspended:
+		if user.SuspensionExpired(time.Now()) {
+			return nil
+		}
+		return &SuspendedError{Reason: user.SuspensionReason, Until: user.SuspendedUntil}
+	case Locked:
+		return ErrLocked
+	case DeletedPendingPurge:
+		return ErrDeleted
+	default:
+		return ErrUnauthorized
+	}
+}
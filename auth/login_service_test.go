@@ -1,8 +1,11 @@
 package auth_test
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/Untanky/go-id/auth"
 	. "github.com/Untanky/go-id/user"
@@ -17,6 +20,14 @@ const (
 	encrypted     = "abcdfa"
 )
 
+func withTimestamps(expected *User, actual *User) *User {
+	if actual != nil {
+		expected.CreatedAt = actual.CreatedAt
+		expected.UpdatedAt = actual.UpdatedAt
+	}
+	return expected
+}
+
 type LoginTestSuite struct {
 	suite.Suite
 	userRepo   UserRepository
@@ -35,7 +46,7 @@ func (suite *LoginTestSuite) SetupTest() {
 	suite.knownUsers = []*User{
 		{Identifier: knownUserId + "0", Passkey: knownUserKey + "0", Status: Active},
 		{Identifier: knownUserId + "1", Passkey: knownUserKey + "1", Status: Active},
-		{Identifier: knownUserId + "2", Passkey: knownUserKey + "2", Status: Inactive},
+		{Identifier: knownUserId + "2", Passkey: knownUserKey + "2", Status: PendingVerification},
 	}
 
 	for index, user := range suite.knownUsers {
@@ -45,7 +56,7 @@ func (suite *LoginTestSuite) SetupTest() {
 			Passkey:    user.Passkey,
 			Status:     user.Status,
 		}
-		suite.service.Register(copy)
+		suite.service.Register(context.Background(), copy)
 	}
 }
 
@@ -57,57 +68,237 @@ func (suite *LoginTestSuite) TestLogin_LoginWithKnownUser() {
 		Identifier: user0.Identifier,
 		Passkey:    "salt:" + encrypted + "0",
 		Status:     user0.Status,
+		Version:    1,
 	}
 
 	expected1 := &User{
 		Identifier: user1.Identifier,
 		Passkey:    "salt:" + encrypted + "1",
 		Status:     user1.Status,
+		Version:    1,
 	}
 
-	loggedIn0, err := suite.service.Login(user0.Identifier, user0.Passkey)
+	loggedIn0, err := suite.service.Login(context.Background(), user0.Identifier, user0.Passkey)
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), expected0, loggedIn0)
+	assert.Equal(suite.T(), withTimestamps(expected0, loggedIn0), loggedIn0)
 
-	loggedIn1, err := suite.service.Login(user1.Identifier, user1.Passkey)
+	loggedIn1, err := suite.service.Login(context.Background(), user1.Identifier, user1.Passkey)
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), expected1, loggedIn1)
+	assert.Equal(suite.T(), withTimestamps(expected1, loggedIn1), loggedIn1)
 }
 
-func (suite *LoginTestSuite) TestLogin_ErrorWithInactiveUser() {
-	inactiveUser := suite.knownUsers[2]
+func (suite *LoginTestSuite) TestLogin_ErrorWithPendingUser() {
+	pendingUser := suite.knownUsers[2]
 
-	user, err := suite.service.Login(inactiveUser.Identifier, "salt:"+encrypted+"2")
-	assert.ErrorContains(suite.T(), err, "user is inactive")
+	user, err := suite.service.Login(context.Background(), pendingUser.Identifier, pendingUser.Passkey)
+	assert.ErrorIs(suite.T(), err, ErrPendingVerification)
 	assert.Nil(suite.T(), user)
 }
 
+func (suite *LoginTestSuite) TestLogin_HideStatusWhenPasskeyDoesNotMatch() {
+	user0 := suite.knownUsers[0]
+	suite.encrypter.On("Encrypt", []byte("wrong"), []byte("salt")).Return("wrong")
+	suite.setStatus(user0.Identifier, func(user *User) {
+		user.Status = Suspended
+		user.SuspensionReason = "abuse"
+	})
+
+	user, err := suite.service.Login(context.Background(), user0.Identifier, "wrong")
+
+	assert.Equal(suite.T(), ErrUnauthorized, err)
+	assert.Nil(suite.T(), user)
+}
+
+func (suite *LoginTestSuite) setStatus(identifier string, apply func(user *User)) {
+	user, _ := suite.userRepo.FindByIdentifier(context.Background(), identifier)
+	apply(user)
+	assert.Nil(suite.T(), suite.userRepo.Update(context.Background(), user))
+}
+
+func (suite *LoginTestSuite) TestLogin_DistinctErrorPerStatus() {
+	user0 := suite.knownUsers[0]
+
+	suite.setStatus(user0.Identifier, func(user *User) { user.Status = Locked })
+	_, err := suite.service.Login(context.Background(), user0.Identifier, user0.Passkey)
+	assert.ErrorIs(suite.T(), err, ErrLocked)
+
+	suite.setStatus(user0.Identifier, func(user *User) { user.Status = DeletedPendingPurge })
+	_, err = suite.service.Login(context.Background(), user0.Identifier, user0.Passkey)
+	assert.ErrorIs(suite.T(), err, ErrDeleted)
+
+	until := time.Now().Add(time.Hour).UTC()
+	suite.setStatus(user0.Identifier, func(user *User) {
+		user.Status = Suspended
+		user.SuspensionReason = "abuse"
+		user.SuspendedUntil = until
+	})
+	_, err = suite.service.Login(context.Background(), user0.Identifier, user0.Passkey)
+	assert.ErrorIs(suite.T(), err, ErrSuspended)
+	var suspended *SuspendedError
+	assert.ErrorAs(suite.T(), err, &suspended)
+	assert.Equal(suite.T(), "abuse", suspended.Reason)
+	assert.Equal(suite.T(), until, suspended.Until)
+}
+
+func (suite *LoginTestSuite) TestLogin_AllowWhenSuspensionExpired() {
+	user0 := suite.knownUsers[0]
+	suite.setStatus(user0.Identifier, func(user *User) {
+		user.Status = Suspended
+		user.SuspendedUntil = time.Now().Add(-time.Minute)
+	})
+
+	user, err := suite.service.Login(context.Background(), user0.Identifier, user0.Passkey)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), user0.Identifier, user.Identifier)
+}
+
 func (suite *LoginTestSuite) TestLogin_ErrorWithKnownUserAndIncorrectPasskey() {
 	user0 := suite.knownUsers[0]
 	user1 := suite.knownUsers[1]
 
-	user, err := suite.service.Login(user0.Identifier, user1.Passkey)
-	assert.ErrorContains(suite.T(), err, "unauthorized")
+	user, err := suite.service.Login(context.Background(), user0.Identifier, user1.Passkey)
+	assert.ErrorIs(suite.T(), err, ErrUnauthorized)
 	assert.Nil(suite.T(), user)
 
-	user, err = suite.service.Login(user1.Identifier, user0.Passkey)
-	assert.ErrorContains(suite.T(), err, "unauthorized")
+	user, err = suite.service.Login(context.Background(), user1.Identifier, user0.Passkey)
+	assert.ErrorIs(suite.T(), err, ErrUnauthorized)
 	assert.Nil(suite.T(), user)
 
 	suite.encrypter.On("Encrypt", []byte("foo"), []byte("salt")).Return("abc")
 
-	user, err = suite.service.Login(user1.Identifier, "foo")
-	assert.ErrorContains(suite.T(), err, "unauthorized")
+	user, err = suite.service.Login(context.Background(), user1.Identifier, "foo")
+	assert.ErrorIs(suite.T(), err, ErrUnauthorized)
 	assert.Nil(suite.T(), user)
 }
 
 func (suite *LoginTestSuite) TestLogin_ErrorWithUnknownUser() {
-	user, err := suite.service.Login(unknownUserId, "xyz")
+	user, err := suite.service.Login(context.Background(), unknownUserId, "xyz")
 
-	assert.ErrorContains(suite.T(), err, "unauthorized")
+	assert.ErrorIs(suite.T(), err, ErrUnauthorized)
 	assert.Nil(suite.T(), user)
 }
 
+func (suite *LoginTestSuite) TestLogin_ErrorWhenPasswordResetRequired() {
+	user0 := suite.knownUsers[0]
+	suite.setStatus(user0.Identifier, func(user *User) { user.PasswordResetRequired = true })
+
+	user, err := suite.service.Login(context.Background(), user0.Identifier, user0.Passkey)
+
+	assert.ErrorIs(suite.T(), err, ErrPasswordResetRequired)
+	assert.Nil(suite.T(), user)
+}
+
+func (suite *LoginTestSuite) TestChangePasskey_ReplacePasskeyAndClearReset() {
+	user0 := suite.knownUsers[0]
+	newPasskey := "N3w-Passkey!"
+	suite.encrypter.On("Encrypt", []byte(newPasskey), []byte("salt")).Return("changed")
+	suite.setStatus(user0.Identifier, func(user *User) { user.PasswordResetRequired = true })
+
+	err := suite.service.ChangePasskey(context.Background(), user0.Identifier, user0.Passkey, newPasskey)
+	assert.Nil(suite.T(), err)
+
+	user, err := suite.service.Login(context.Background(), user0.Identifier, newPasskey)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "salt:changed", user.Passkey)
+	assert.False(suite.T(), user.PasswordResetRequired)
+}
+
+func (suite *LoginTestSuite) TestChangePasskey_ErrorWithIncorrectPasskey() {
+	user0 := suite.knownUsers[0]
+	user1 := suite.knownUsers[1]
+
+	err := suite.service.ChangePasskey(context.Background(), user0.Identifier, user1.Passkey, "N3w-Passkey!")
+
+	assert.ErrorIs(suite.T(), err, ErrUnauthorized)
+}
+
+func (suite *LoginTestSuite) TestChangePasskey_ErrorWhenNewPasskeyViolatesPolicy() {
+	user0 := suite.knownUsers[0]
+
+	err := suite.service.ChangePasskey(context.Background(), user0.Identifier, user0.Passkey, "short")
+
+	assert.ErrorIs(suite.T(), err, ErrPolicyViolation)
+}
+
+func (suite *LoginTestSuite) TestRecordLogin_StampLastLogin() {
+	user0 := suite.knownUsers[0]
+	before := time.Now().UTC().Add(-time.Second)
+
+	err := suite.service.RecordLogin(context.Background(), user0.Identifier)
+	assert.Nil(suite.T(), err)
+
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
+	assert.True(suite.T(), found.LastLoginAt.After(before))
+	assert.Equal(suite.T(), int64(2), found.Version)
+}
+
+func (suite *LoginTestSuite) TestRecordLogin_ErrorWithUnknownUser() {
+	err := suite.service.RecordLogin(context.Background(), unknownUserId)
+
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func (suite *LoginTestSuite) TestLoginPasswordless_LoginWithKnownUser() {
+	user0 := suite.knownUsers[0]
+
+	user, err := suite.service.LoginPasswordless(context.Background(), user0.Identifier)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), user0.Identifier, user.Identifier)
+}
+
+func (suite *LoginTestSuite) TestLoginPasswordless_ErrorWithPendingUser() {
+	pendingUser := suite.knownUsers[2]
+
+	user, err := suite.service.LoginPasswordless(context.Background(), pendingUser.Identifier)
+
+	assert.ErrorIs(suite.T(), err, ErrPendingVerification)
+	assert.Nil(suite.T(), user)
+}
+
+func (suite *LoginTestSuite) TestLoginPasswordless_ErrorWithUnknownUser() {
+	user, err := suite.service.LoginPasswordless(context.Background(), unknownUserId)
+
+	assert.ErrorIs(suite.T(), err, ErrUnauthorized)
+	assert.Nil(suite.T(), user)
+}
+
+func (suite *LoginTestSuite) TestLogin_ConcurrentRegisterLoginAndUpdate() {
+	userService := new(UserService)
+	userService.Init(suite.userRepo)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		newUser := &User{Identifier: fmt.Sprintf("concurrentUser%d", i), Passkey: knownUserKey, Status: Active}
+		suite.encrypter.On("Encrypt", []byte(knownUserKey), []byte("salt")).Return(encrypted)
+
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			assert.Nil(suite.T(), suite.service.Register(context.Background(), newUser))
+		}()
+		go func() {
+			defer wg.Done()
+			user, err := suite.service.Login(context.Background(), suite.knownUsers[0].Identifier, suite.knownUsers[0].Passkey)
+			assert.Nil(suite.T(), err)
+			assert.NotNil(suite.T(), user)
+		}()
+		go func() {
+			defer wg.Done()
+			userService.Lock(context.Background(), suite.knownUsers[1].Identifier)
+			userService.Activate(context.Background(), suite.knownUsers[1].Identifier)
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		user, err := suite.service.Login(context.Background(), fmt.Sprintf("concurrentUser%d", i), knownUserKey)
+		assert.Nil(suite.T(), err)
+		assert.NotNil(suite.T(), user)
+	}
+}
+
 type RegisterTestSuite struct {
 	suite.Suite
 	userRepo   UserRepository
@@ -128,75 +319,99 @@ func (suite *RegisterTestSuite) SetupTest() {
 
 func (suite *RegisterTestSuite) TestRegister_KnownUserShouldContainNewUser() {
 	var err error
-	user0 := &User{knownUserId + "0", knownUserKey + "0", Active}
+	user0 := &User{Identifier: knownUserId + "0", Passkey: knownUserKey + "0", Status: Active}
 	encrypted0 := "abc"
-	expected0 := &User{user0.Identifier, "salt:" + encrypted0, user0.Status}
-	user1 := &User{knownUserId + "1", knownUserKey + "1", Active}
+	expected0 := &User{Identifier: user0.Identifier, Passkey: "salt:" + encrypted0, Status: user0.Status, Version: 1}
+	user1 := &User{Identifier: knownUserId + "1", Passkey: knownUserKey + "1", Status: Active}
 	encrypted1 := "def"
-	expected1 := &User{user1.Identifier, "salt:" + encrypted1, user1.Status}
+	expected1 := &User{Identifier: user1.Identifier, Passkey: "salt:" + encrypted1, Status: user1.Status, Version: 1}
 
 	suite.encrypter.On("Encrypt", []byte(user0.Passkey), []byte("salt")).Return(encrypted0)
 	suite.encrypter.On("Encrypt", []byte(user1.Passkey), []byte("salt")).Return(encrypted1)
 
-	err = suite.service.Register(user0)
+	err = suite.service.Register(context.Background(), user0)
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), "salt:"+encrypted0, user0.Passkey)
-	err = suite.service.Register(user1)
+	err = suite.service.Register(context.Background(), user1)
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), "salt:"+encrypted1, user1.Passkey)
 
-	foundUser, err := suite.userRepo.FindByIdentifier(user0.Identifier)
+	foundUser, err := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), foundUser, expected0)
+	assert.Equal(suite.T(), foundUser, withTimestamps(expected0, foundUser))
 
-	foundUser, err = suite.userRepo.FindByIdentifier(user1.Identifier)
+	foundUser, err = suite.userRepo.FindByIdentifier(context.Background(), user1.Identifier)
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), foundUser, expected1)
+	assert.Equal(suite.T(), foundUser, withTimestamps(expected1, foundUser))
 }
 
 func (suite *RegisterTestSuite) TestRegister_ErrorWhenUserIdExists() {
-	user0 := &User{knownUserId, knownUserKey, Active}
+	user0 := &User{Identifier: knownUserId, Passkey: knownUserKey, Status: Active}
 	encrypted0 := "abc"
-	user1 := &User{knownUserId, knownUserKey, Active}
-	expected0 := &User{user0.Identifier, "salt:" + encrypted0, user0.Status}
+	user1 := &User{Identifier: knownUserId, Passkey: knownUserKey, Status: Active}
+	expected0 := &User{Identifier: user0.Identifier, Passkey: "salt:" + encrypted0, Status: user0.Status, Version: 1}
 
 	suite.encrypter.On("Encrypt", []byte(user0.Passkey), []byte("salt")).Return(encrypted0)
 
-	err := suite.service.Register(user0)
+	err := suite.service.Register(context.Background(), user0)
 	assert.Nil(suite.T(), err)
-	err = suite.service.Register(user1)
-	assert.ErrorContains(suite.T(), err, "Identifier already exists")
+	err = suite.service.Register(context.Background(), user1)
+	assert.ErrorIs(suite.T(), err, ErrUserExists)
 
-	foundUser, err := suite.userRepo.FindByIdentifier(user0.Identifier)
+	foundUser, err := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), foundUser, expected0)
+	assert.Equal(suite.T(), foundUser, withTimestamps(expected0, foundUser))
+}
+
+func (suite *RegisterTestSuite) TestRegister_ErrorWithFederatedIdentifier() {
+	err := suite.service.Register(context.Background(), &User{Identifier: "google:123", Passkey: knownUserKey, Status: Active})
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdentifier)
+	_, err = suite.userRepo.FindByIdentifier(context.Background(), "google:123")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
 }
 
 func (suite *RegisterTestSuite) TestRegister_PasskeyContainsLetterNumberAndSpecialChar() {
-	passKeyShorterThan10 := &User{knownUserId, "123456789", Active}
-	passKeyWithoutNumber := &User{knownUserId, "abcdefghij", Active}
-	passKeyWithoutUppercaseLetter := &User{knownUserId, "abcdefghi1", Active}
-	passKeyWithoutLowercaseLetter := &User{knownUserId, "ABCDEFGHI1", Active}
-	passKeyWithoutSpecialChar := &User{knownUserId, "aBcDeFgHi1", Active}
+	passKeyShorterThan10 := &User{Identifier: knownUserId, Passkey: "123456789", Status: Active}
+	passKeyWithoutNumber := &User{Identifier: knownUserId, Passkey: "abcdefghij", Status: Active}
+	passKeyWithoutUppercaseLetter := &User{Identifier: knownUserId, Passkey: "abcdefghi1", Status: Active}
+	passKeyWithoutLowercaseLetter := &User{Identifier: knownUserId, Passkey: "ABCDEFGHI1", Status: Active}
+	passKeyWithoutSpecialChar := &User{Identifier: knownUserId, Passkey: "aBcDeFgHi1", Status: Active}
 
-	errShorterThan10 := suite.service.Register(passKeyShorterThan10)
+	errShorterThan10 := suite.service.Register(context.Background(), passKeyShorterThan10)
 	assert.ErrorContains(suite.T(), errShorterThan10, "Validation Error: Passkey too short")
 
-	errWithoutNumber := suite.service.Register(passKeyWithoutNumber)
+	errWithoutNumber := suite.service.Register(context.Background(), passKeyWithoutNumber)
 	assert.ErrorContains(suite.T(), errWithoutNumber, "Validation Error: Passkey missing number")
 
-	errWithoutUppercaseLetter := suite.service.Register(passKeyWithoutUppercaseLetter)
+	errWithoutUppercaseLetter := suite.service.Register(context.Background(), passKeyWithoutUppercaseLetter)
 	assert.ErrorContains(suite.T(), errWithoutUppercaseLetter, "Validation Error: Passkey missing uppercase character")
 
-	errWithoutLowercaseLetter := suite.service.Register(passKeyWithoutLowercaseLetter)
+	errWithoutLowercaseLetter := suite.service.Register(context.Background(), passKeyWithoutLowercaseLetter)
 	assert.ErrorContains(suite.T(), errWithoutLowercaseLetter, "Validation Error: Passkey missing lowercase character")
 
-	errWithoutSpecialChar := suite.service.Register(passKeyWithoutSpecialChar)
+	errWithoutSpecialChar := suite.service.Register(context.Background(), passKeyWithoutSpecialChar)
 	assert.ErrorContains(suite.T(), errWithoutSpecialChar, "Validation Error: Passkey missing special character")
 
 	assert.Len(suite.T(), suite.knownUsers, 0)
 }
 
+func (suite *RegisterTestSuite) TestRegister_PolicyViolationListsEveryViolation() {
+	err := suite.service.Register(context.Background(), &User{Identifier: knownUserId, Passkey: "abc", Status: Active})
+
+	assert.ErrorIs(suite.T(), err, ErrPolicyViolation)
+	var violation *PolicyViolationError
+	assert.ErrorAs(suite.T(), err, &violation)
+	assert.Equal(suite.T(), []string{
+		"Passkey too short",
+		"Passkey missing number",
+		"Passkey missing uppercase character",
+		"Passkey missing special character",
+	}, violation.Violations)
+
+	assert.Len(suite.T(), suite.knownUsers, 0)
+}
+
 func TestLoginService(t *testing.T) {
 	suite.Run(t, new(LoginTestSuite))
 	suite.Run(t, new(RegisterTestSuite))
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	jwt "github.com/Untanky/go-id/jwt"
+)
+
+type LinkSender interface {
+	SendLink(identifier string, token jwt.Jwt) error
+}
+
+type MagicLinkService struct {
+	tokenService TokenService[*ChallengeTokenPayload]
+	sender       LinkSender
+	redeemed     map[string]int64
+	lock         sync.Mutex
+}
+
+func (service *MagicLinkService) Init(tokenService TokenService[*ChallengeTokenPayload], sender LinkSender) {
+	service.tokenService = tokenService
+	service.sender = sender
+	service.redeemed = map[string]int64{}
+}
+
+func (service *MagicLinkService) Decoy() (string, error) {
+	return randomString()
+}
+
+func (service *MagicLinkService) Send(ctx context.Context, identifier string) (string, error) {
+	binding, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	duration, _ := time.ParseDuration("15m")
+
+	token, err := service.tokenService.Create(ctx, &ChallengeTokenPayload{
+		Sub:      identifier,
+		Duration: duration,
+		Purpose:  MagicLinkPurpose,
+		Jti:      jti,
+		Binding:  hashBinding(binding),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := service.sender.SendLink(identifier, token); err != nil {
+		return "", err
+	}
+	return binding, nil
+}
+
+func (service *MagicLinkService) Redeem(ctx context.Context, token jwt.Jwt, binding string) (string, error) {
+	payload, err := service.tokenService.Validate(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	if payload.Purpose != MagicLinkPurpose || payload.Jti == "" {
+		return "", errors.New("not a magic link")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(payload.Binding), []byte(hashBinding(binding))) != 1 {
+		return "", errors.New("magic link was requested from another browser")
+	}
+
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	now := time.Now().Unix()
+	for jti, exp := range service.redeemed {
+		if exp < now {
+			delete(service.redeemed, jti)
+		}
+	}
+
+	if _, ok := service.redeemed[payload.Jti]; ok {
+		return "", errors.New("magic link already used")
+	}
+	service.redeemed[payload.Jti] = payload.Exp
+
+	return payload.Sub, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashBinding(binding string) string {
+	hash := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,108 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/auth"
+	jwt "github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type capturingLinkSender struct {
+	token jwt.Jwt
+}
+
+func (sender *capturingLinkSender) SendLink(identifier string, token jwt.Jwt) error {
+	sender.token = token
+	return nil
+}
+
+type MagicLinkTestSuite struct {
+	suite.Suite
+	tokenService *ChallengeTokenService
+	sender       *capturingLinkSender
+	service      *MagicLinkService
+}
+
+func (suite *MagicLinkTestSuite) SetupTest() {
+	jwtService := new(jwt.JwtService[SecretString])
+	jwtService.Init(jwt.HS256, NewSecretValue("key"))
+
+	suite.tokenService = new(ChallengeTokenService)
+	suite.tokenService.Init(jwtService)
+	suite.sender = new(capturingLinkSender)
+
+	suite.service = new(MagicLinkService)
+	suite.service.Init(suite.tokenService, suite.sender)
+}
+
+func (suite *MagicLinkTestSuite) TestSend_MintShortLivedTokenBoundToBrowser() {
+	binding, err := suite.service.Send(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), binding)
+
+	payload, err := suite.tokenService.Validate(context.Background(), suite.sender.token)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "abc", payload.Sub)
+	assert.Equal(suite.T(), MagicLinkPurpose, payload.Purpose)
+	assert.Equal(suite.T(), float64(15), payload.Duration.Minutes())
+	assert.NotEmpty(suite.T(), payload.Jti)
+	assert.NotContains(suite.T(), payload.Binding, binding)
+}
+
+func (suite *MagicLinkTestSuite) TestDecoy_ReturnBindingWithoutSendingLink() {
+	binding, _ := suite.service.Send(context.Background(), "abc")
+	sent := suite.sender.token
+
+	decoy, err := suite.service.Decoy()
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), decoy, len(binding))
+	assert.NotEqual(suite.T(), binding, decoy)
+	assert.Equal(suite.T(), sent, suite.sender.token)
+	_, err = suite.service.Redeem(context.Background(), sent, decoy)
+	assert.ErrorContains(suite.T(), err, "another browser")
+}
+
+func (suite *MagicLinkTestSuite) TestRedeem_OnlyOnce() {
+	binding, _ := suite.service.Send(context.Background(), "abc")
+
+	identifier, err := suite.service.Redeem(context.Background(), suite.sender.token, binding)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "abc", identifier)
+
+	identifier, err = suite.service.Redeem(context.Background(), suite.sender.token, binding)
+	assert.ErrorContains(suite.T(), err, "already used")
+	assert.Empty(suite.T(), identifier)
+}
+
+func (suite *MagicLinkTestSuite) TestRedeem_ErrorWithOtherBinding() {
+	suite.service.Send(context.Background(), "abc")
+
+	identifier, err := suite.service.Redeem(context.Background(), suite.sender.token, "other")
+
+	assert.ErrorContains(suite.T(), err, "another browser")
+	assert.Empty(suite.T(), identifier)
+}
+
+func (suite *MagicLinkTestSuite) TestRedeem_ErrorWithOtherChallengeToken() {
+	duration, _ := time.ParseDuration("5m")
+	token, _ := suite.tokenService.Create(context.Background(), &ChallengeTokenPayload{
+		Sub:      "abc",
+		Duration: duration,
+		Purpose:  MfaPurpose,
+	})
+
+	identifier, err := suite.service.Redeem(context.Background(), token, "")
+
+	assert.ErrorContains(suite.T(), err, "not a magic link")
+	assert.Empty(suite.T(), identifier)
+}
+
+func TestMagicLinkService(t *testing.T) {
+	suite.Run(t, new(MagicLinkTestSuite))
+}
@@ -1,17 +1,43 @@
 package auth
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	jwt "github.com/Untanky/go-id/jwt"
 	secret "github.com/Untanky/go-id/secret"
 )
 
+const (
+	AdminScope       = "admin"
+	ImpersonateScope = "impersonate"
+
+	RefreshTokenJwtType = "rt+jwt"
+)
+
+type Actor struct {
+	Sub      string
+	ClientId string
+	Act      *Actor
+}
+
 type RefreshTokenPayload struct {
-	Sid string
-	Sub string
-	Iat int64
-	Exp int64
+	Sid      string
+	Sub      string
+	Amr      []string
+	ClientId string
+	Scope    []string
+	Act      *Actor
+	Gid      string
+	AuthTime int64
+	Iat      int64
+	Exp      int64
+	Lifetime time.Duration
+}
+
+func (payload *RefreshTokenPayload) AuthenticatedSince(since time.Time) bool {
+	return payload.AuthTime >= since.Unix()
 }
 
 type RefreshTokenService struct {
@@ -22,36 +48,151 @@ func (service *RefreshTokenService) Init(jwtService *jwt.JwtService[secret.Secre
 	service.jwtService = jwtService
 }
 
-func (service *RefreshTokenService) Create(payload *RefreshTokenPayload) (jwt.Jwt, error) {
+func (service *RefreshTokenService) Create(ctx context.Context, payload *RefreshTokenPayload) (jwt.Jwt, error) {
 	payloadMap := make(map[string]interface{})
 	payloadMap["sid"] = payload.Sid
 	payloadMap["sub"] = payload.Sub
+	payloadMap["amr"] = payload.Amr
+	if payload.ClientId != "" {
+		payloadMap["client_id"] = payload.ClientId
+	}
+	if len(payload.Scope) > 0 {
+		payloadMap["scope"] = strings.Join(payload.Scope, " ")
+	}
+	if payload.Act != nil {
+		payloadMap["act"] = payload.Act.claim()
+	}
+	if payload.AuthTime > 0 {
+		payloadMap["auth_time"] = payload.AuthTime
+	}
+	if payload.Gid != "" {
+		payloadMap["gid"] = payload.Gid
+	}
 	payloadMap["iat"] = time.Now().Unix()
 	payloadMap["exp"] = time.Now().AddDate(1, 0, 0).Unix()
+	if payload.Lifetime > 0 {
+		payloadMap["exp"] = time.Now().Add(payload.Lifetime).Unix()
+	}
 
-	token, err := service.jwtService.Create(payloadMap)
+	token, err := service.jwtService.CreateTyped(RefreshTokenJwtType, payloadMap)
 
 	return token, err
 }
 
-func (service *RefreshTokenService) Validate(token jwt.Jwt) (*RefreshTokenPayload, error) {
+func (service *RefreshTokenService) Validate(ctx context.Context, token jwt.Jwt) (*RefreshTokenPayload, error) {
 	payload, err := token.Payload()
 	if err != nil {
 		return nil, err
 	}
 
-	err = token.Validate(string(service.jwtService.Secret.GetSecret()))
+	err = service.jwtService.Validate(token)
 	if err != nil {
 		return nil, err
 	}
 
-	iat := int64(payload["iat"].(float64))
-	exp := int64(payload["exp"].(float64))
+	header, err := token.Header()
+	if err != nil || header.Typ != RefreshTokenJwtType {
+		return nil, ErrInvalidTokenType
+	}
+
+	return readPayload(payload)
+}
+
+func readPayload(payload map[string]interface{}) (*RefreshTokenPayload, error) {
+	if _, ok := payload["purpose"]; ok {
+		return nil, ErrInvalidPayload
+	}
+
+	sid, _ := payload["sid"].(string)
+	sub, ok := payload["sub"].(string)
+	if !ok || sub == "" {
+		return nil, ErrInvalidPayload
+	}
+
+	iat, _ := payload["iat"].(float64)
+	exp, ok := payload["exp"].(float64)
+	if !ok {
+		return nil, ErrInvalidPayload
+	}
+
+	clientId, _ := payload["client_id"].(string)
+	gid, _ := payload["gid"].(string)
+	authTime, _ := payload["auth_time"].(float64)
 
 	return &RefreshTokenPayload{
-		Sid: payload["sid"].(string),
-		Sub: payload["sub"].(string),
-		Iat: iat,
-		Exp: exp,
+		Sid:      sid,
+		Sub:      sub,
+		Amr:      readAmr(payload["amr"]),
+		ClientId: clientId,
+		Scope:    readScope(payload["scope"]),
+		Act:      readActor(payload["act"]),
+		Gid:      gid,
+		AuthTime: int64(authTime),
+		Iat:      int64(iat),
+		Exp:      int64(exp),
 	}, nil
 }
+
+func readActor(claim interface{}) *Actor {
+	values, ok := claim.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	sub, ok := values["sub"].(string)
+	if !ok {
+		return nil
+	}
+
+	clientId, _ := values["client_id"].(string)
+	return &Actor{
+		Sub:      sub,
+		ClientId: clientId,
+		Act:      readActor(values["act"]),
+	}
+}
+
+func (actor *Actor) claim() map[string]interface{} {
+	claim := map[string]interface{}{
+		"sub": actor.Sub,
+	}
+	if actor.ClientId != "" {
+		claim["client_id"] = actor.ClientId
+	}
+	if actor.Act != nil {
+		claim["act"] = actor.Act.claim()
+	}
+	return claim
+}
+
+func readAmr(claim interface{}) []string {
+	values, ok := claim.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	amr := make([]string, 0, len(values))
+	for _, value := range values {
+		if method, ok := value.(string); ok {
+			amr = append(amr, method)
+		}
+	}
+	return amr
+}
+
+func readScope(claim interface{}) []string {
+	scope, ok := claim.(string)
+	if !ok {
+		return nil
+	}
+	return strings.Fields(scope)
+}
+
+func (payload *RefreshTokenPayload) HasScope(scope string) bool {
+	for _, granted := range payload.Scope {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	jwt "github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/user"
+)
+
+type SessionTokenService struct {
+	tokenService TokenService[*RefreshTokenPayload]
+	userRepo     UserRepository
+}
+
+func (service *SessionTokenService) Init(tokenService TokenService[*RefreshTokenPayload], userRepo UserRepository) {
+	service.tokenService = tokenService
+	service.userRepo = userRepo
+}
+
+func (service *SessionTokenService) Create(ctx context.Context, payload *RefreshTokenPayload) (jwt.Jwt, error) {
+	return service.tokenService.Create(ctx, payload)
+}
+
+func (service *SessionTokenService) Validate(ctx context.Context, token jwt.Jwt) (*RefreshTokenPayload, error) {
+	payload, err := service.tokenService.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := service.userRepo.FindByIdentifier(ctx, payload.Sub)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrSessionRevoked
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.SessionsRevokedAt.IsZero() && !time.Unix(payload.Iat, 0).After(user.SessionsRevokedAt) {
+		return nil, ErrSessionRevoked
+	}
+
+	return payload, nil
+}
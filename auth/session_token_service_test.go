@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/auth"
+	jwt "github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/secret"
+	. "github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionTokenTestSuite struct {
+	suite.Suite
+	userRepo              UserRepository
+	jwtService            *jwt.JwtService[secret.SecretString]
+	challengeTokenService *ChallengeTokenService
+	service               *SessionTokenService
+}
+
+func (suite *SessionTokenTestSuite) SetupTest() {
+	suite.userRepo = new(MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &User{Identifier: "abc", Status: Active})
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	refreshTokenService := new(RefreshTokenService)
+	refreshTokenService.Init(jwtService)
+	suite.jwtService = jwtService
+	suite.challengeTokenService = new(ChallengeTokenService)
+	suite.challengeTokenService.Init(jwtService)
+
+	suite.service = new(SessionTokenService)
+	suite.service.Init(refreshTokenService, suite.userRepo)
+}
+
+func (suite *SessionTokenTestSuite) revokeAt(revokedAt time.Time) {
+	user, _ := suite.userRepo.FindByIdentifier(context.Background(), "abc")
+	user.SessionsRevokedAt = revokedAt
+	assert.Nil(suite.T(), suite.userRepo.Update(context.Background(), user))
+}
+
+func (suite *SessionTokenTestSuite) TestValidate_AcceptTokenWithoutRevocation() {
+	token, _ := suite.service.Create(context.Background(), &RefreshTokenPayload{Sid: "123", Sub: "abc"})
+
+	payload, err := suite.service.Validate(context.Background(), token)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "abc", payload.Sub)
+}
+
+func (suite *SessionTokenTestSuite) TestValidate_RejectTokenIssuedBeforeRevocation() {
+	token, _ := suite.service.Create(context.Background(), &RefreshTokenPayload{Sid: "123", Sub: "abc"})
+	suite.revokeAt(time.Now().UTC())
+
+	payload, err := suite.service.Validate(context.Background(), token)
+
+	assert.ErrorIs(suite.T(), err, ErrSessionRevoked)
+	assert.Nil(suite.T(), payload)
+}
+
+func (suite *SessionTokenTestSuite) TestValidate_AcceptTokenIssuedAfterRevocation() {
+	suite.revokeAt(time.Now().Add(-time.Minute).UTC())
+	token, _ := suite.service.Create(context.Background(), &RefreshTokenPayload{Sid: "123", Sub: "abc"})
+
+	_, err := suite.service.Validate(context.Background(), token)
+
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *SessionTokenTestSuite) TestValidate_RejectTokenOfUnknownUser() {
+	token, _ := suite.service.Create(context.Background(), &RefreshTokenPayload{Sid: "123", Sub: "unknown"})
+
+	_, err := suite.service.Validate(context.Background(), token)
+
+	assert.ErrorIs(suite.T(), err, ErrSessionRevoked)
+}
+
+func (suite *SessionTokenTestSuite) TestValidate_RejectMfaChallengeToken() {
+	token, _ := suite.challengeTokenService.Create(context.Background(), &ChallengeTokenPayload{
+		Sub:      "abc",
+		Duration: time.Minute,
+		Purpose:  MfaPurpose,
+		Amr:      []string{"pwd"},
+	})
+
+	payload, err := suite.service.Validate(context.Background(), token)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidTokenType)
+	assert.Nil(suite.T(), payload)
+}
+
+func (suite *SessionTokenTestSuite) TestValidate_RejectTokenWithChallengePurpose() {
+	token, _ := suite.jwtService.CreateTyped(RefreshTokenJwtType, map[string]interface{}{
+		"sub":     "abc",
+		"purpose": string(MagicLinkPurpose),
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+
+	payload, err := suite.service.Validate(context.Background(), token)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidPayload)
+	assert.Nil(suite.T(), payload)
+}
+
+func TestSessionTokenService(t *testing.T) {
+	suite.Run(t, new(SessionTokenTestSuite))
+}
@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"context"
+
 	jwt "github.com/Untanky/go-id/jwt"
 )
 
 type TokenService[Payload any] interface {
-	Create(payload Payload) (jwt.Jwt, error)
-	Validate(token jwt.Jwt) (Payload, error)
+	Create(ctx context.Context, payload Payload) (jwt.Jwt, error)
+	Validate(ctx context.Context, token jwt.Jwt) (Payload, error)
 }
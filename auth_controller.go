@@ -1,30 +1,53 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/base64"
-	"github.com/Untanky/go-id/user"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/Untanky/go-id/federation"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/user"
+
 	"github.com/Untanky/go-id/auth"
 	"github.com/gin-gonic/gin"
 )
 
-const AuthorizationHeader = "Authorization"
+const (
+	AuthorizationHeader   = "Authorization"
+	MagicLinkCookie       = "magic_link_binding"
+	FederationStateCookie = "federation_state"
+	FederationLinkCookie  = "federation_link_state"
+)
 
 type AuthController struct {
 	authService           *auth.LoginService
+	mfaService            *mfa.MfaService
+	magicLinkService      *auth.MagicLinkService
+	federationService     *federation.FederationService
 	refreshTokenService   auth.TokenService[*auth.RefreshTokenPayload]
 	challengeTokenService auth.TokenService[*auth.ChallengeTokenPayload]
 }
 
 func (controller *AuthController) Init(
 	authService *auth.LoginService,
+	mfaService *mfa.MfaService,
+	magicLinkService *auth.MagicLinkService,
+	federationService *federation.FederationService,
 	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload],
 	challengeTokenService auth.TokenService[*auth.ChallengeTokenPayload],
 ) {
 	controller.authService = authService
+	controller.mfaService = mfaService
+	controller.magicLinkService = magicLinkService
+	controller.federationService = federationService
 	controller.refreshTokenService = refreshTokenService
 	controller.challengeTokenService = challengeTokenService
 }
@@ -35,7 +58,181 @@ func (controller *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	loggedInUser, err := controller.authService.Login(userId, password)
+	loggedInUser, err := controller.authService.Login(c.Request.Context(), userId, password)
+	if err != nil {
+		respondAuthFailure(c, err)
+		return
+	}
+
+	controller.completeLogin(c, loggedInUser.Identifier, []string{"pwd"})
+}
+
+func (controller *AuthController) RequestMagicLink(c *gin.Context) {
+	var request struct {
+		Identifier string `json:"identifier"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Identifier == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "identifier required",
+		})
+		return
+	}
+
+	binding, err := controller.magicLinkService.Decoy()
+	if _, loginErr := controller.authService.LoginPasswordless(c.Request.Context(), request.Identifier); loginErr == nil {
+		binding, err = controller.magicLinkService.Send(c.Request.Context(), request.Identifier)
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "magic link could not be sent",
+		})
+		return
+	}
+
+	c.SetCookie(MagicLinkCookie, binding, 15*60, "/", "", true, true)
+	c.JSON(http.StatusAccepted, nil)
+}
+
+func (controller *AuthController) VerifyMagicLink(c *gin.Context) {
+	binding, err := c.Cookie(MagicLinkCookie)
+	if err != nil || binding == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "magic link must be opened in the requesting browser",
+		})
+		return
+	}
+
+	identifier, err := controller.magicLinkService.Redeem(c.Request.Context(), jwt.Jwt(c.Query("token")), binding)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "cannot validate magic link",
+		})
+		return
+	}
+
+	loggedInUser, err := controller.authService.LoginPasswordless(c.Request.Context(), identifier)
+	if err != nil {
+		respondAuthFailure(c, err)
+		return
+	}
+
+	c.SetCookie(MagicLinkCookie, "", -1, "/", "", true, true)
+	controller.completeLogin(c, loggedInUser.Identifier, []string{"email"})
+}
+
+func (controller *AuthController) BeginFederatedLogin(c *gin.Context) {
+	authorization, err := controller.federationService.Begin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		respondProblem(c, http.StatusBadGateway, err)
+		return
+	}
+
+	c.SetCookie(FederationStateCookie, authorization.State, 10*60, "/", "", true, true)
+	c.Redirect(http.StatusFound, authorization.URL)
+}
+
+func (controller *AuthController) CompleteFederatedLogin(c *gin.Context) {
+	if state, ok := federationState(c, FederationLinkCookie); ok {
+		controller.completeFederatedLink(c, state)
+		return
+	}
+
+	state, ok := federationState(c, FederationStateCookie)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "federated login must be completed in the requesting browser",
+		})
+		return
+	}
+
+	if c.Query("error") != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "identity provider rejected the login",
+		})
+		return
+	}
+
+	federatedUser, err := controller.federationService.Complete(c.Request.Context(), c.Param("provider"), state, c.Query("code"))
+	if err != nil {
+		respondProblem(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	loggedInUser, err := controller.authService.LoginPasswordless(c.Request.Context(), federatedUser.Identifier)
+	if err != nil {
+		respondAuthFailure(c, err)
+		return
+	}
+
+	controller.completeLogin(c, loggedInUser.Identifier, []string{"fed"})
+}
+
+func (controller *AuthController) completeFederatedLink(c *gin.Context, state string) {
+	if c.Query("error") != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "identity provider rejected the login",
+		})
+		return
+	}
+
+	linkedUser, err := controller.federationService.CompleteLink(c.Request.Context(), c.Param("provider"), state, c.Query("code"))
+	if err != nil {
+		respondProblem(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newIdentityResponses(linkedUser))
+}
+
+func federationState(c *gin.Context, cookie string) (string, bool) {
+	state, err := c.Cookie(cookie)
+	if err != nil || state == "" || state != c.Query("state") {
+		return "", false
+	}
+
+	c.SetCookie(cookie, "", -1, "/", "", true, true)
+	return state, true
+}
+
+func (controller *AuthController) Mfa(c *gin.Context) {
+	challengeString := c.Request.Header.Get(ChallengeHeader)
+	if challengeString == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "mfa token required",
+		})
+		return
+	}
+
+	payload, err := controller.challengeTokenService.Validate(c.Request.Context(), jwt.Jwt(challengeString))
+	if err != nil {
+		respondProblem(c, http.StatusUnauthorized, fmt.Errorf("cannot validate mfa token: %w", err))
+		return
+	}
+
+	if payload.Purpose != auth.MfaPurpose {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "cannot validate mfa token",
+		})
+		return
+	}
+
+	otp, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "cannot read body",
+		})
+		return
+	}
+
+	factor, err := controller.mfaService.Verify(payload.Sub, strings.TrimSpace(string(otp)), payload.Event)
+	if errors.Is(err, mfa.ErrChallengeNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "mfa token is invalid or already used",
+		})
+		return
+	}
+
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"message": "unauthorized",
@@ -43,11 +240,83 @@ func (controller *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	amr := append(payload.Amr, "mfa", factor.Amr())
+	issueRefreshToken(c, controller.authService, controller.refreshTokenService, payload.Sub, amr)
+}
+
+func (controller *AuthController) completeLogin(c *gin.Context, identifier string, amr []string) {
+	enrolled, err := controller.mfaService.IsEnrolled(identifier)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "factors could not be loaded",
+		})
+		return
+	}
+
+	if enrolled {
+		controller.requireMfa(c, identifier, amr)
+		return
+	}
+
+	issueRefreshToken(c, controller.authService, controller.refreshTokenService, identifier, amr)
+}
+
+func (controller *AuthController) requireMfa(c *gin.Context, identifier string, amr []string) {
+	event, err := rand.Int(rand.Reader, big.NewInt(1<<53))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "token could not be created",
+		})
+		return
+	}
+
+	err = controller.mfaService.Challenge(identifier, event.Int64())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "mfa challenge could not be sent",
+		})
+		return
+	}
+
+	payload := &auth.ChallengeTokenPayload{
+		Sub:      identifier,
+		Duration: mfa.ChallengeLifetime,
+		Event:    event.Int64(),
+		Purpose:  auth.MfaPurpose,
+		Amr:      amr,
+	}
+	token, err := controller.challengeTokenService.Create(c.Request.Context(), payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "token could not be created",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"mfaToken": token,
+	})
+}
+
+func issueRefreshToken(
+	c *gin.Context,
+	authService *auth.LoginService,
+	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	identifier string,
+	amr []string,
+) {
+	if err := authService.RecordLogin(c.Request.Context(), identifier); err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
 	payload := auth.RefreshTokenPayload{
-		Sid: "123",
-		Sub: loggedInUser.Identifier,
+		Sid:      "123",
+		Sub:      identifier,
+		Amr:      amr,
+		AuthTime: time.Now().Unix(),
 	}
-	token, err := controller.refreshTokenService.Create(&payload)
+	token, err := refreshTokenService.Create(c.Request.Context(), &payload)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "token could not be created",
@@ -69,13 +338,11 @@ func (controller *AuthController) Register(c *gin.Context) {
 	newUser := new(user.User)
 	newUser.Identifier = userId
 	newUser.Passkey = password
-	newUser.Status = user.Inactive
+	newUser.Status = user.PendingVerification
 
-	err := controller.authService.Register(newUser)
+	err := controller.authService.Register(c.Request.Context(), newUser)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "userId already exists",
-		})
+		respondProblem(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -85,8 +352,9 @@ func (controller *AuthController) Register(c *gin.Context) {
 		Sub:      userId,
 		Duration: duration,
 		Event:    6,
+		Purpose:  auth.VerifyEmailPurpose,
 	}
-	_, err = controller.challengeTokenService.Create(payload)
+	_, err = controller.challengeTokenService.Create(c.Request.Context(), payload)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "token could not be created",
@@ -97,6 +365,31 @@ func (controller *AuthController) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, nil)
 }
 
+func (controller *AuthController) ChangePasskey(c *gin.Context) {
+	userId, password, shouldReturn := controller.decodeBasicAuthHeader(c)
+	if shouldReturn {
+		return
+	}
+
+	var request struct {
+		Passkey string `json:"passkey"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Passkey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "new passkey required",
+		})
+		return
+	}
+
+	err := controller.authService.ChangePasskey(c.Request.Context(), userId, password, request.Passkey)
+	if err != nil {
+		respondAuthFailure(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
 func (*AuthController) decodeBasicAuthHeader(c *gin.Context) (string, string, bool) {
 	basic := c.Request.Header.Get(AuthorizationHeader)
 
@@ -1,16 +1,25 @@
 package main_test
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/Untanky/go-id"
 	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/federation"
 	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/mfa"
 	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
 	"github.com/Untanky/go-id/user"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -20,8 +29,29 @@ import (
 type AuthControllerSuite struct {
 	suite.Suite
 
-	knownUsers []*user.User
-	controller *AuthController
+	knownUsers            []*user.User
+	userRepo              user.UserRepository
+	challengeTokenService auth.TokenService[*auth.ChallengeTokenPayload]
+	mfaService            *mfa.MfaService
+	linkSender            *capturingLinkSender
+	provider              *federation.MockProvider
+	federationService     *federation.FederationService
+	controller            *AuthController
+}
+
+type noopSender struct{}
+
+type capturingLinkSender struct {
+	links map[string]jwt.Jwt
+}
+
+func (sender *capturingLinkSender) SendLink(identifier string, token jwt.Jwt) error {
+	sender.links[identifier] = token
+	return nil
+}
+
+func (noopSender) Send(factor *mfa.Factor, otp string) error {
+	return nil
 }
 
 func (suite *AuthControllerSuite) SetupTest() {
@@ -29,13 +59,15 @@ func (suite *AuthControllerSuite) SetupTest() {
 
 	suite.knownUsers = []*user.User{
 		{Identifier: "user", Passkey: "Test1Test!", Status: user.Active},
+		{Identifier: "mfaUser", Passkey: "Test1Test!", Status: user.Active, Email: "mfa@example.com", EmailVerified: true},
 	}
 
+	suite.userRepo = new(user.MemoryUserRepository)
 	authService := new(auth.LoginService)
-	authService.Init(new(user.MemoryUserRepository), auth.NewArgon2Encrypter())
+	authService.Init(suite.userRepo, auth.NewArgon2Encrypter())
 
 	for _, u := range suite.knownUsers {
-		authService.Register(u)
+		authService.Register(context.Background(), u)
 	}
 
 	jwtService := new(jwt.JwtService[secret.SecretString])
@@ -45,9 +77,46 @@ func (suite *AuthControllerSuite) SetupTest() {
 
 	challengeTokenService := new(auth.ChallengeTokenService)
 	challengeTokenService.Init(jwtService)
+	suite.challengeTokenService = challengeTokenService
+
+	otpService := new(totp.OtpService)
+	otpService.Init(30)
+
+	factorRepo := new(mfa.MemoryFactorRepository)
+	factorRepo.Create(&mfa.Factor{
+		Identifier: "totp",
+		UserId:     "mfaUser",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("secret"),
+		},
+	})
+	mfaService := new(mfa.MfaService)
+	mfaService.Init(factorRepo, new(mfa.MemoryRecoveryCodeRepository), otpService, noopSender{})
+	suite.mfaService = mfaService
+
+	suite.linkSender = &capturingLinkSender{links: map[string]jwt.Jwt{}}
+	magicLinkService := new(auth.MagicLinkService)
+	magicLinkService.Init(challengeTokenService, suite.linkSender)
+
+	signingService := new(jwt.JwtService[secret.KeyPair])
+	signingService.Init(jwt.RS256, secret.NewSecretPair(oidcKeyPair))
+	suite.provider = new(federation.MockProvider)
+	suite.provider.Init(signingService, "go-id", "secret")
+	relyingParty := new(federation.RelyingParty)
+	relyingParty.Init(&federation.Provider{
+		Name:         "corp",
+		Issuer:       suite.provider.Issuer(),
+		ClientId:     "go-id",
+		ClientSecret: "secret",
+		RedirectURI:  oidcIssuer + "/login/corp/callback",
+		Scopes:       []string{"email"},
+	}, suite.provider.Client())
+	suite.federationService = new(federation.FederationService)
+	suite.federationService.Init(suite.userRepo, []*federation.RelyingParty{relyingParty})
 
 	controller := new(AuthController)
-	controller.Init(authService, refreshTokenService, challengeTokenService)
+	controller.Init(authService, mfaService, magicLinkService, suite.federationService, refreshTokenService, challengeTokenService)
 	suite.controller = controller
 
 	assert.NotNil(suite.T(), controller)
@@ -62,6 +131,10 @@ func buildContext() (*httptest.ResponseRecorder, *gin.Context) {
 	return w, context
 }
 
+func (suite *AuthControllerSuite) TearDownTest() {
+	suite.provider.Close()
+}
+
 func (suite *AuthControllerSuite) TestLogin_SucceedWithBasicToken() {
 	w, context := buildContext()
 	context.Request.Header.Add(AuthorizationHeader, "Basic dXNlcjpUZXN0MVRlc3Qh")
@@ -74,7 +147,373 @@ func (suite *AuthControllerSuite) TestLogin_SucceedWithBasicToken() {
 	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
 	result := regex.FindStringSubmatch(string(body))
 	token := jwt.Jwt(result[1])
-	assert.Nil(suite.T(), token.Validate("secret"))
+	assert.Nil(suite.T(), token.Validate(jwt.HS256, "secret"))
+}
+
+func (suite *AuthControllerSuite) TestLogin_RequireMfaWhenFactorEnrolled() {
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic bWZhVXNlcjpUZXN0MVRlc3Qh")
+
+	suite.controller.Login(context)
+
+	assert.Equal(suite.T(), 202, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.NotContains(suite.T(), string(body), "\"refreshToken\"")
+	regex := regexp.MustCompile(`"mfaToken"[:]"(?P<Token>.*)"`)
+	result := regex.FindStringSubmatch(string(body))
+	payload, err := suite.challengeTokenService.Validate(context.Request.Context(), jwt.Jwt(result[1]))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "mfaUser", payload.Sub)
+	assert.Equal(suite.T(), auth.MfaPurpose, payload.Purpose)
+}
+
+func (suite *AuthControllerSuite) loginWithMfa() string {
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic bWZhVXNlcjpUZXN0MVRlc3Qh")
+
+	suite.controller.Login(context)
+
+	var body map[string]string
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	return body["mfaToken"]
+}
+
+func (suite *AuthControllerSuite) submitMfa(token string, otp string) *httptest.ResponseRecorder {
+	w, context := buildContext()
+	context.Request.Header.Set(ChallengeHeader, token)
+	context.Request.Body = io.NopCloser(strings.NewReader(otp))
+
+	suite.controller.Mfa(context)
+
+	return w
+}
+
+func (suite *AuthControllerSuite) TestMfa_SucceedWithValidOtp() {
+	w := suite.submitMfa(suite.loginWithMfa(), totp.GenerateTotp("secret", 30))
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	result := regex.FindStringSubmatch(string(body))
+	refreshToken := jwt.Jwt(result[1])
+	payload, err := refreshToken.Payload()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "mfaUser", payload["sub"])
+	assert.Equal(suite.T(), []interface{}{"pwd", "mfa", "otp"}, payload["amr"])
+}
+
+func (suite *AuthControllerSuite) TestMfa_SucceedWithRecoveryCode() {
+	codes, _ := suite.mfaService.RegenerateRecoveryCodes("mfaUser")
+
+	w := suite.submitMfa(suite.loginWithMfa(), codes[0])
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	result := regex.FindStringSubmatch(string(body))
+	refreshToken := jwt.Jwt(result[1])
+	payload, _ := refreshToken.Payload()
+	assert.Equal(suite.T(), []interface{}{"pwd", "mfa", "recovery"}, payload["amr"])
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailWithInvalidOtp() {
+	w := suite.submitMfa(suite.loginWithMfa(), "000000")
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "unauthorized")
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailWithRefreshTokenAsChallenge() {
+	w := suite.submitMfa(suite.loginWithMfa(), totp.GenerateTotp("secret", 30))
+	body, _ := io.ReadAll(w.Result().Body)
+	result := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`).FindStringSubmatch(string(body))
+
+	w = suite.submitMfa(result[1], totp.GenerateTotp("secret", 30))
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailWhenMfaTokenIsReplayed() {
+	token := suite.loginWithMfa()
+	otp := totp.GenerateTotp("secret", 30)
+
+	w := suite.submitMfa(token, otp)
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+
+	w = suite.submitMfa(token, otp)
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "mfa token is invalid or already used")
+	assert.NotContains(suite.T(), string(body), "refreshToken")
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailAfterTooManyInvalidOtps() {
+	token := suite.loginWithMfa()
+
+	for i := 0; i < 5; i++ {
+		w := suite.submitMfa(token, "000000")
+		assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	}
+
+	w := suite.submitMfa(token, totp.GenerateTotp("secret", 30))
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.NotContains(suite.T(), string(body), "refreshToken")
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailWithForgedMfaToken() {
+	token := suite.createChallengeToken(&auth.ChallengeTokenPayload{Sub: "mfaUser", Purpose: auth.MfaPurpose, Amr: []string{"pwd"}})
+
+	w := suite.submitMfa(string(token), totp.GenerateTotp("secret", 30))
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailWithoutMfaToken() {
+	w, context := buildContext()
+
+	suite.controller.Mfa(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "mfa token required")
+}
+
+func (suite *AuthControllerSuite) TestMfa_FailWithOtherChallengePurpose() {
+	w, context := buildContext()
+	token := suite.createChallengeToken(&auth.ChallengeTokenPayload{Sub: "mfaUser", Purpose: auth.VerifyEmailPurpose})
+	context.Request.Header.Set(ChallengeHeader, string(token))
+	context.Request.Body = io.NopCloser(strings.NewReader(totp.GenerateTotp("secret", 30)))
+
+	suite.controller.Mfa(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "cannot validate mfa token")
+}
+
+func (suite *AuthControllerSuite) createChallengeToken(payload *auth.ChallengeTokenPayload) jwt.Jwt {
+	payload.Duration, _ = time.ParseDuration("5m")
+	token, _ := suite.challengeTokenService.Create(context.Background(), payload)
+	return token
+}
+
+func (suite *AuthControllerSuite) requestMagicLink(identifier string) *http.Cookie {
+	w, context := buildContext()
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"identifier":"` + identifier + `"}`))
+
+	suite.controller.RequestMagicLink(context)
+
+	assert.Equal(suite.T(), 202, w.Result().StatusCode)
+	cookies := w.Result().Cookies()
+	assert.Len(suite.T(), cookies, 1)
+	assert.Equal(suite.T(), MagicLinkCookie, cookies[0].Name)
+	return cookies[0]
+}
+
+func (suite *AuthControllerSuite) verifyMagicLink(token jwt.Jwt, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{RawQuery: url.Values{"token": {string(token)}}.Encode()}
+	if cookie != nil {
+		context.Request.AddCookie(cookie)
+	}
+
+	suite.controller.VerifyMagicLink(context)
+	return w
+}
+
+func (suite *AuthControllerSuite) TestMagicLink_SucceedInRequestingBrowser() {
+	cookie := suite.requestMagicLink("user")
+
+	w := suite.verifyMagicLink(suite.linkSender.links["user"], cookie)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	token := jwt.Jwt(regex.FindStringSubmatch(string(body))[1])
+	assert.Nil(suite.T(), token.Validate(jwt.HS256, "secret"))
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), "user", payload["sub"])
+	assert.Equal(suite.T(), []interface{}{"email"}, payload["amr"])
+}
+
+func (suite *AuthControllerSuite) TestMagicLink_RequireMfaWhenFactorEnrolled() {
+	cookie := suite.requestMagicLink("mfaUser")
+
+	w := suite.verifyMagicLink(suite.linkSender.links["mfaUser"], cookie)
+
+	assert.Equal(suite.T(), 202, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"mfaToken"[:]"(?P<Token>.*)"`)
+	payload, err := suite.challengeTokenService.Validate(context.Background(), jwt.Jwt(regex.FindStringSubmatch(string(body))[1]))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"email"}, payload.Amr)
+}
+
+func (suite *AuthControllerSuite) TestMagicLink_FailWhenUsedTwice() {
+	cookie := suite.requestMagicLink("user")
+	token := suite.linkSender.links["user"]
+
+	w := suite.verifyMagicLink(token, cookie)
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+
+	w = suite.verifyMagicLink(token, cookie)
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "cannot validate magic link")
+}
+
+func (suite *AuthControllerSuite) TestMagicLink_FailInOtherBrowser() {
+	suite.requestMagicLink("user")
+	otherCookie := suite.requestMagicLink("mfaUser")
+
+	w := suite.verifyMagicLink(suite.linkSender.links["user"], otherCookie)
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+
+	w = suite.verifyMagicLink(suite.linkSender.links["user"], nil)
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "requesting browser")
+}
+
+func (suite *AuthControllerSuite) TestMagicLink_DoNotSendForUnknownUser() {
+	suite.requestMagicLink("unknown")
+
+	assert.NotContains(suite.T(), suite.linkSender.links, "unknown")
+}
+
+func (suite *AuthControllerSuite) TestMagicLink_SetIndistinguishableCookieForUnknownUser() {
+	known := suite.requestMagicLink("user")
+	unknown := suite.requestMagicLink("unknown")
+
+	assert.NotEmpty(suite.T(), unknown.Value)
+	assert.Len(suite.T(), unknown.Value, len(known.Value))
+	assert.NotEqual(suite.T(), known.Value, unknown.Value)
+	assert.Equal(suite.T(), known.MaxAge, unknown.MaxAge)
+}
+
+func (suite *AuthControllerSuite) beginFederatedLogin(provider string) *httptest.ResponseRecorder {
+	w, context := buildContext()
+	context.Request.Method = http.MethodGet
+	context.Request.URL = &url.URL{}
+	context.Params = gin.Params{{Key: "provider", Value: provider}}
+
+	suite.controller.BeginFederatedLogin(context)
+	return w
+}
+
+func (suite *AuthControllerSuite) completeFederatedLogin(callback *url.URL, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w, context := buildContext()
+	context.Request.URL = callback
+	context.Params = gin.Params{{Key: "provider", Value: "corp"}}
+	if cookie != nil {
+		context.Request.AddCookie(cookie)
+	}
+
+	suite.controller.CompleteFederatedLogin(context)
+	return w
+}
+
+func (suite *AuthControllerSuite) federatedLogin(claims map[string]interface{}) *httptest.ResponseRecorder {
+	w := suite.beginFederatedLogin("corp")
+	assert.Equal(suite.T(), 302, w.Result().StatusCode)
+	cookies := w.Result().Cookies()
+	assert.Len(suite.T(), cookies, 1)
+	assert.Equal(suite.T(), FederationStateCookie, cookies[0].Name)
+
+	callback, err := suite.provider.SignIn(w.Result().Header.Get("Location"), claims)
+	suite.Require().Nil(err)
+	return suite.completeFederatedLogin(callback, cookies[0])
+}
+
+func (suite *AuthControllerSuite) TestFederatedLogin_ProvisionUserAndIssueRefreshToken() {
+	w := suite.federatedLogin(map[string]interface{}{"sub": "123", "email": "carol@example.com", "email_verified": true})
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	token := jwt.Jwt(regex.FindStringSubmatch(string(body))[1])
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), "corp:123", payload["sub"])
+	assert.Equal(suite.T(), []interface{}{"fed"}, payload["amr"])
+	provisioned, err := suite.userRepo.FindByIdentifier(context.Background(), "corp:123")
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), provisioned.LastLoginAt.IsZero())
+}
+
+func (suite *AuthControllerSuite) TestFederatedLogin_RequireMfaForLinkedUser() {
+	w := suite.federatedLogin(map[string]interface{}{"sub": "123", "email": "mfa@example.com", "email_verified": true})
+
+	assert.Equal(suite.T(), 202, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"mfaToken"[:]"(?P<Token>.*)"`)
+	payload, err := suite.challengeTokenService.Validate(context.Background(), jwt.Jwt(regex.FindStringSubmatch(string(body))[1]))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "mfaUser", payload.Sub)
+	assert.Equal(suite.T(), []string{"fed"}, payload.Amr)
+}
+
+func (suite *AuthControllerSuite) TestFederatedLogin_FailInOtherBrowser() {
+	w := suite.beginFederatedLogin("corp")
+	callback, _ := suite.provider.SignIn(w.Result().Header.Get("Location"), map[string]interface{}{"sub": "123"})
+
+	w = suite.completeFederatedLogin(callback, nil)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "requesting browser")
+}
+
+func (suite *AuthControllerSuite) TestFederatedLogin_FailForSuspendedUser() {
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "suspended", Status: user.Suspended, SuspensionReason: "abuse", Identities: []user.Identity{{Provider: "corp", Subject: "123"}}})
+
+	w := suite.federatedLogin(map[string]interface{}{"sub": "123"})
+
+	assert.Equal(suite.T(), 403, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "/problems/suspended")
+	assert.NotContains(suite.T(), string(body), "abuse")
+}
+
+func (suite *AuthControllerSuite) TestFederatedLink_LoginByLinkedIdentity() {
+	authorization, _ := suite.federationService.BeginLink(context.Background(), "corp", "user")
+	callback, _ := suite.provider.SignIn(authorization.URL, map[string]interface{}{"sub": "123"})
+
+	w := suite.completeFederatedLogin(callback, &http.Cookie{Name: FederationLinkCookie, Value: authorization.State})
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var identities []map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&identities)
+	assert.Len(suite.T(), identities, 1)
+	assert.Equal(suite.T(), "corp", identities[0]["provider"])
+	assert.Equal(suite.T(), "123", identities[0]["subject"])
+
+	w = suite.federatedLogin(map[string]interface{}{"sub": "123"})
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	token := jwt.Jwt(regex.FindStringSubmatch(string(body))[1])
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), "user", payload["sub"])
+}
+
+func (suite *AuthControllerSuite) TestFederatedLink_FailWithLoginCookie() {
+	authorization, _ := suite.federationService.BeginLink(context.Background(), "corp", "user")
+	callback, _ := suite.provider.SignIn(authorization.URL, map[string]interface{}{"sub": "123"})
+
+	w := suite.completeFederatedLogin(callback, &http.Cookie{Name: FederationStateCookie, Value: authorization.State})
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	linked, _ := suite.userRepo.FindByIdentifier(context.Background(), "user")
+	assert.Empty(suite.T(), linked.Identities)
+}
+
+func (suite *AuthControllerSuite) TestBeginFederatedLogin_FailForUnknownProvider() {
+	w := suite.beginFederatedLogin("unknown")
+
+	assert.Equal(suite.T(), 404, w.Result().StatusCode)
+	assert.Empty(suite.T(), w.Result().Cookies())
 }
 
 func (suite *AuthControllerSuite) TestLogin_FailWithoutAuthorizationHeader() {
@@ -167,9 +606,95 @@ func (suite *AuthControllerSuite) TestRegister_FailWhenUserIdAlreadyExists() {
 
 	suite.controller.Register(context)
 
-	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	assert.Equal(suite.T(), 409, w.Result().StatusCode)
+	assert.Equal(suite.T(), ProblemContentType, w.Result().Header.Get("Content-Type"))
 	body, _ := io.ReadAll(w.Result().Body)
-	assert.Contains(suite.T(), string(body), "userId already exists")
+	assert.Contains(suite.T(), string(body), "user already exists")
+}
+
+func (suite *AuthControllerSuite) TestRegister_FailWithPolicyViolation() {
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic "+base64.StdEncoding.EncodeToString([]byte("lukas:short")))
+
+	suite.controller.Register(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	var problem Problem
+	json.NewDecoder(w.Result().Body).Decode(&problem)
+	assert.Equal(suite.T(), "/problems/policy-violation", problem.Type)
+	assert.Contains(suite.T(), problem.Violations, "Passkey too short")
+}
+
+func (suite *AuthControllerSuite) requirePasswordReset(identifier string) {
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), identifier)
+	found.PasswordResetRequired = true
+	assert.Nil(suite.T(), suite.userRepo.Update(context.Background(), found))
+}
+
+func (suite *AuthControllerSuite) TestLogin_FailWhenPasswordResetRequired() {
+	suite.requirePasswordReset("user")
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic dXNlcjpUZXN0MVRlc3Qh")
+
+	suite.controller.Login(context)
+
+	assert.Equal(suite.T(), 403, w.Result().StatusCode)
+	var problem Problem
+	json.NewDecoder(w.Result().Body).Decode(&problem)
+	assert.Equal(suite.T(), "/problems/password-reset-required", problem.Type)
+}
+
+func (suite *AuthControllerSuite) TestChangePasskey_AllowLoginWithNewPasskey() {
+	suite.requirePasswordReset("user")
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic dXNlcjpUZXN0MVRlc3Qh")
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"passkey":"N3w-Passkey!"}`))
+
+	suite.controller.ChangePasskey(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+
+	w, context = buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:N3w-Passkey!")))
+	suite.controller.Login(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+}
+
+func (suite *AuthControllerSuite) TestLogin_RespondSameProblemForUnknownUser() {
+	unknownW, unknownContext := buildContext()
+	unknownContext.Request.SetBasicAuth("nobody", "fail")
+	suite.controller.Login(unknownContext)
+
+	wrongW, wrongContext := buildContext()
+	wrongContext.Request.SetBasicAuth("user", "fail")
+	suite.controller.Login(wrongContext)
+
+	assert.Equal(suite.T(), 401, unknownW.Result().StatusCode)
+	unknownBody, _ := io.ReadAll(unknownW.Result().Body)
+	wrongBody, _ := io.ReadAll(wrongW.Result().Body)
+	assert.Equal(suite.T(), string(wrongBody), string(unknownBody))
+	assert.Contains(suite.T(), string(unknownBody), "/problems/unauthorized")
+}
+
+func (suite *AuthControllerSuite) TestChangePasskey_FailWhenCredentialsDoNotMatch() {
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic dXNlcjpmYWls")
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"passkey":"N3w-Passkey!"}`))
+
+	suite.controller.ChangePasskey(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *AuthControllerSuite) TestChangePasskey_FailWithoutNewPasskey() {
+	w, context := buildContext()
+	context.Request.Header.Add(AuthorizationHeader, "Basic dXNlcjpUZXN0MVRlc3Qh")
+	context.Request.Body = io.NopCloser(strings.NewReader(`{}`))
+
+	suite.controller.ChangePasskey(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
 }
 
 func TestAuthController(t *testing.T) {
@@ -47,12 +47,10 @@ func (controller *ChallengeController) VerifyEmail(context *gin.Context) {
 
 	token := jwt.Jwt(string(challengeString))
 
-	payload, err := controller.tokenService.Validate(token)
+	payload, err := controller.tokenService.Validate(context.Request.Context(), token)
 
 	if err != nil {
-		context.JSON(http.StatusUnauthorized, gin.H{
-			"message": "cannot validate challenge token",
-		})
+		respondProblem(context, http.StatusUnauthorized, fmt.Errorf("cannot validate challenge token: %w", err))
 		return
 	}
 
@@ -1,6 +1,7 @@
 package main_test
 
 import (
+	"context"
 	"github.com/Untanky/go-id/auth"
 	"github.com/Untanky/go-id/jwt"
 	"github.com/Untanky/go-id/secret"
@@ -40,11 +41,11 @@ func (suite *ChallengeControllerSuite) SetupTest() {
 
 	suite.user = &user.User{
 		Identifier: "abc",
-		Status:     user.Inactive,
+		Status:     user.PendingVerification,
 	}
 
 	userRepo := new(user.MemoryUserRepository)
-	userRepo.Create(suite.user)
+	userRepo.Create(context.Background(), suite.user)
 
 	userService := new(user.UserService)
 	userService.Init(userRepo)
@@ -75,7 +76,7 @@ func (suite ChallengeControllerSuite) TestVerifyEmail_DoNothing() {
 	context.BindJSON(gin.H{"password": password})
 	w.Flush()
 
-	token, _ := suite.challengeTokenService.Create(payload)
+	token, _ := suite.challengeTokenService.Create(context.Request.Context(), payload)
 
 	context.Request.Header.Set(ChallengeHeader, string(token))
 	suite.controller.VerifyEmail(context)
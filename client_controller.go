@@ -0,0 +1,220 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/gin-gonic/gin"
+)
+
+type ClientController struct {
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	clientService      *oauth.ClientService
+	auditRepo          audit.AuditRepository
+}
+
+type clientResponse struct {
+	*oauth.ClientMetadata
+	ClientId              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	ClientIdIssuedAt      int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt *int64 `json:"client_secret_expires_at,omitempty"`
+}
+
+func (controller *ClientController) Init(
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	clientService *oauth.ClientService,
+	auditRepo audit.AuditRepository,
+) {
+	controller.accessTokenService = accessTokenService
+	controller.clientService = clientService
+	controller.auditRepo = auditRepo
+}
+
+func (controller *ClientController) Register(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	metadata := new(oauth.ClientMetadata)
+	if err := c.ShouldBindJSON(metadata); err != nil {
+		respondOAuthError(c, &oauth.Error{Code: oauth.ErrInvalidClientMetadata.Code, Description: "client metadata must be a JSON object"})
+		return
+	}
+
+	metadata.AccessTokenLifetime = 0
+	metadata.RefreshTokenLifetime = 0
+
+	client, secret, err := controller.clientService.Register(c.Request.Context(), metadata)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newClientResponse(client, secret))
+}
+
+func (controller *ClientController) ListClients(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	clients, err := controller.clientService.List(c.Request.Context())
+	if recordAudit(c, controller.auditRepo, admin, "client.list", "", err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := make([]*clientResponse, len(clients))
+	for i, client := range clients {
+		response[i] = newClientResponse(client, "")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clients": response,
+	})
+}
+
+func (controller *ClientController) GetClient(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	identifier := c.Param("identifier")
+	client, err := controller.clientService.Find(c.Request.Context(), identifier)
+	if recordAudit(c, controller.auditRepo, admin, "client.read", identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newClientResponse(client, ""))
+}
+
+func (controller *ClientController) CreateClient(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	metadata := new(oauth.ClientMetadata)
+	if err := c.ShouldBindJSON(metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid client metadata",
+		})
+		return
+	}
+
+	client, secret, err := controller.clientService.RegisterTrusted(c.Request.Context(), metadata)
+	target := ""
+	if client != nil {
+		target = client.Identifier
+	}
+	if recordAudit(c, controller.auditRepo, admin, "client.create", target, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newClientResponse(client, secret))
+}
+
+func (controller *ClientController) UpdateClient(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	metadata := new(oauth.ClientMetadata)
+	if err := c.ShouldBindJSON(metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid client metadata",
+		})
+		return
+	}
+
+	identifier := c.Param("identifier")
+	client, err := controller.clientService.Update(c.Request.Context(), identifier, metadata)
+	if recordAudit(c, controller.auditRepo, admin, "client.update", identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newClientResponse(client, ""))
+}
+
+func (controller *ClientController) RotateClientSecret(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	identifier := c.Param("identifier")
+	secret, err := controller.clientService.RotateSecret(c.Request.Context(), identifier)
+	if recordAudit(c, controller.auditRepo, admin, "client.rotate_secret", identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"client_id":     identifier,
+		"client_secret": secret,
+	})
+}
+
+func (controller *ClientController) DeleteClient(c *gin.Context) {
+	admin, shouldReturn := authenticateAdmin(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	identifier := c.Param("identifier")
+	err := controller.clientService.Remove(c.Request.Context(), identifier)
+	if recordAudit(c, controller.auditRepo, admin, "client.delete", identifier, err) {
+		return
+	}
+
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func newClientResponse(client *oauth.Client, secret string) *clientResponse {
+	response := &clientResponse{
+		ClientMetadata:   oauth.NewClientMetadata(client),
+		ClientId:         client.Identifier,
+		ClientSecret:     secret,
+		ClientIdIssuedAt: client.CreatedAt.Unix(),
+	}
+
+	if secret != "" {
+		expiresAt := int64(0)
+		response.ClientSecretExpiresAt = &expiresAt
+	}
+	return response
+}
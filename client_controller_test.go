@@ -0,0 +1,213 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientControllerSuite struct {
+	suite.Suite
+
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	clientRepo         oauth.ClientRepository
+	auditRepo          audit.AuditRepository
+	controller         *ClientController
+}
+
+type registrationResponse struct {
+	ClientId                string   `json:"client_id"`
+	ClientSecret            string   `json:"client_secret"`
+	ClientSecretExpiresAt   *int64   `json:"client_secret_expires_at"`
+	ClientName              string   `json:"client_name"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	GrantTypes              []string `json:"grant_types"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	Scope                   string   `json:"scope"`
+	AccessTokenLifetime     int64    `json:"access_token_lifetime"`
+	Error                   string   `json:"error"`
+}
+
+func (suite *ClientControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	accessTokenService := new(auth.RefreshTokenService)
+	accessTokenService.Init(jwtService)
+	suite.accessTokenService = accessTokenService
+
+	suite.clientRepo = new(oauth.MemoryClientRepository)
+	clientService := new(oauth.ClientService)
+	clientService.Init(suite.clientRepo, []string{"RS256"})
+	suite.auditRepo = new(audit.MemoryAuditRepository)
+
+	suite.controller = new(ClientController)
+	suite.controller.Init(accessTokenService, clientService, suite.auditRepo)
+}
+
+func (suite *ClientControllerSuite) clientContext(identifier string, body string, scope ...string) (*httptest.ResponseRecorder, *gin.Context) {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{}
+	context.Request.Body = io.NopCloser(strings.NewReader(body))
+	context.Params = gin.Params{{Key: "identifier", Value: identifier}}
+
+	token, _ := suite.accessTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: "admin", Scope: scope})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+	return w, context
+}
+
+func decodeRegistration(w *httptest.ResponseRecorder) *registrationResponse {
+	response := new(registrationResponse)
+	json.NewDecoder(w.Result().Body).Decode(response)
+	return response
+}
+
+func (suite *ClientControllerSuite) TestRegister_IssueClientCredentials() {
+	w, context := buildContext()
+	context.Request.Body = io.NopCloser(strings.NewReader(`{
+		"client_name": "App",
+		"redirect_uris": ["https://app.example.com/callback"],
+		"scope": "openid profile",
+		"access_token_lifetime": 86400
+	}`))
+
+	suite.controller.Register(context)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Result().StatusCode)
+	assert.Equal(suite.T(), "no-store", w.Result().Header.Get("Cache-Control"))
+	response := decodeRegistration(w)
+	assert.NotEmpty(suite.T(), response.ClientId)
+	assert.NotEmpty(suite.T(), response.ClientSecret)
+	assert.Equal(suite.T(), int64(0), *response.ClientSecretExpiresAt)
+	assert.Equal(suite.T(), []string{"authorization_code"}, response.GrantTypes)
+	assert.Equal(suite.T(), "client_secret_basic", response.TokenEndpointAuthMethod)
+	assert.Zero(suite.T(), response.AccessTokenLifetime)
+
+	client, err := suite.clientRepo.FindByIdentifier(context.Request.Context(), response.ClientId)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), client.VerifySecret(response.ClientSecret))
+}
+
+func (suite *ClientControllerSuite) TestRegister_PublicClientWithoutSecret() {
+	w, context := buildContext()
+	context.Request.Body = io.NopCloser(strings.NewReader(`{
+		"redirect_uris": ["http://localhost:8400/callback"],
+		"token_endpoint_auth_method": "none"
+	}`))
+
+	suite.controller.Register(context)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Result().StatusCode)
+	response := decodeRegistration(w)
+	assert.Empty(suite.T(), response.ClientSecret)
+	assert.Nil(suite.T(), response.ClientSecretExpiresAt)
+}
+
+func (suite *ClientControllerSuite) TestRegister_FailWithInvalidRedirectURI() {
+	w, context := buildContext()
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"redirect_uris": ["http://app.example.com/callback"]}`))
+
+	suite.controller.Register(context)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Result().StatusCode)
+	assert.Equal(suite.T(), "invalid_redirect_uri", decodeRegistration(w).Error)
+}
+
+func (suite *ClientControllerSuite) TestRegister_FailWithAdminScope() {
+	w, context := buildContext()
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"redirect_uris": ["https://app.example.com/callback"], "scope": "admin"}`))
+
+	suite.controller.Register(context)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Result().StatusCode)
+	assert.Equal(suite.T(), "invalid_client_metadata", decodeRegistration(w).Error)
+}
+
+func (suite *ClientControllerSuite) TestCreateClient_AllowLifetimesAndRecordEvent() {
+	w, context := suite.clientContext("", `{
+		"redirect_uris": ["https://app.example.com/callback"],
+		"access_token_lifetime": 300
+	}`, auth.AdminScope)
+
+	suite.controller.CreateClient(context)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Result().StatusCode)
+	response := decodeRegistration(w)
+	assert.Equal(suite.T(), int64(300), response.AccessTokenLifetime)
+	events, _ := suite.auditRepo.FindByTarget(context.Request.Context(), response.ClientId)
+	assert.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), "client.create", events[0].Action)
+}
+
+func (suite *ClientControllerSuite) TestCreateClient_ForbiddenWithoutAdminScope() {
+	w, context := suite.clientContext("", `{"redirect_uris": ["https://app.example.com/callback"]}`)
+
+	suite.controller.CreateClient(context)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Result().StatusCode)
+	clients, _ := suite.clientRepo.List(context.Request.Context())
+	assert.Empty(suite.T(), clients)
+}
+
+func (suite *ClientControllerSuite) TestUpdateClient_ReplaceMetadata() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{Identifier: "app", TokenEndpointAuthMethod: oauth.ClientSecretBasicAuthMethod})
+	w, context := suite.clientContext("app", `{"client_name": "Renamed", "redirect_uris": ["https://app.example.com/callback"]}`, auth.AdminScope)
+
+	suite.controller.UpdateClient(context)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Result().StatusCode)
+	assert.Equal(suite.T(), "Renamed", decodeRegistration(w).ClientName)
+}
+
+func (suite *ClientControllerSuite) TestGetClient_NotFoundForUnknownClient() {
+	w, context := suite.clientContext("unknown", "", auth.AdminScope)
+
+	suite.controller.GetClient(context)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "/problems/client-not-found")
+}
+
+func (suite *ClientControllerSuite) TestRotateClientSecret_ReturnNewSecret() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{Identifier: "app", TokenEndpointAuthMethod: oauth.ClientSecretBasicAuthMethod})
+	w, context := suite.clientContext("app", "", auth.AdminScope)
+
+	suite.controller.RotateClientSecret(context)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Result().StatusCode)
+	response := decodeRegistration(w)
+	client, _ := suite.clientRepo.FindByIdentifier(context.Request.Context(), "app")
+	assert.True(suite.T(), client.VerifySecret(response.ClientSecret))
+}
+
+func (suite *ClientControllerSuite) TestDeleteClient_RemoveClient() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{Identifier: "app"})
+	w, context := suite.clientContext("app", "", auth.AdminScope)
+
+	suite.controller.DeleteClient(context)
+
+	assert.Equal(suite.T(), http.StatusNoContent, w.Result().StatusCode)
+	_, err := suite.clientRepo.FindByIdentifier(context.Request.Context(), "app")
+	assert.ErrorIs(suite.T(), err, oauth.ErrClientNotFound)
+}
+
+func TestClientController(t *testing.T) {
+	suite.Run(t, new(ClientControllerSuite))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/gin-gonic/gin"
+)
+
+type ConsentController struct {
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	consentService      *oauth.ConsentService
+}
+
+type consentResponse struct {
+	ClientId  string    `json:"clientId"`
+	Scope     []string  `json:"scope"`
+	GrantedAt time.Time `json:"grantedAt"`
+}
+
+func (controller *ConsentController) Init(
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	consentService *oauth.ConsentService,
+) {
+	controller.sessionTokenService = sessionTokenService
+	controller.consentService = consentService
+}
+
+func (controller *ConsentController) ListConsents(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	consents, err := controller.consentService.List(c.Request.Context(), payload.Sub)
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := make([]*consentResponse, 0, len(consents))
+	for _, consent := range consents {
+		response = append(response, &consentResponse{
+			ClientId:  consent.ClientId,
+			Scope:     consent.Scope,
+			GrantedAt: consent.GrantedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (controller *ConsentController) RevokeConsent(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	err := controller.consentService.Revoke(c.Request.Context(), payload.Sub, c.Param("clientId"))
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
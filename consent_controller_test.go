@@ -0,0 +1,105 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/secret"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConsentControllerSuite struct {
+	suite.Suite
+
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	consentRepo         oauth.ConsentRepository
+	controller          *ConsentController
+}
+
+func (suite *ConsentControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	sessionTokenService := new(auth.RefreshTokenService)
+	sessionTokenService.Init(jwtService)
+	suite.sessionTokenService = sessionTokenService
+
+	suite.consentRepo = new(oauth.MemoryConsentRepository)
+	suite.consentRepo.Save(context.Background(), &oauth.Consent{Sub: "user", ClientId: "app", Scope: []string{"openid", "profile"}, GrantedAt: time.Now()})
+	suite.consentRepo.Save(context.Background(), &oauth.Consent{Sub: "other", ClientId: "app", Scope: []string{"profile"}, GrantedAt: time.Now()})
+	consentService := new(oauth.ConsentService)
+	consentService.Init(suite.consentRepo)
+
+	suite.controller = new(ConsentController)
+	suite.controller.Init(sessionTokenService, consentService)
+}
+
+func (suite *ConsentControllerSuite) authorize(context *gin.Context) {
+	token, _ := suite.sessionTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user"})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *ConsentControllerSuite) listConsents() []map[string]interface{} {
+	w, context := buildContext()
+	suite.authorize(context)
+
+	suite.controller.ListConsents(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body []map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	return body
+}
+
+func (suite *ConsentControllerSuite) TestListConsents_ReturnConsentsOfLoggedInUser() {
+	consents := suite.listConsents()
+
+	assert.Len(suite.T(), consents, 1)
+	assert.Equal(suite.T(), "app", consents[0]["clientId"])
+	assert.Equal(suite.T(), []interface{}{"openid", "profile"}, consents[0]["scope"])
+	assert.NotEmpty(suite.T(), consents[0]["grantedAt"])
+}
+
+func (suite *ConsentControllerSuite) TestListConsents_FailWithoutBearerToken() {
+	w, context := buildContext()
+
+	suite.controller.ListConsents(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *ConsentControllerSuite) TestRevokeConsent_RemoveConsentOfLoggedInUser() {
+	w, context := buildContext()
+	suite.authorize(context)
+	context.Params = gin.Params{{Key: "clientId", Value: "app"}}
+
+	suite.controller.RevokeConsent(context)
+
+	assert.Equal(suite.T(), 204, w.Result().StatusCode)
+	assert.Empty(suite.T(), suite.listConsents())
+	consent, _ := suite.consentRepo.Find(context.Request.Context(), "other", "app")
+	assert.True(suite.T(), consent.Active())
+}
+
+func (suite *ConsentControllerSuite) TestRevokeConsent_FailForUnknownClient() {
+	w, context := buildContext()
+	suite.authorize(context)
+	context.Params = gin.Params{{Key: "clientId", Value: "unknown"}}
+
+	suite.controller.RevokeConsent(context)
+
+	assert.Equal(suite.T(), 404, w.Result().StatusCode)
+}
+
+func TestConsentController(t *testing.T) {
+	suite.Run(t, new(ConsentControllerSuite))
+}
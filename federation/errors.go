@@ -0,0 +1,11 @@
+package federation
+
+import "errors"
+
+var (
+	ErrProviderNotFound = errors.New("identity provider not found")
+	ErrInvalidState     = errors.New("federated login state is invalid")
+	ErrInvalidIdToken   = errors.New("id token is invalid")
+	ErrAmbiguousEmail   = errors.New("email belongs to multiple users")
+	ErrUpstream         = errors.New("identity provider request failed")
+)
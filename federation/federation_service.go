@@ -0,0 +1,235 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Untanky/go-id/user"
+)
+
+const loginLifetime = 10 * time.Minute
+
+type Authorization struct {
+	URL   string
+	State string
+}
+
+type pendingLogin struct {
+	provider   string
+	identifier string
+	nonce      string
+	verifier   string
+	expiresAt  time.Time
+}
+
+type FederationService struct {
+	userRepo user.UserRepository
+	parties  map[string]*RelyingParty
+
+	mu      sync.Mutex
+	pending map[string]*pendingLogin
+}
+
+func (service *FederationService) Init(userRepo user.UserRepository, parties []*RelyingParty) {
+	service.userRepo = userRepo
+	service.parties = make(map[string]*RelyingParty)
+	for _, party := range parties {
+		service.parties[party.Provider().Name] = party
+	}
+	service.pending = make(map[string]*pendingLogin)
+}
+
+func (service *FederationService) Begin(ctx context.Context, provider string) (*Authorization, error) {
+	return service.begin(ctx, provider, "")
+}
+
+func (service *FederationService) BeginLink(ctx context.Context, provider string, identifier string) (*Authorization, error) {
+	return service.begin(ctx, provider, identifier)
+}
+
+func (service *FederationService) begin(ctx context.Context, provider string, identifier string) (*Authorization, error) {
+	party, ok := service.parties[provider]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := party.AuthorizationURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	now := time.Now()
+	for key, login := range service.pending {
+		if now.After(login.expiresAt) {
+			delete(service.pending, key)
+		}
+	}
+	service.pending[state] = &pendingLogin{
+		provider:   provider,
+		identifier: identifier,
+		nonce:      nonce,
+		verifier:   verifier,
+		expiresAt:  now.Add(loginLifetime),
+	}
+
+	return &Authorization{URL: location, State: state}, nil
+}
+
+func (service *FederationService) Complete(ctx context.Context, provider string, state string, code string) (*user.User, error) {
+	identity, _, err := service.complete(ctx, provider, state, code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.resolve(ctx, identity)
+}
+
+func (service *FederationService) CompleteLink(ctx context.Context, provider string, state string, code string) (*user.User, error) {
+	identity, identifier, err := service.complete(ctx, provider, state, code, true)
+	if err != nil {
+		return nil, err
+	}
+
+	linking, err := service.userRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.link(ctx, linking, identity); err != nil {
+		return nil, err
+	}
+	return linking, nil
+}
+
+func (service *FederationService) complete(ctx context.Context, provider string, state string, code string, linking bool) (*Identity, string, error) {
+	party, ok := service.parties[provider]
+	if !ok {
+		return nil, "", ErrProviderNotFound
+	}
+
+	login, ok := service.consume(provider, state)
+	if !ok || linking != (login.identifier != "") {
+		return nil, "", ErrInvalidState
+	}
+
+	token, err := party.Exchange(ctx, code, login.verifier)
+	if err != nil {
+		return nil, "", err
+	}
+
+	identity, err := party.Verify(ctx, token, login.nonce)
+	if err != nil {
+		return nil, "", err
+	}
+	return identity, login.identifier, nil
+}
+
+func (service *FederationService) consume(provider string, state string) (*pendingLogin, bool) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	login, ok := service.pending[state]
+	if !ok {
+		return nil, false
+	}
+
+	delete(service.pending, state)
+	if login.provider != provider || time.Now().After(login.expiresAt) {
+		return nil, false
+	}
+	return login, true
+}
+
+func (service *FederationService) resolve(ctx context.Context, identity *Identity) (*user.User, error) {
+	existing, err := service.userRepo.FindByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return existing, nil
+	}
+
+	if !errors.Is(err, user.ErrUserNotFound) {
+		return nil, err
+	}
+
+	if identity.Email != "" && identity.EmailVerified {
+		linked, err := service.findByVerifiedEmail(ctx, identity.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		if linked != nil {
+			return linked, service.link(ctx, linked, identity)
+		}
+	}
+
+	provisioned := &user.User{
+		Identifier:    identity.Provider + ":" + identity.Subject,
+		Status:        user.Active,
+		Email:         identity.Email,
+		EmailVerified: identity.Email != "" && identity.EmailVerified,
+		DisplayName:   identity.Name,
+	}
+	provisioned.Link(identity.Provider, identity.Subject)
+	if err := service.userRepo.Create(ctx, provisioned); err != nil {
+		return nil, err
+	}
+	return provisioned, nil
+}
+
+func (service *FederationService) link(ctx context.Context, linking *user.User, identity *Identity) error {
+	if linking.HasIdentity(identity.Provider, identity.Subject) {
+		return nil
+	}
+
+	linking.Link(identity.Provider, identity.Subject)
+	return service.userRepo.Update(ctx, linking)
+}
+
+func (service *FederationService) findByVerifiedEmail(ctx context.Context, email string) (*user.User, error) {
+	users, err := service.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *user.User
+	for _, candidate := range users {
+		if !candidate.EmailVerified {
+			continue
+		}
+
+		if found != nil {
+			return nil, ErrAmbiguousEmail
+		}
+		found = candidate
+	}
+	return found, nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
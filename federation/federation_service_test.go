@@ -0,0 +1,176 @@
+package federation_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/Untanky/go-id/federation"
+	"github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FederationServiceTestSuite struct {
+	suite.Suite
+	mock     *MockProvider
+	userRepo user.UserRepository
+	service  *FederationService
+}
+
+func (suite *FederationServiceTestSuite) SetupTest() {
+	suite.mock = newMockProvider(generateKeyPair())
+
+	suite.userRepo = new(user.MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "alice", Status: user.Active, Email: "alice@example.com", EmailVerified: true})
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "bob", Status: user.Active, Email: "bob@example.com"})
+
+	suite.service = new(FederationService)
+	suite.service.Init(suite.userRepo, []*RelyingParty{newRelyingParty(suite.mock)})
+}
+
+func (suite *FederationServiceTestSuite) TearDownTest() {
+	suite.mock.Close()
+}
+
+func (suite *FederationServiceTestSuite) signIn(claims map[string]interface{}) (*user.User, error) {
+	authorization, err := suite.service.Begin(context.Background(), "corp")
+	suite.Require().Nil(err)
+
+	callback, err := suite.mock.SignIn(authorization.URL, claims)
+	suite.Require().Nil(err)
+	suite.Require().Equal(authorization.State, callback.Query().Get("state"))
+
+	return suite.service.Complete(context.Background(), "corp", callback.Query().Get("state"), callback.Query().Get("code"))
+}
+
+func (suite *FederationServiceTestSuite) link(identifier string, claims map[string]interface{}) (*user.User, error) {
+	authorization, err := suite.service.BeginLink(context.Background(), "corp", identifier)
+	suite.Require().Nil(err)
+
+	callback, err := suite.mock.SignIn(authorization.URL, claims)
+	suite.Require().Nil(err)
+
+	return suite.service.CompleteLink(context.Background(), "corp", callback.Query().Get("state"), callback.Query().Get("code"))
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_ProvisionNewUser() {
+	provisioned, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "carol@example.com", "email_verified": true, "name": "Carol"})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "corp:123", provisioned.Identifier)
+	stored, err := suite.userRepo.FindByIdentifier(context.Background(), "corp:123")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), user.Active, stored.Status)
+	assert.Equal(suite.T(), "carol@example.com", stored.Email)
+	assert.True(suite.T(), stored.EmailVerified)
+	assert.Equal(suite.T(), "Carol", stored.DisplayName)
+	assert.True(suite.T(), stored.HasIdentity("corp", "123"))
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_ReturnProvisionedUserOnNextLogin() {
+	suite.signIn(map[string]interface{}{"sub": "123", "email": "carol@example.com", "email_verified": true})
+
+	returning, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "carol@corp.example.com", "email_verified": true})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "corp:123", returning.Identifier)
+	assert.Equal(suite.T(), "carol@example.com", returning.Email)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_LinkExistingUserByVerifiedEmail() {
+	linked, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@example.com", "email_verified": true})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "alice", linked.Identifier)
+	_, err = suite.userRepo.FindByIdentifier(context.Background(), "corp:123")
+	assert.ErrorIs(suite.T(), err, user.ErrUserNotFound)
+	stored, _ := suite.userRepo.FindByIdentity(context.Background(), "corp", "123")
+	assert.Equal(suite.T(), "alice", stored.Identifier)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_ResolveLinkedIdentityAfterEmailChange() {
+	suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@example.com", "email_verified": true})
+
+	returning, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@corp.example.com", "email_verified": true})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "alice", returning.Identifier)
+}
+
+func (suite *FederationServiceTestSuite) TestCompleteLink_AttachIdentityToUser() {
+	linked, err := suite.link("bob", map[string]interface{}{"sub": "456", "email": "robert@corp.example.com", "email_verified": true})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "bob", linked.Identifier)
+	assert.True(suite.T(), linked.HasIdentity("corp", "456"))
+
+	returning, err := suite.signIn(map[string]interface{}{"sub": "456"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "bob", returning.Identifier)
+}
+
+func (suite *FederationServiceTestSuite) TestCompleteLink_FailWhenIdentityBelongsToOtherUser() {
+	suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@example.com", "email_verified": true})
+
+	_, err := suite.link("bob", map[string]interface{}{"sub": "123"})
+
+	assert.ErrorIs(suite.T(), err, user.ErrIdentityLinked)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_FailWithLinkState() {
+	authorization, _ := suite.service.BeginLink(context.Background(), "corp", "bob")
+	callback, _ := suite.mock.SignIn(authorization.URL, map[string]interface{}{"sub": "123"})
+
+	_, err := suite.service.Complete(context.Background(), "corp", authorization.State, callback.Query().Get("code"))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidState)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_DoNotLinkUnverifiedUpstreamEmail() {
+	provisioned, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@example.com", "email_verified": false})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "corp:123", provisioned.Identifier)
+	assert.False(suite.T(), provisioned.EmailVerified)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_DoNotLinkUnverifiedLocalEmail() {
+	provisioned, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "bob@example.com", "email_verified": true})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "corp:123", provisioned.Identifier)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_FailWhenEmailIsAmbiguous() {
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "alice2", Status: user.Active, Email: "alice@example.com", EmailVerified: true})
+
+	_, err := suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@example.com", "email_verified": true})
+
+	assert.ErrorIs(suite.T(), err, ErrAmbiguousEmail)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_FailWhenStateIsReused() {
+	authorization, _ := suite.service.Begin(context.Background(), "corp")
+	callback, _ := suite.mock.SignIn(authorization.URL, map[string]interface{}{"sub": "123"})
+	suite.service.Complete(context.Background(), "corp", authorization.State, callback.Query().Get("code"))
+
+	_, err := suite.service.Complete(context.Background(), "corp", authorization.State, callback.Query().Get("code"))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidState)
+}
+
+func (suite *FederationServiceTestSuite) TestComplete_FailWithUnknownState() {
+	_, err := suite.service.Complete(context.Background(), "corp", "unknown", "code")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidState)
+}
+
+func (suite *FederationServiceTestSuite) TestBegin_FailForUnknownProvider() {
+	_, err := suite.service.Begin(context.Background(), "unknown")
+
+	assert.ErrorIs(suite.T(), err, ErrProviderNotFound)
+}
+
+func TestFederationService(t *testing.T) {
+	suite.Run(t, new(FederationServiceTestSuite))
+}
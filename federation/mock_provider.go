@@ -0,0 +1,193 @@
+package federation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/secret"
+)
+
+type mockGrant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+type MockProvider struct {
+	ClientId     string
+	ClientSecret string
+
+	signingService *jwt.JwtService[secret.KeyPair]
+	server         *httptest.Server
+
+	mu     sync.Mutex
+	grants map[string]*mockGrant
+}
+
+func (mock *MockProvider) Init(signingService *jwt.JwtService[secret.KeyPair], clientId string, clientSecret string) {
+	mock.signingService = signingService
+	mock.ClientId = clientId
+	mock.ClientSecret = clientSecret
+	mock.grants = make(map[string]*mockGrant)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, mock.discovery)
+	mux.HandleFunc("/jwks", mock.jwks)
+	mux.HandleFunc("/token", mock.token)
+	mock.server = httptest.NewServer(mux)
+}
+
+func (mock *MockProvider) Issuer() string {
+	return mock.server.URL
+}
+
+func (mock *MockProvider) Client() *http.Client {
+	return mock.server.Client()
+}
+
+func (mock *MockProvider) Close() {
+	mock.server.Close()
+}
+
+func (mock *MockProvider) SignIn(authorizationURL string, claims map[string]interface{}) (*url.URL, error) {
+	location, err := url.Parse(authorizationURL)
+	if err != nil {
+		return nil, err
+	}
+
+	query := location.Query()
+	if location.Path != "/authorize" || query.Get("client_id") != mock.ClientId || query.Get("response_type") != "code" {
+		return nil, errors.New("invalid authorization request")
+	}
+
+	if query.Get("code_challenge_method") != oauth.S256 || query.Get("code_challenge") == "" {
+		return nil, errors.New("pkce is required")
+	}
+
+	code, err := mock.issueCode(&mockGrant{
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		claims:      claims,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		return nil, err
+	}
+
+	callbackQuery := callback.Query()
+	callbackQuery.Set("code", code)
+	callbackQuery.Set("state", query.Get("state"))
+	callback.RawQuery = callbackQuery.Encode()
+	return callback, nil
+}
+
+func (mock *MockProvider) issueCode(grant *mockGrant) (string, error) {
+	code, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+
+	mock.grants[code] = grant
+	return code, nil
+}
+
+func (mock *MockProvider) Sign(claims map[string]interface{}) (jwt.Jwt, error) {
+	now := time.Now()
+	payload := map[string]interface{}{
+		"iss": mock.Issuer(),
+		"aud": mock.ClientId,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for key, value := range claims {
+		payload[key] = value
+	}
+	return mock.signingService.Create(payload)
+}
+
+func (mock *MockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, &oidc.Configuration{
+		Issuer:                            mock.Issuer(),
+		AuthorizationEndpoint:             mock.Issuer() + "/authorize",
+		TokenEndpoint:                     mock.Issuer() + "/token",
+		JwksURI:                           mock.Issuer() + "/jwks",
+		ResponseTypesSupported:            []string{"code"},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{mock.signingService.Method()},
+		TokenEndpointAuthMethodsSupported: []string{oauth.ClientSecretBasicAuthMethod},
+		CodeChallengeMethodsSupported:     []string{oauth.S256},
+	})
+}
+
+func (mock *MockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	jwk, err := mock.signingService.PublicJwk()
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string][]*jwt.Jwk{"keys": {jwk}})
+}
+
+func (mock *MockProvider) token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok || clientId != mock.ClientId || clientSecret != mock.ClientSecret {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.Method != http.MethodPost || r.PostFormValue("grant_type") != "authorization_code" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	mock.mu.Lock()
+	grant, ok := mock.grants[r.PostFormValue("code")]
+	delete(mock.grants, r.PostFormValue("code"))
+	mock.mu.Unlock()
+
+	if !ok || grant.redirectURI != r.PostFormValue("redirect_uri") || !oauth.VerifyCodeChallenge(r.PostFormValue("code_verifier"), grant.challenge) {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]interface{}{"nonce": grant.nonce}
+	for key, value := range grant.claims {
+		claims[key] = value
+	}
+
+	idToken, err := mock.Sign(claims)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,18 @@
+package federation
+
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+}
+
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
@@ -0,0 +1,286 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oidc"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+type RelyingParty struct {
+	provider *Provider
+	client   *http.Client
+
+	mu            sync.Mutex
+	configuration *oidc.Configuration
+	keys          []jwt.Jwk
+}
+
+func (rp *RelyingParty) Init(provider *Provider, client *http.Client) {
+	rp.provider = provider
+	rp.client = client
+}
+
+func (rp *RelyingParty) Provider() *Provider {
+	return rp.provider
+}
+
+func (rp *RelyingParty) AuthorizationURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	configuration, err := rp.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	location, err := url.Parse(configuration.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint", ErrUpstream)
+	}
+
+	query := location.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", rp.provider.ClientId)
+	query.Set("redirect_uri", rp.provider.RedirectURI)
+	query.Set("scope", strings.Join(rp.scope(), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	location.RawQuery = query.Encode()
+	return location.String(), nil
+}
+
+func (rp *RelyingParty) Exchange(ctx context.Context, code string, verifier string) (jwt.Jwt, error) {
+	configuration, err := rp.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.provider.RedirectURI},
+		"code_verifier": {verifier},
+	}
+	if rp.provider.ClientSecret == "" {
+		form.Set("client_id", rp.provider.ClientId)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, configuration.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rp.provider.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(rp.provider.ClientId), url.QueryEscape(rp.provider.ClientSecret))
+	}
+
+	var response struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := rp.fetch(request, &response); err != nil {
+		return "", err
+	}
+
+	if response.IdToken == "" {
+		return "", fmt.Errorf("%w: token response has no id_token", ErrInvalidIdToken)
+	}
+	return jwt.Jwt(response.IdToken), nil
+}
+
+func (rp *RelyingParty) Verify(ctx context.Context, token jwt.Jwt, nonce string) (*Identity, error) {
+	configuration, err := rp.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := rp.verifySignature(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims["iss"] != configuration.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIdToken)
+	}
+
+	if !rp.hasAudience(claims) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIdToken)
+	}
+
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, fmt.Errorf("%w: missing expiry", ErrInvalidIdToken)
+	}
+
+	if claims["nonce"] != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIdToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIdToken)
+	}
+
+	identity := &Identity{Provider: rp.provider.Name, Subject: subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+	return identity, nil
+}
+
+func (rp *RelyingParty) scope() []string {
+	scope := []string{"openid"}
+	for _, value := range rp.provider.Scopes {
+		if value != "openid" {
+			scope = append(scope, value)
+		}
+	}
+	return scope
+}
+
+func (rp *RelyingParty) hasAudience(claims map[string]interface{}) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == rp.provider.ClientId
+	case []interface{}:
+		if azp, ok := claims["azp"]; ok && azp != rp.provider.ClientId {
+			return false
+		}
+		for _, value := range aud {
+			if value == rp.provider.ClientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (rp *RelyingParty) verifySignature(ctx context.Context, token jwt.Jwt) (map[string]interface{}, error) {
+	keys, err := rp.jwks(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, err := verifyWithAny(keys, token); err == nil {
+		return claims, nil
+	}
+
+	keys, err = rp.jwks(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := verifyWithAny(keys, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIdToken, err)
+	}
+	return claims, nil
+}
+
+func verifyWithAny(keys []jwt.Jwk, token jwt.Jwt) (map[string]interface{}, error) {
+	err := errors.New("no signing key found")
+	for _, key := range keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var claims map[string]interface{}
+		claims, err = key.Verify(token)
+		if err == nil {
+			return claims, nil
+		}
+	}
+	return nil, err
+}
+
+func (rp *RelyingParty) discover(ctx context.Context) (*oidc.Configuration, error) {
+	rp.mu.Lock()
+	configuration := rp.configuration
+	rp.mu.Unlock()
+	if configuration != nil {
+		return configuration, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(rp.provider.Issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+
+	configuration = new(oidc.Configuration)
+	if err := rp.fetch(request, configuration); err != nil {
+		return nil, err
+	}
+
+	if configuration.Issuer != rp.provider.Issuer {
+		return nil, fmt.Errorf("%w: discovered issuer %s does not match", ErrUpstream, configuration.Issuer)
+	}
+
+	rp.mu.Lock()
+	rp.configuration = configuration
+	rp.mu.Unlock()
+	return configuration, nil
+}
+
+func (rp *RelyingParty) jwks(ctx context.Context, refresh bool) ([]jwt.Jwk, error) {
+	rp.mu.Lock()
+	keys := rp.keys
+	rp.mu.Unlock()
+	if keys != nil && !refresh {
+		return keys, nil
+	}
+
+	configuration, err := rp.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, configuration.JwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+
+	var set struct {
+		Keys []jwt.Jwk `json:"keys"`
+	}
+	if err := rp.fetch(request, &set); err != nil {
+		return nil, err
+	}
+
+	rp.mu.Lock()
+	rp.keys = set.Keys
+	rp.mu.Unlock()
+	return set.Keys, nil
+}
+
+func (rp *RelyingParty) fetch(request *http.Request, target interface{}) error {
+	request.Header.Set("Accept", "application/json")
+
+	response, err := rp.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s responded with %d", ErrUpstream, request.URL.Path, response.StatusCode)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(target); err != nil {
+		return fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	return nil
+}
+
+func codeChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
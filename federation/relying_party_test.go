@@ -0,0 +1,201 @@
+package federation_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/federation"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	redirectURI  = "https://id.example.com/login/corp/callback"
+	codeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+type RelyingPartyTestSuite struct {
+	suite.Suite
+	keyPair secret.KeyPair
+	mock    *MockProvider
+	rp      *RelyingParty
+}
+
+func generateKeyPair() secret.KeyPair {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	private, _ := x509.MarshalPKCS8PrivateKey(key)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+	return secret.KeyPair{
+		PrivateKey: secret.SecretString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+		PublicKey:  secret.SecretString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+	}
+}
+
+func newMockProvider(keyPair secret.KeyPair) *MockProvider {
+	signingService := new(jwt.JwtService[secret.KeyPair])
+	signingService.Init(jwt.RS256, secret.NewSecretPair(keyPair))
+
+	mock := new(MockProvider)
+	mock.Init(signingService, "go-id", "secret")
+	return mock
+}
+
+func newRelyingParty(mock *MockProvider) *RelyingParty {
+	rp := new(RelyingParty)
+	rp.Init(&Provider{
+		Name:         "corp",
+		Issuer:       mock.Issuer(),
+		ClientId:     "go-id",
+		ClientSecret: "secret",
+		RedirectURI:  redirectURI,
+		Scopes:       []string{"email", "profile"},
+	}, mock.Client())
+	return rp
+}
+
+func (suite *RelyingPartyTestSuite) SetupSuite() {
+	suite.keyPair = generateKeyPair()
+}
+
+func (suite *RelyingPartyTestSuite) SetupTest() {
+	suite.mock = newMockProvider(suite.keyPair)
+	suite.rp = newRelyingParty(suite.mock)
+}
+
+func (suite *RelyingPartyTestSuite) TearDownTest() {
+	suite.mock.Close()
+}
+
+func (suite *RelyingPartyTestSuite) signIn(claims map[string]interface{}) string {
+	location, err := suite.rp.AuthorizationURL(context.Background(), "state", "nonce", codeVerifier)
+	suite.Require().Nil(err)
+
+	callback, err := suite.mock.SignIn(location, claims)
+	suite.Require().Nil(err)
+	return callback.Query().Get("code")
+}
+
+func (suite *RelyingPartyTestSuite) TestAuthorizationURL_UseDiscoveredEndpointWithPkce() {
+	location, err := suite.rp.AuthorizationURL(context.Background(), "state", "nonce", codeVerifier)
+
+	assert.Nil(suite.T(), err)
+	parsed, _ := url.Parse(location)
+	assert.Equal(suite.T(), suite.mock.Issuer()+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(suite.T(), "code", parsed.Query().Get("response_type"))
+	assert.Equal(suite.T(), "go-id", parsed.Query().Get("client_id"))
+	assert.Equal(suite.T(), redirectURI, parsed.Query().Get("redirect_uri"))
+	assert.Equal(suite.T(), "openid email profile", parsed.Query().Get("scope"))
+	assert.Equal(suite.T(), "state", parsed.Query().Get("state"))
+	assert.Equal(suite.T(), "nonce", parsed.Query().Get("nonce"))
+	assert.Equal(suite.T(), "S256", parsed.Query().Get("code_challenge_method"))
+	assert.True(suite.T(), oauth.VerifyCodeChallenge(codeVerifier, parsed.Query().Get("code_challenge")))
+}
+
+func (suite *RelyingPartyTestSuite) TestExchange_ReturnVerifiedIdentity() {
+	code := suite.signIn(map[string]interface{}{"sub": "123", "email": "alice@example.com", "email_verified": true, "name": "Alice"})
+
+	token, err := suite.rp.Exchange(context.Background(), code, codeVerifier)
+	assert.Nil(suite.T(), err)
+
+	identity, err := suite.rp.Verify(context.Background(), token, "nonce")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &Identity{Provider: "corp", Subject: "123", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}, identity)
+}
+
+func (suite *RelyingPartyTestSuite) TestExchange_FailWithWrongVerifier() {
+	code := suite.signIn(map[string]interface{}{"sub": "123"})
+
+	_, err := suite.rp.Exchange(context.Background(), code, "M25iVXpKU3puUjFaYWg3T1NDTDQtcW1ROUY5YXlwalNoc0hhakxifmZHag")
+
+	assert.ErrorIs(suite.T(), err, ErrUpstream)
+}
+
+func (suite *RelyingPartyTestSuite) TestExchange_FailWhenCodeIsReused() {
+	code := suite.signIn(map[string]interface{}{"sub": "123"})
+	suite.rp.Exchange(context.Background(), code, codeVerifier)
+
+	_, err := suite.rp.Exchange(context.Background(), code, codeVerifier)
+
+	assert.ErrorIs(suite.T(), err, ErrUpstream)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWithWrongNonce() {
+	token, _ := suite.mock.Sign(map[string]interface{}{"sub": "123", "nonce": "other"})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWithWrongAudience() {
+	token, _ := suite.mock.Sign(map[string]interface{}{"sub": "123", "nonce": "nonce", "aud": "other"})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWithWrongAuthorizedParty() {
+	token, _ := suite.mock.Sign(map[string]interface{}{"sub": "123", "nonce": "nonce", "aud": []string{"go-id", "other"}, "azp": "other"})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWithWrongIssuer() {
+	token, _ := suite.mock.Sign(map[string]interface{}{"sub": "123", "nonce": "nonce", "iss": "https://evil.example.com"})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWhenExpired() {
+	token, _ := suite.mock.Sign(map[string]interface{}{"sub": "123", "nonce": "nonce", "exp": time.Now().Add(-time.Minute).Unix()})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWhenSignedByUnknownKey() {
+	other := newMockProvider(generateKeyPair())
+	defer other.Close()
+	token, _ := other.Sign(map[string]interface{}{"sub": "123", "nonce": "nonce", "iss": suite.mock.Issuer()})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestVerify_FailWithoutSubject() {
+	token, _ := suite.mock.Sign(map[string]interface{}{"nonce": "nonce"})
+
+	_, err := suite.rp.Verify(context.Background(), token, "nonce")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidIdToken)
+}
+
+func (suite *RelyingPartyTestSuite) TestAuthorizationURL_FailWhenDiscoveredIssuerDiffers() {
+	rp := new(RelyingParty)
+	rp.Init(&Provider{Name: "corp", Issuer: suite.mock.Issuer() + "/", ClientId: "go-id"}, suite.mock.Client())
+
+	_, err := rp.AuthorizationURL(context.Background(), "state", "nonce", codeVerifier)
+
+	assert.ErrorIs(suite.T(), err, ErrUpstream)
+}
+
+func TestRelyingParty(t *testing.T) {
+	suite.Run(t, new(RelyingPartyTestSuite))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/federation"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+)
+
+const reauthenticationWindow = 5 * time.Minute
+
+type IdentityController struct {
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	federationService   *federation.FederationService
+	userRepo            user.UserRepository
+	userService         *user.UserService
+}
+
+type identityResponse struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+func (controller *IdentityController) Init(
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	federationService *federation.FederationService,
+	userRepo user.UserRepository,
+	userService *user.UserService,
+) {
+	controller.sessionTokenService = sessionTokenService
+	controller.federationService = federationService
+	controller.userRepo = userRepo
+	controller.userService = userService
+}
+
+func (controller *IdentityController) ListIdentities(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	account, err := controller.userRepo.FindByIdentifier(c.Request.Context(), payload.Sub)
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newIdentityResponses(account))
+}
+
+func (controller *IdentityController) LinkIdentity(c *gin.Context) {
+	payload, shouldReturn := authenticateRecently(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	authorization, err := controller.federationService.BeginLink(c.Request.Context(), c.Param("provider"), payload.Sub)
+	if err != nil {
+		respondProblem(c, http.StatusBadGateway, err)
+		return
+	}
+
+	c.SetCookie(FederationLinkCookie, authorization.State, 10*60, "/", "", true, true)
+	c.JSON(http.StatusOK, gin.H{
+		"authorizationUrl": authorization.URL,
+	})
+}
+
+func (controller *IdentityController) UnlinkIdentity(c *gin.Context) {
+	payload, shouldReturn := authenticateRecently(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	err := controller.userService.UnlinkIdentity(c.Request.Context(), payload.Sub, c.Param("provider"), c.Param("subject"))
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func authenticateRecently(c *gin.Context, tokenService auth.TokenService[*auth.RefreshTokenPayload]) (*auth.RefreshTokenPayload, bool) {
+	payload, shouldReturn := authenticateSession(c, tokenService)
+	if shouldReturn {
+		return nil, true
+	}
+
+	if !payload.AuthenticatedSince(time.Now().Add(-reauthenticationWindow)) {
+		respondProblem(c, http.StatusUnauthorized, auth.ErrReauthenticationRequired)
+		return nil, true
+	}
+	return payload, false
+}
+
+func newIdentityResponses(account *user.User) []*identityResponse {
+	identities := make([]*identityResponse, 0, len(account.Identities))
+	for _, identity := range account.Identities {
+		identities = append(identities, &identityResponse{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			LinkedAt: identity.LinkedAt,
+		})
+	}
+	return identities
+}
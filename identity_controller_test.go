@@ -0,0 +1,220 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/federation"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdentityControllerSuite struct {
+	suite.Suite
+
+	jwtService          *jwt.JwtService[secret.SecretString]
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo            user.UserRepository
+	provider            *federation.MockProvider
+	controller          *IdentityController
+}
+
+func (suite *IdentityControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	suite.userRepo = new(user.MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &user.User{
+		Identifier: "user",
+		Passkey:    "encrypted",
+		Status:     user.Active,
+		Identities: []user.Identity{{Provider: "corp", Subject: "123", LinkedAt: time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)}},
+	})
+	suite.userRepo.Create(context.Background(), &user.User{
+		Identifier: "federated",
+		Status:     user.Active,
+		Identities: []user.Identity{{Provider: "corp", Subject: "456"}},
+	})
+
+	suite.jwtService = new(jwt.JwtService[secret.SecretString])
+	suite.jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	sessionTokenService := new(auth.RefreshTokenService)
+	sessionTokenService.Init(suite.jwtService)
+	suite.sessionTokenService = sessionTokenService
+
+	signingService := new(jwt.JwtService[secret.KeyPair])
+	signingService.Init(jwt.RS256, secret.NewSecretPair(oidcKeyPair))
+	suite.provider = new(federation.MockProvider)
+	suite.provider.Init(signingService, "go-id", "secret")
+	relyingParty := new(federation.RelyingParty)
+	relyingParty.Init(&federation.Provider{
+		Name:         "corp",
+		Issuer:       suite.provider.Issuer(),
+		ClientId:     "go-id",
+		ClientSecret: "secret",
+		RedirectURI:  oidcIssuer + "/login/corp/callback",
+	}, suite.provider.Client())
+	federationService := new(federation.FederationService)
+	federationService.Init(suite.userRepo, []*federation.RelyingParty{relyingParty})
+
+	userService := new(user.UserService)
+	userService.Init(suite.userRepo)
+
+	suite.controller = new(IdentityController)
+	suite.controller.Init(sessionTokenService, federationService, suite.userRepo, userService)
+}
+
+func (suite *IdentityControllerSuite) TearDownTest() {
+	suite.provider.Close()
+}
+
+func (suite *IdentityControllerSuite) authorize(context *gin.Context, sub string, authenticatedAt time.Time) {
+	token, _ := suite.jwtService.CreateTyped(auth.RefreshTokenJwtType, map[string]interface{}{
+		"sid":       "123",
+		"sub":       sub,
+		"amr":       []string{"pwd"},
+		"auth_time": authenticatedAt.Unix(),
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *IdentityControllerSuite) unlink(sub string, subject string, authenticatedAt time.Time) *http.Response {
+	w, context := buildContext()
+	suite.authorize(context, sub, authenticatedAt)
+	context.Params = gin.Params{{Key: "provider", Value: "corp"}, {Key: "subject", Value: subject}}
+
+	suite.controller.UnlinkIdentity(context)
+
+	return w.Result()
+}
+
+func problemType(response *http.Response) string {
+	problem := new(Problem)
+	json.NewDecoder(response.Body).Decode(problem)
+	return problem.Type
+}
+
+func (suite *IdentityControllerSuite) TestListIdentities_ReturnLinkedIdentities() {
+	w, context := buildContext()
+	suite.authorize(context, "user", time.Now().Add(-time.Hour))
+
+	suite.controller.ListIdentities(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body []map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	assert.Equal(suite.T(), []map[string]interface{}{
+		{"provider": "corp", "subject": "123", "linkedAt": "2022-10-01T12:00:00Z"},
+	}, body)
+}
+
+func (suite *IdentityControllerSuite) TestListIdentities_FailWithoutBearerToken() {
+	w, context := buildContext()
+
+	suite.controller.ListIdentities(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *IdentityControllerSuite) TestLinkIdentity_ReturnAuthorizationUrlAndBindBrowser() {
+	w, context := buildContext()
+	suite.authorize(context, "user", time.Now())
+	context.Params = gin.Params{{Key: "provider", Value: "corp"}}
+
+	suite.controller.LinkIdentity(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body map[string]string
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	location, _ := url.Parse(body["authorizationUrl"])
+	assert.Equal(suite.T(), "/authorize", location.Path)
+	cookies := w.Result().Cookies()
+	assert.Len(suite.T(), cookies, 1)
+	assert.Equal(suite.T(), FederationLinkCookie, cookies[0].Name)
+	assert.Equal(suite.T(), location.Query().Get("state"), cookies[0].Value)
+}
+
+func (suite *IdentityControllerSuite) TestLinkIdentity_RequireRecentAuthentication() {
+	w, context := buildContext()
+	suite.authorize(context, "user", time.Now().Add(-time.Hour))
+	context.Params = gin.Params{{Key: "provider", Value: "corp"}}
+
+	suite.controller.LinkIdentity(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	assert.Equal(suite.T(), "/problems/reauthentication-required", problemType(w.Result()))
+	assert.Empty(suite.T(), w.Result().Cookies())
+}
+
+func (suite *IdentityControllerSuite) TestLinkIdentity_RequireAuthTimeOfLogin() {
+	token, _ := suite.jwtService.CreateTyped(auth.RefreshTokenJwtType, map[string]interface{}{
+		"sid": "123",
+		"sub": "user",
+		"amr": []string{"pwd"},
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	w, context := buildContext()
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+	context.Params = gin.Params{{Key: "provider", Value: "corp"}}
+
+	suite.controller.LinkIdentity(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	assert.Equal(suite.T(), "/problems/reauthentication-required", problemType(w.Result()))
+}
+
+func (suite *IdentityControllerSuite) TestLinkIdentity_FailForUnknownProvider() {
+	w, context := buildContext()
+	suite.authorize(context, "user", time.Now())
+	context.Params = gin.Params{{Key: "provider", Value: "unknown"}}
+
+	suite.controller.LinkIdentity(context)
+
+	assert.Equal(suite.T(), 404, w.Result().StatusCode)
+}
+
+func (suite *IdentityControllerSuite) TestUnlinkIdentity_RemoveIdentity() {
+	result := suite.unlink("user", "123", time.Now())
+
+	assert.Equal(suite.T(), 204, result.StatusCode)
+	_, err := suite.userRepo.FindByIdentity(context.Background(), "corp", "123")
+	assert.ErrorIs(suite.T(), err, user.ErrUserNotFound)
+}
+
+func (suite *IdentityControllerSuite) TestUnlinkIdentity_RequireRecentAuthentication() {
+	result := suite.unlink("user", "123", time.Now().Add(-time.Hour))
+
+	assert.Equal(suite.T(), 401, result.StatusCode)
+	_, err := suite.userRepo.FindByIdentity(context.Background(), "corp", "123")
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *IdentityControllerSuite) TestUnlinkIdentity_FailForIdentityOfOtherUser() {
+	result := suite.unlink("user", "456", time.Now())
+
+	assert.Equal(suite.T(), 404, result.StatusCode)
+	assert.Equal(suite.T(), "/problems/identity-not-found", problemType(result))
+}
+
+func (suite *IdentityControllerSuite) TestUnlinkIdentity_KeepOnlySignInMethod() {
+	result := suite.unlink("federated", "456", time.Now())
+
+	assert.Equal(suite.T(), 409, result.StatusCode)
+	assert.Equal(suite.T(), "/problems/last-identity", problemType(result))
+}
+
+func TestIdentityController(t *testing.T) {
+	suite.Run(t, new(IdentityControllerSuite))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Untanky/go-id/oauth"
+	"github.com/gin-gonic/gin"
+)
+
+type IntrospectionController struct {
+	clientAuthenticator  *oauth.ClientAuthenticator
+	introspectionService *oauth.IntrospectionService
+}
+
+func (controller *IntrospectionController) Init(clientAuthenticator *oauth.ClientAuthenticator, introspectionService *oauth.IntrospectionService) {
+	controller.clientAuthenticator = clientAuthenticator
+	controller.introspectionService = introspectionService
+}
+
+func (controller *IntrospectionController) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, shouldReturn := authenticateClient(c, controller.clientAuthenticator)
+	if shouldReturn {
+		return
+	}
+
+	introspection, err := controller.introspectionService.Introspect(c.Request.Context(), client, c.PostForm("token"), c.PostForm("token_type_hint"))
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	if !introspection.Active {
+		c.JSON(http.StatusOK, gin.H{
+			"active": false,
+		})
+		return
+	}
+
+	response := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"sub":        introspection.Sub,
+		"exp":        introspection.Exp,
+		"iat":        introspection.Iat,
+	}
+
+	if introspection.TokenType == oauth.RefreshTokenTypeHint {
+		response["token_type"] = oauth.RefreshTokenTypeHint
+	}
+
+	if introspection.ClientId != "" {
+		response["client_id"] = introspection.ClientId
+	}
+
+	if len(introspection.Scope) > 0 {
+		response["scope"] = strings.Join(introspection.Scope, " ")
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,118 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IntrospectionControllerSuite struct {
+	suite.Suite
+
+	tokenService auth.TokenService[*auth.RefreshTokenPayload]
+	controller   *IntrospectionController
+}
+
+func (suite *IntrospectionControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	tokenService := new(auth.RefreshTokenService)
+	tokenService.Init(jwtService)
+	suite.tokenService = tokenService
+
+	userRepo := new(user.MemoryUserRepository)
+	userRepo.Create(context.Background(), &user.User{Identifier: "user", Status: user.Active})
+
+	clientRepo := new(oauth.MemoryClientRepository)
+	clientRepo.Create(context.Background(), &oauth.Client{Identifier: "app", TokenEndpointAuthMethod: oauth.NoneAuthMethod})
+	clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "api",
+		SecretHash:              "K7gNU3sdo-OL0wNhqoVWhr3g6s1xYv72ol_pe_Unols",
+		TokenEndpointAuthMethod: oauth.ClientSecretBasicAuthMethod,
+	})
+	clientAuthenticator := new(oauth.ClientAuthenticator)
+	clientAuthenticator.Init(clientRepo, []string{oidcIssuer + "/introspect"})
+
+	introspectionService := new(oauth.IntrospectionService)
+	introspectionService.Init(tokenService, tokenService, userRepo, clientRepo, new(oauth.MemoryConsentRepository))
+
+	suite.controller = new(IntrospectionController)
+	suite.controller.Init(clientAuthenticator, introspectionService)
+}
+
+func (suite *IntrospectionControllerSuite) introspect(form url.Values, authorization string) (*http.Response, map[string]interface{}) {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{}
+	context.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if authorization != "" {
+		context.Request.Header.Set(AuthorizationHeader, authorization)
+	}
+	context.Request.Body = io.NopCloser(strings.NewReader(form.Encode()))
+
+	suite.controller.Introspect(context)
+
+	var response map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&response)
+	return w.Result(), response
+}
+
+func (suite *IntrospectionControllerSuite) TestIntrospect_DescribeActiveToken() {
+	token, _ := suite.tokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Scope: []string{"openid", "profile"}})
+
+	result, response := suite.introspect(url.Values{"token": {string(token)}}, basicAuthorization("api", "secret"))
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	assert.Equal(suite.T(), "no-store", result.Header.Get("Cache-Control"))
+	assert.Equal(suite.T(), true, response["active"])
+	assert.Equal(suite.T(), "user", response["sub"])
+	assert.Equal(suite.T(), "app", response["client_id"])
+	assert.Equal(suite.T(), "openid profile", response["scope"])
+	assert.NotNil(suite.T(), response["exp"])
+}
+
+func (suite *IntrospectionControllerSuite) TestIntrospect_OnlyReportInactiveToken() {
+	result, response := suite.introspect(url.Values{"token": {"invalid"}}, basicAuthorization("api", "secret"))
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	assert.Equal(suite.T(), map[string]interface{}{"active": false}, response)
+}
+
+func (suite *IntrospectionControllerSuite) TestIntrospect_FailWithWrongClientSecret() {
+	token, _ := suite.tokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user"})
+
+	result, response := suite.introspect(url.Values{"token": {string(token)}}, basicAuthorization("api", "wrong"))
+
+	assert.Equal(suite.T(), 401, result.StatusCode)
+	assert.Equal(suite.T(), `Basic realm="go-id"`, result.Header.Get("WWW-Authenticate"))
+	assert.Equal(suite.T(), "invalid_client", response["error"])
+}
+
+func (suite *IntrospectionControllerSuite) TestIntrospect_FailForPublicClient() {
+	token, _ := suite.tokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user"})
+
+	result, response := suite.introspect(url.Values{"token": {string(token)}, "client_id": {"app"}}, "")
+
+	assert.Equal(suite.T(), 401, result.StatusCode)
+	assert.Equal(suite.T(), "invalid_client", response["error"])
+}
+
+func TestIntrospectionController(t *testing.T) {
+	suite.Run(t, new(IntrospectionControllerSuite))
+}
@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	curves       = map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+	curveMethods = map[string]signingMethod{"P-256": ES256, "P-384": ES384, "P-521": ES512}
+)
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func PublicJwk(method signingMethod, publicKey string) (*Jwk, error) {
+	key, err := decodePublicPem(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk := &Jwk{Use: "sig", Alg: string(method)}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBigInt(key.N, 0)
+		jwk.E = encodeBigInt(big.NewInt(int64(key.E)), 0)
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = encodeBigInt(key.X, size)
+		jwk.Y = encodeBigInt(key.Y, size)
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+
+	jwk.Kid = jwk.thumbprint()
+	return jwk, nil
+}
+
+func (jwk *Jwk) thumbprint() string {
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	}
+
+	encoded, _ := json.Marshal(members)
+	hash := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func encodeBigInt(value *big.Int, size int) string {
+	bytes := value.Bytes()
+	if len(bytes) < size {
+		bytes = append(make([]byte, size-len(bytes)), bytes...)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func (jwk *Jwk) PublicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := decodeBigInt(jwk.N)
+		e, errE := decodeBigInt(jwk.E)
+		if errN != nil || errE != nil || !e.IsInt64() {
+			return nil, errors.New("invalid rsa key")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[jwk.Crv]
+		if !ok {
+			return nil, errors.New("unsupported curve")
+		}
+
+		x, errX := decodeBigInt(jwk.X)
+		y, errY := decodeBigInt(jwk.Y)
+		if errX != nil || errY != nil || !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid ec key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+}
+
+func (jwk *Jwk) Verify(token Jwt) (map[string]interface{}, error) {
+	key, err := jwk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(string(token), claims, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods(jwk.methods()))
+
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired {
+		return nil, ErrTokenExpired
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (jwk *Jwk) methods() []string {
+	var candidates []signingMethod
+	switch jwk.Kty {
+	case "RSA":
+		candidates = []signingMethod{RS256, RS384, RS512, PS256, PS384, PS512}
+	case "EC":
+		candidates = []signingMethod{curveMethods[jwk.Crv]}
+	}
+
+	methods := []string{}
+	for _, method := range candidates {
+		if jwk.Alg == "" || jwk.Alg == string(method) {
+			methods = append(methods, string(method))
+		}
+	}
+	return methods
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(bytes) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
@@ -0,0 +1,141 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	. "github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JwkTestSuite struct {
+	suite.Suite
+}
+
+func decodeBigInt(value string) *big.Int {
+	bytes, _ := base64.RawURLEncoding.DecodeString(value)
+	return new(big.Int).SetBytes(bytes)
+}
+
+func parsePublicKey(publicKey string) interface{} {
+	block, _ := pem.Decode([]byte(publicKey))
+	key, _ := x509.ParsePKIXPublicKey(block.Bytes)
+	return key
+}
+
+func (suite *JwkTestSuite) TestPublicJwk_ConvertRsaKey() {
+	jwk, err := PublicJwk(RS256, rsaPublicKey)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "RSA", jwk.Kty)
+	assert.Equal(suite.T(), "RS256", jwk.Alg)
+	assert.Equal(suite.T(), "sig", jwk.Use)
+	assert.Equal(suite.T(), "AQAB", jwk.E)
+	assert.NotEmpty(suite.T(), jwk.Kid)
+
+	converted := &rsa.PublicKey{N: decodeBigInt(jwk.N), E: int(decodeBigInt(jwk.E).Int64())}
+	assert.Equal(suite.T(), parsePublicKey(rsaPublicKey), converted)
+}
+
+func (suite *JwkTestSuite) TestPublicJwk_ConvertEcdsaKey() {
+	jwk, err := PublicJwk(ES256, ecdsaPublicKey)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "EC", jwk.Kty)
+	assert.Equal(suite.T(), "P-256", jwk.Crv)
+	assert.Len(suite.T(), jwk.X, 43)
+	assert.Len(suite.T(), jwk.Y, 43)
+
+	converted := &ecdsa.PublicKey{Curve: elliptic.P256(), X: decodeBigInt(jwk.X), Y: decodeBigInt(jwk.Y)}
+	assert.Equal(suite.T(), parsePublicKey(ecdsaPublicKey), converted)
+}
+
+func (suite *JwkTestSuite) TestPublicJwk_StableKeyId() {
+	first, _ := PublicJwk(RS256, rsaPublicKey)
+	second, _ := PublicJwk(RS256, rsaPublicKey)
+	other, _ := PublicJwk(ES256, ecdsaPublicKey)
+
+	assert.Equal(suite.T(), first.Kid, second.Kid)
+	assert.NotEqual(suite.T(), first.Kid, other.Kid)
+}
+
+func (suite *JwkTestSuite) TestPublicJwk_ErrorForSymmetricService() {
+	jwtService := new(JwtService[SecretString])
+	jwtService.Init(HS256, NewSecretValue("secret"))
+
+	jwk, err := jwtService.PublicJwk()
+
+	assert.Nil(suite.T(), jwk)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *JwkTestSuite) TestPublicJwk_PublishKeyOfService() {
+	jwtService := new(JwtService[KeyPair])
+	jwtService.Init(RS256, NewSecretPair(KeyPair{PrivateKey: rsaPrivateKey, PublicKey: rsaPublicKey}))
+
+	jwk, err := jwtService.PublicJwk()
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "RS256", jwtService.Method())
+	assert.Equal(suite.T(), "RSA", jwk.Kty)
+}
+
+func (suite *JwkTestSuite) TestVerify_AcceptRsaTokenSignedWithKey() {
+	jwk, _ := PublicJwk(RS256, rsaPublicKey)
+	token, _ := CreateJwt(RS256, map[string]interface{}{"sub": "client"}, rsaPrivateKey)
+
+	claims, err := jwk.Verify(token)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "client", claims["sub"])
+}
+
+func (suite *JwkTestSuite) TestVerify_AcceptEcdsaTokenSignedWithKey() {
+	jwk, _ := PublicJwk(ES256, ecdsaPublicKey)
+	token, _ := CreateJwt(ES256, map[string]interface{}{"sub": "client"}, ecdsaPrivateKey)
+
+	claims, err := jwk.Verify(token)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "client", claims["sub"])
+}
+
+func (suite *JwkTestSuite) TestVerify_RejectSymmetricTokenKeyedWithPublicKey() {
+	jwk, _ := PublicJwk(RS256, rsaPublicKey)
+	jwk.Alg = ""
+	token, _ := CreateJwt(HS256, map[string]interface{}{"sub": "client"}, rsaPublicKey)
+
+	_, err := jwk.Verify(token)
+
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *JwkTestSuite) TestVerify_RejectTokenOfOtherKey() {
+	jwk, _ := PublicJwk(ES256, ecdsaPublicKey)
+	token, _ := CreateJwt(RS256, map[string]interface{}{"sub": "client"}, rsaPrivateKey)
+
+	_, err := jwk.Verify(token)
+
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *JwkTestSuite) TestVerify_ErrorWhenExpired() {
+	jwk, _ := PublicJwk(RS256, rsaPublicKey)
+	token, _ := CreateJwt(RS256, map[string]interface{}{"exp": 1}, rsaPrivateKey)
+
+	_, err := jwk.Verify(token)
+
+	assert.ErrorIs(suite.T(), err, ErrTokenExpired)
+}
+
+func TestJwk(t *testing.T) {
+	suite.Run(t, new(JwkTestSuite))
+}
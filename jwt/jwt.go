@@ -28,10 +28,15 @@ const (
 	PS512 signingMethod = "PS512"
 )
 
+var (
+	ErrTokenExpired = errors.New("token is expired")
+	ErrMalformed    = errors.New("jwt malformed")
+)
+
 var SigningMethods = []signingMethod{HS256, HS384, HS512, RS256, RS384, RS512, PS256, PS384, PS512}
 
 func readBase64Json(base64Json string) (map[string]interface{}, error) {
-	utf8Json, err := base64.RawStdEncoding.DecodeString(base64Json)
+	utf8Json, err := base64.RawURLEncoding.DecodeString(base64Json)
 	if err != nil {
 		return map[string]interface{}{}, errors.New("cannot convert from base64")
 	}
@@ -57,8 +62,8 @@ type payload map[string]interface{}
 func (jwt *Jwt) Header() (header, error) {
 	splitJwt := strings.SplitN(string(*jwt), ".", 3)
 
-	if len(splitJwt) <= 3 {
-		errors.New("jwt malformed")
+	if len(splitJwt) != 3 {
+		return header{}, ErrMalformed
 	}
 
 	headerMap, err := readBase64Json(splitJwt[0])
@@ -81,8 +86,8 @@ func (jwt *Jwt) Header() (header, error) {
 func (jwt *Jwt) Payload() (payload, error) {
 	splitJwt := strings.SplitN(string(*jwt), ".", 3)
 
-	if len(splitJwt) <= 3 {
-		errors.New("jwt malformed")
+	if len(splitJwt) != 3 {
+		return payload{}, ErrMalformed
 	}
 
 	payloadMap, err := readBase64Json(splitJwt[1])
@@ -93,23 +98,15 @@ func (jwt *Jwt) Payload() (payload, error) {
 	return payload(payloadMap), nil
 }
 
-func (token *Jwt) Validate(key string) error {
+func (token *Jwt) Validate(method signingMethod, key string) error {
 	_, err := jwt.Parse(string(*token), func(t *jwt.Token) (interface{}, error) {
-		switch t.Method.(type) {
-		case *jwt.SigningMethodHMAC:
-			return []byte(key), nil
-		case *jwt.SigningMethodRSA:
-			return decodePublicPem(key)
-		case *jwt.SigningMethodECDSA:
-			return decodePublicPem(key)
-		case *jwt.SigningMethodRSAPSS:
-			return decodePublicPem(key)
-		default:
-			return nil, errors.New("unknown signing method")
-		}
-
-	})
+		return decodeVerificationKey(method, key)
+	}, jwt.WithValidMethods([]string{string(method)}))
 
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired {
+		return ErrTokenExpired
+	}
 	return err
 }
 
@@ -140,8 +137,13 @@ func decodePrivatePem(pemString string) (interface{}, error) {
 }
 
 func CreateJwt(method signingMethod, data payload, key string) (Jwt, error) {
+	return CreateTypedJwt(method, "JWT", data, key)
+}
+
+func CreateTypedJwt(method signingMethod, typ string, data payload, key string) (Jwt, error) {
 	signing := convertSigningMethod(method)
 	token := jwt.NewWithClaims(signing, jwt.MapClaims(data))
+	token.Header["typ"] = typ
 	decodedKey, _ := decodeKey(method, key)
 	tokenString, err := token.SignedString(decodedKey)
 
@@ -189,3 +191,14 @@ func decodeKey(method signingMethod, key string) (interface{}, error) {
 		return nil, errors.New("unknown signing method")
 	}
 }
+
+func decodeVerificationKey(method signingMethod, key string) (interface{}, error) {
+	switch method {
+	case HS256, HS384, HS512:
+		return []byte(key), nil
+	case RS256, RS384, RS512, ES256, ES384, ES512, PS256, PS384, PS512:
+		return decodePublicPem(key)
+	default:
+		return nil, errors.New("unknown signing method")
+	}
+}
@@ -17,13 +17,41 @@ func (service *JwtService[Type]) Init(method signingMethod, secret Secret[Type])
 }
 
 func (service *JwtService[Type]) Create(data map[string]interface{}) (Jwt, error) {
+	return service.CreateTyped("JWT", data)
+}
+
+func (service *JwtService[Type]) CreateTyped(typ string, data map[string]interface{}) (Jwt, error) {
 	if str, ok := any(service.Secret.GetSecret()).(SecretString); ok == true {
-		return CreateJwt(service.method, data, string(str))
+		return CreateTypedJwt(service.method, typ, data, string(str))
 	}
 
 	if pair, ok := any(service.Secret.GetSecret()).(KeyPair); ok == true {
-		return CreateJwt(service.method, data, string(pair.PrivateKey))
+		return CreateTypedJwt(service.method, typ, data, string(pair.PrivateKey))
 	}
 
 	return Jwt(""), errors.New("unknown secret type")
 }
+
+func (service *JwtService[Type]) Validate(token Jwt) error {
+	if str, ok := any(service.Secret.GetSecret()).(SecretString); ok == true {
+		return token.Validate(service.method, string(str))
+	}
+
+	if pair, ok := any(service.Secret.GetSecret()).(KeyPair); ok == true {
+		return token.Validate(service.method, string(pair.PublicKey))
+	}
+
+	return errors.New("unknown secret type")
+}
+
+func (service *JwtService[Type]) Method() string {
+	return string(service.method)
+}
+
+func (service *JwtService[Type]) PublicJwk() (*Jwk, error) {
+	pair, ok := any(service.Secret.GetSecret()).(KeyPair)
+	if !ok {
+		return nil, errors.New("symmetric secrets cannot be published")
+	}
+	return PublicJwk(service.method, string(pair.PublicKey))
+}
@@ -30,7 +30,7 @@ func (suite *JwtServiceTestSuite) TestJwtCreate_CreateHS256Token() {
 	assert.Equal(suite.T(), "HS256", header.Alg)
 	assert.Nil(suite.T(), payloadErr)
 	assert.Equal(suite.T(), data, map[string]interface{}(payload))
-	assert.Nil(suite.T(), token.Validate(string(key.GetSecret())))
+	assert.Nil(suite.T(), jwtService.Validate(token))
 	assert.Nil(suite.T(), err)
 }
 
@@ -55,7 +55,7 @@ func (suite *JwtServiceTestSuite) TestJwtCreate_CreateRS256Token() {
 	assert.Equal(suite.T(), "RS256", header.Alg)
 	assert.Nil(suite.T(), payloadErr)
 	assert.Equal(suite.T(), data, map[string]interface{}(payload))
-	assert.Nil(suite.T(), token.Validate(string(keyPair.GetSecret().PublicKey)))
+	assert.Nil(suite.T(), jwtService.Validate(token))
 	assert.Nil(suite.T(), err)
 }
 
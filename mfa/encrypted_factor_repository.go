@@ -0,0 +1,48 @@
+package mfa
+
+import "github.com/Untanky/go-id/secret"
+
+type EncryptedFactorRepository struct {
+	repo     FactorRepository
+	envelope *secret.EnvelopeService
+}
+
+func (repo *EncryptedFactorRepository) Init(factorRepo FactorRepository, envelope *secret.EnvelopeService) {
+	repo.repo = factorRepo
+	repo.envelope = envelope
+}
+
+func (repo *EncryptedFactorRepository) FindByUser(userId string) ([]*Factor, error) {
+	sealedFactors, err := repo.repo.FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	factors := make([]*Factor, len(sealedFactors))
+	for i, sealed := range sealedFactors {
+		plaintext, err := repo.envelope.Open(sealed.Challenge.Secret.GetSecret())
+		if err != nil {
+			return nil, err
+		}
+
+		factor := *sealed
+		factor.Challenge.Secret = secret.NewSecretValue(string(plaintext))
+		factors[i] = &factor
+	}
+	return factors, nil
+}
+
+func (repo *EncryptedFactorRepository) Create(factor *Factor) error {
+	sealed, err := repo.envelope.Seal(factor.Challenge.Secret.GetSecret())
+	if err != nil {
+		return err
+	}
+
+	sealedFactor := *factor
+	sealedFactor.Challenge.Secret = secret.NewSecretValue(string(sealed))
+	return repo.repo.Create(&sealedFactor)
+}
+
+func (repo *EncryptedFactorRepository) Remove(identifier string) error {
+	return repo.repo.Remove(identifier)
+}
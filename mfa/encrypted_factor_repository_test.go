@@ -0,0 +1,71 @@
+package mfa_test
+
+import (
+	"testing"
+
+	. "github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EncryptedFactorRepoTestSuite struct {
+	suite.Suite
+	storage *MemoryFactorRepository
+	factor  *Factor
+	repo    *EncryptedFactorRepository
+}
+
+func (suite *EncryptedFactorRepoTestSuite) SetupTest() {
+	envelope := new(secret.EnvelopeService)
+	envelope.Init(secret.NewRotatingSecret(secret.NewSecretValue("master")), new(secret.MemoryDataKeyRepository))
+
+	suite.storage = new(MemoryFactorRepository)
+	suite.repo = new(EncryptedFactorRepository)
+	suite.repo.Init(suite.storage, envelope)
+
+	suite.factor = &Factor{
+		Identifier: "totp",
+		UserId:     "abc",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("JBSWY3DPEHPK3PXP"),
+		},
+	}
+}
+
+func (suite *EncryptedFactorRepoTestSuite) TestCreate_StoreSecretEncrypted() {
+	err := suite.repo.Create(suite.factor)
+	assert.Nil(suite.T(), err)
+
+	stored, _ := suite.storage.FindByUser("abc")
+	assert.Len(suite.T(), stored, 1)
+	assert.NotEqual(suite.T(), secret.SecretString("JBSWY3DPEHPK3PXP"), stored[0].Challenge.Secret.GetSecret())
+	assert.Equal(suite.T(), secret.SecretString("JBSWY3DPEHPK3PXP"), suite.factor.Challenge.Secret.GetSecret())
+}
+
+func (suite *EncryptedFactorRepoTestSuite) TestFindByUser_ReturnDecryptedSecret() {
+	suite.repo.Create(suite.factor)
+
+	factors, err := suite.repo.FindByUser("abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), factors, 1)
+	assert.Equal(suite.T(), "totp", factors[0].Identifier)
+	assert.Equal(suite.T(), secret.SecretString("JBSWY3DPEHPK3PXP"), factors[0].Challenge.Secret.GetSecret())
+}
+
+func (suite *EncryptedFactorRepoTestSuite) TestRemove_RemoveFromStorage() {
+	suite.repo.Create(suite.factor)
+
+	err := suite.repo.Remove("totp")
+
+	assert.Nil(suite.T(), err)
+	stored, _ := suite.storage.FindByUser("abc")
+	assert.Len(suite.T(), stored, 0)
+}
+
+func TestEncryptedFactorRepository(t *testing.T) {
+	suite.Run(t, new(EncryptedFactorRepoTestSuite))
+}
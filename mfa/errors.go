@@ -0,0 +1,8 @@
+package mfa
+
+import "errors"
+
+var (
+	ErrInvalidOtp        = errors.New("invalid otp")
+	ErrChallengeNotFound = errors.New("mfa challenge is invalid or already used")
+)
@@ -0,0 +1,25 @@
+package mfa
+
+import "github.com/Untanky/go-id/totp"
+
+type Factor struct {
+	Identifier string
+	UserId     string
+	Challenge  totp.Challenge
+}
+
+func (factor *Factor) Amr() string {
+	if factor.Identifier == RecoveryCodeFactor {
+		return "recovery"
+	}
+
+	switch factor.Challenge.ChallengeType {
+	case totp.SMS_CHALLENGE:
+		return "sms"
+	case totp.EMAIL_CHALLENGE:
+		return "email"
+	case totp.MFA_CHALLENGE:
+		return "otp"
+	}
+	return "mfa"
+}
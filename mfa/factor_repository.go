@@ -0,0 +1,7 @@
+package mfa
+
+type FactorRepository interface {
+	FindByUser(userId string) ([]*Factor, error)
+	Create(factor *Factor) error
+	Remove(identifier string) error
+}
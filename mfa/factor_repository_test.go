@@ -0,0 +1,89 @@
+package mfa_test
+
+import (
+	"testing"
+
+	. "github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FactorRepoTestSuite struct {
+	suite.Suite
+	factor0 *Factor
+	factor1 *Factor
+	repo    FactorRepository
+}
+
+func (suite *FactorRepoTestSuite) SetupTest() {
+	suite.factor0 = &Factor{
+		Identifier: "factor0",
+		UserId:     "abc",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("secret"),
+		},
+	}
+	suite.factor1 = &Factor{
+		Identifier: "factor1",
+		UserId:     "abc",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.SMS_CHALLENGE,
+			Secret:        secret.NewSecretValue("secret"),
+		},
+	}
+	suite.repo = new(MemoryFactorRepository)
+}
+
+func (suite *FactorRepoTestSuite) TestFindByUser_EmptyWhenNoFactorEnrolled() {
+	factors, err := suite.repo.FindByUser("abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), factors, 0)
+}
+
+func (suite *FactorRepoTestSuite) TestCreate_CreateTwoThenFindTwo() {
+	err := suite.repo.Create(suite.factor0)
+	assert.Nil(suite.T(), err)
+	err = suite.repo.Create(suite.factor1)
+	assert.Nil(suite.T(), err)
+
+	factors, err := suite.repo.FindByUser("abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*Factor{suite.factor0, suite.factor1}, factors)
+
+	factors, err = suite.repo.FindByUser("other")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), factors, 0)
+}
+
+func (suite *FactorRepoTestSuite) TestCreate_ErrorWhenIdentifierAlreadyExists() {
+	err := suite.repo.Create(suite.factor0)
+	assert.Nil(suite.T(), err)
+	err = suite.repo.Create(suite.factor0)
+	assert.ErrorContains(suite.T(), err, "already exists")
+}
+
+func (suite *FactorRepoTestSuite) TestRemove_ErrorWhenFactorNotFound() {
+	err := suite.repo.Remove(suite.factor0.Identifier)
+
+	assert.ErrorContains(suite.T(), err, "no factor found")
+}
+
+func (suite *FactorRepoTestSuite) TestRemove_RemoveFactorFromUser() {
+	suite.repo.Create(suite.factor0)
+	suite.repo.Create(suite.factor1)
+
+	err := suite.repo.Remove(suite.factor0.Identifier)
+	assert.Nil(suite.T(), err)
+
+	factors, err := suite.repo.FindByUser("abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*Factor{suite.factor1}, factors)
+}
+
+func TestFactorRepository(t *testing.T) {
+	suite.Run(t, new(FactorRepoTestSuite))
+}
@@ -0,0 +1,40 @@
+package mfa
+
+import "errors"
+
+type MemoryFactorRepository struct {
+	factors []*Factor
+}
+
+func (repo *MemoryFactorRepository) FindByUser(userId string) ([]*Factor, error) {
+	factors := []*Factor{}
+
+	for _, factor := range repo.factors {
+		if factor.UserId == userId {
+			factors = append(factors, factor)
+		}
+	}
+	return factors, nil
+}
+
+func (repo *MemoryFactorRepository) Create(factor *Factor) error {
+	for _, f := range repo.factors {
+		if f.Identifier == factor.Identifier {
+			return errors.New("factor already exists")
+		}
+	}
+
+	repo.factors = append(repo.factors, factor)
+	return nil
+}
+
+func (repo *MemoryFactorRepository) Remove(identifier string) error {
+	for index, factor := range repo.factors {
+		if factor.Identifier == identifier {
+			repo.factors[index] = repo.factors[len(repo.factors)-1]
+			repo.factors = repo.factors[:len(repo.factors)-1]
+			return nil
+		}
+	}
+	return errors.New("no factor found")
+}
@@ -0,0 +1,42 @@
+package mfa
+
+import "errors"
+
+type MemoryRecoveryCodeRepository struct {
+	codes []*RecoveryCode
+}
+
+func (repo *MemoryRecoveryCodeRepository) FindByUser(userId string) ([]*RecoveryCode, error) {
+	codes := []*RecoveryCode{}
+
+	for _, code := range repo.codes {
+		if code.UserId == userId {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func (repo *MemoryRecoveryCodeRepository) Replace(userId string, codes []*RecoveryCode) error {
+	kept := []*RecoveryCode{}
+
+	for _, code := range repo.codes {
+		if code.UserId != userId {
+			kept = append(kept, code)
+		}
+	}
+
+	repo.codes = append(kept, codes...)
+	return nil
+}
+
+func (repo *MemoryRecoveryCodeRepository) Remove(code *RecoveryCode) error {
+	for index, c := range repo.codes {
+		if c.UserId == code.UserId && c.Hash == code.Hash {
+			repo.codes[index] = repo.codes[len(repo.codes)-1]
+			repo.codes = repo.codes[:len(repo.codes)-1]
+			return nil
+		}
+	}
+	return errors.New("no recovery code found")
+}
@@ -0,0 +1,254 @@
+package mfa
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Untanky/go-id/totp"
+)
+
+const (
+	ChallengeLifetime = 5 * time.Minute
+	maxVerifyAttempts = 5
+)
+
+type pendingChallenge struct {
+	UserId   string
+	Attempts int
+	Expires  time.Time
+}
+
+type MfaService struct {
+	factorRepo       FactorRepository
+	recoveryCodeRepo RecoveryCodeRepository
+	otpService       *totp.OtpService
+	sender           Sender
+	challenges       map[int64]*pendingChallenge
+	lock             sync.Mutex
+}
+
+func (service *MfaService) Init(
+	factorRepo FactorRepository,
+	recoveryCodeRepo RecoveryCodeRepository,
+	otpService *totp.OtpService,
+	sender Sender,
+) {
+	service.factorRepo = factorRepo
+	service.recoveryCodeRepo = recoveryCodeRepo
+	service.otpService = otpService
+	service.sender = sender
+	service.challenges = map[int64]*pendingChallenge{}
+}
+
+func (service *MfaService) Enroll(factor *Factor) ([]string, error) {
+	enrolled, err := service.IsEnrolled(factor.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.factorRepo.Create(factor); err != nil {
+		return nil, err
+	}
+
+	if enrolled {
+		return nil, nil
+	}
+
+	return service.RegenerateRecoveryCodes(factor.UserId)
+}
+
+func (service *MfaService) RegenerateRecoveryCodes(userId string) ([]string, error) {
+	enrolled, err := service.IsEnrolled(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enrolled {
+		return nil, errors.New("no factor enrolled")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]*RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = code
+		hashes[i] = &RecoveryCode{
+			UserId: userId,
+			Hash:   hashRecoveryCode(code),
+		}
+	}
+
+	if err := service.recoveryCodeRepo.Replace(userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (service *MfaService) Reset(userId string) error {
+	factors, err := service.factorRepo.FindByUser(userId)
+	if err != nil {
+		return err
+	}
+
+	for _, factor := range factors {
+		if err := service.factorRepo.Remove(factor.Identifier); err != nil {
+			return err
+		}
+	}
+
+	return service.recoveryCodeRepo.Replace(userId, nil)
+}
+
+func (service *MfaService) RemainingRecoveryCodes(userId string) (int, error) {
+	codes, err := service.recoveryCodeRepo.FindByUser(userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(codes), nil
+}
+
+func (service *MfaService) Factors(userId string) ([]*Factor, error) {
+	return service.factorRepo.FindByUser(userId)
+}
+
+func (service *MfaService) IsEnrolled(userId string) (bool, error) {
+	factors, err := service.factorRepo.FindByUser(userId)
+	if err != nil {
+		return false, err
+	}
+
+	return len(factors) > 0, nil
+}
+
+func (service *MfaService) Challenge(userId string, event int64) error {
+	factors, err := service.factorRepo.FindByUser(userId)
+	if err != nil {
+		return err
+	}
+
+	service.openChallenge(userId, event)
+
+	for _, factor := range factors {
+		if factor.Challenge.ChallengeType == totp.MFA_CHALLENGE {
+			continue
+		}
+
+		challenge := factor.Challenge
+		challenge.Event = event
+		if err := service.sender.Send(factor, service.otpService.GenerateOtp(challenge)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (service *MfaService) Verify(userId string, otp string, event int64) (*Factor, error) {
+	challenge, ok := service.attemptChallenge(userId, event)
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+
+	factor, err := service.verify(userId, otp, event)
+	if err != nil {
+		return nil, err
+	}
+
+	if !service.consumeChallenge(event, challenge) {
+		return nil, ErrChallengeNotFound
+	}
+	return factor, nil
+}
+
+func (service *MfaService) verify(userId string, otp string, event int64) (*Factor, error) {
+	factors, err := service.factorRepo.FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, factor := range factors {
+		challenge := factor.Challenge
+		if challenge.ChallengeType != totp.MFA_CHALLENGE {
+			challenge.Event = event
+		}
+
+		if service.otpService.ValidateOtp(otp, challenge) {
+			return factor, nil
+		}
+	}
+
+	if len(factors) > 0 && service.useRecoveryCode(userId, otp) {
+		return &Factor{
+			Identifier: RecoveryCodeFactor,
+			UserId:     userId,
+		}, nil
+	}
+	return nil, ErrInvalidOtp
+}
+
+func (service *MfaService) openChallenge(userId string, event int64) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	now := time.Now()
+	for pending, challenge := range service.challenges {
+		if !now.Before(challenge.Expires) {
+			delete(service.challenges, pending)
+		}
+	}
+
+	service.challenges[event] = &pendingChallenge{
+		UserId:  userId,
+		Expires: now.Add(ChallengeLifetime),
+	}
+}
+
+func (service *MfaService) attemptChallenge(userId string, event int64) (*pendingChallenge, bool) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	challenge, ok := service.challenges[event]
+	if !ok || challenge.UserId != userId {
+		return nil, false
+	}
+
+	challenge.Attempts++
+	if !time.Now().Before(challenge.Expires) || challenge.Attempts > maxVerifyAttempts {
+		delete(service.challenges, event)
+		return nil, false
+	}
+	return challenge, true
+}
+
+func (service *MfaService) consumeChallenge(event int64, challenge *pendingChallenge) bool {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if service.challenges[event] != challenge {
+		return false
+	}
+
+	delete(service.challenges, event)
+	return true
+}
+
+func (service *MfaService) useRecoveryCode(userId string, code string) bool {
+	codes, err := service.recoveryCodeRepo.FindByUser(userId)
+	if err != nil {
+		return false
+	}
+
+	hash := hashRecoveryCode(code)
+	for _, recoveryCode := range codes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode.Hash), []byte(hash)) == 1 {
+			return service.recoveryCodeRepo.Remove(recoveryCode) == nil
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package mfa_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MfaServiceTestSuite struct {
+	suite.Suite
+	otpService       *totp.OtpService
+	sender           *MockSender
+	recoveryCodeRepo RecoveryCodeRepository
+	totpFactor       *Factor
+	smsFactor        *Factor
+	service          *MfaService
+}
+
+func (suite *MfaServiceTestSuite) SetupTest() {
+	suite.otpService = new(totp.OtpService)
+	suite.otpService.Init(30)
+	suite.sender = new(MockSender)
+
+	suite.totpFactor = &Factor{
+		Identifier: "totp",
+		UserId:     "abc",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("totpsecret"),
+		},
+	}
+	suite.smsFactor = &Factor{
+		Identifier: "sms",
+		UserId:     "abc",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.SMS_CHALLENGE,
+			Secret:        secret.NewSecretValue("smssecret"),
+		},
+	}
+
+	repo := new(MemoryFactorRepository)
+	repo.Create(suite.totpFactor)
+	repo.Create(suite.smsFactor)
+
+	suite.service = new(MfaService)
+	suite.recoveryCodeRepo = new(MemoryRecoveryCodeRepository)
+	suite.service.Init(repo, suite.recoveryCodeRepo, suite.otpService, suite.sender)
+}
+
+func (suite *MfaServiceTestSuite) challenge(userId string, event int64) {
+	suite.sender.On("Send", suite.smsFactor, mock.Anything).Return(nil)
+	assert.Nil(suite.T(), suite.service.Challenge(userId, event))
+}
+
+func (suite *MfaServiceTestSuite) TestIsEnrolled_TrueWhenUserHasFactors() {
+	enrolled, err := suite.service.IsEnrolled("abc")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), enrolled)
+
+	enrolled, err = suite.service.IsEnrolled("unknown")
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), enrolled)
+}
+
+func (suite *MfaServiceTestSuite) TestChallenge_SendOtpForSmsFactorOnly() {
+	expectedOtp := totp.GenerateHotp("smssecret", 42)
+	suite.sender.On("Send", suite.smsFactor, expectedOtp).Return(nil)
+
+	err := suite.service.Challenge("abc", 42)
+
+	assert.Nil(suite.T(), err)
+	suite.sender.AssertExpectations(suite.T())
+	suite.sender.AssertNumberOfCalls(suite.T(), "Send", 1)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_AcceptTotp() {
+	otp := totp.GenerateTotp("totpsecret", 30)
+	suite.challenge("abc", 42)
+
+	factor, err := suite.service.Verify("abc", otp, 42)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.totpFactor, factor)
+	assert.Equal(suite.T(), "otp", factor.Amr())
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_AcceptSmsCodeForEvent() {
+	otp := totp.GenerateHotp("smssecret", 42)
+	suite.challenge("abc", 42)
+
+	factor, err := suite.service.Verify("abc", otp, 42)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.smsFactor, factor)
+	assert.Equal(suite.T(), "sms", factor.Amr())
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_ErrorWhenSmsCodeForOtherEvent() {
+	otp := totp.GenerateHotp("smssecret", 41)
+	suite.challenge("abc", 42)
+
+	factor, err := suite.service.Verify("abc", otp, 42)
+
+	assert.ErrorContains(suite.T(), err, "invalid otp")
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_ErrorWhenUserHasNoFactors() {
+	otp := totp.GenerateTotp("totpsecret", 30)
+	suite.challenge("unknown", 42)
+
+	factor, err := suite.service.Verify("unknown", otp, 42)
+
+	assert.ErrorContains(suite.T(), err, "invalid otp")
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_ErrorWithoutChallenge() {
+	otp := totp.GenerateTotp("totpsecret", 30)
+
+	factor, err := suite.service.Verify("abc", otp, 42)
+
+	assert.ErrorIs(suite.T(), err, ErrChallengeNotFound)
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_ErrorWhenChallengeBelongsToOtherUser() {
+	suite.challenge("abc", 42)
+	otp := totp.GenerateTotp("totpsecret", 30)
+
+	factor, err := suite.service.Verify("def", otp, 42)
+
+	assert.ErrorIs(suite.T(), err, ErrChallengeNotFound)
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_ChallengeIsSingleUse() {
+	suite.challenge("abc", 42)
+	otp := totp.GenerateTotp("totpsecret", 30)
+
+	_, err := suite.service.Verify("abc", otp, 42)
+	assert.Nil(suite.T(), err)
+
+	factor, err := suite.service.Verify("abc", otp, 42)
+	assert.ErrorIs(suite.T(), err, ErrChallengeNotFound)
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_ConcurrentSubmissionsSucceedOnce() {
+	suite.challenge("abc", 42)
+	otp := totp.GenerateTotp("totpsecret", 30)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := suite.service.Verify("abc", otp, 42)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(suite.T(), err, ErrChallengeNotFound)
+		}
+	}
+	assert.Equal(suite.T(), 1, succeeded)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_InvalidateChallengeAfterTooManyAttempts() {
+	suite.challenge("abc", 42)
+
+	for i := 0; i < 5; i++ {
+		_, err := suite.service.Verify("abc", "000000", 42)
+		assert.ErrorIs(suite.T(), err, ErrInvalidOtp)
+	}
+
+	factor, err := suite.service.Verify("abc", totp.GenerateTotp("totpsecret", 30), 42)
+	assert.ErrorIs(suite.T(), err, ErrChallengeNotFound)
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestEnroll_GenerateRecoveryCodesForFirstFactor() {
+	factor := &Factor{
+		Identifier: "email",
+		UserId:     "def",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.EMAIL_CHALLENGE,
+			Secret:        secret.NewSecretValue("emailsecret"),
+		},
+	}
+
+	codes, err := suite.service.Enroll(factor)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), codes, 10)
+	stored, _ := suite.recoveryCodeRepo.FindByUser("def")
+	assert.Len(suite.T(), stored, 10)
+	for _, code := range stored {
+		assert.NotContains(suite.T(), codes, code.Hash)
+	}
+}
+
+func (suite *MfaServiceTestSuite) TestEnroll_KeepRecoveryCodesForFurtherFactors() {
+	factor := &Factor{
+		Identifier: "email",
+		UserId:     "abc",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.EMAIL_CHALLENGE,
+			Secret:        secret.NewSecretValue("emailsecret"),
+		},
+	}
+
+	codes, err := suite.service.Enroll(factor)
+
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), codes)
+	factors, _ := suite.service.Factors("abc")
+	assert.Len(suite.T(), factors, 3)
+}
+
+func (suite *MfaServiceTestSuite) TestRegenerateRecoveryCodes_InvalidateOldSet() {
+	oldCodes, err := suite.service.RegenerateRecoveryCodes("abc")
+	assert.Nil(suite.T(), err)
+
+	newCodes, err := suite.service.RegenerateRecoveryCodes("abc")
+	assert.Nil(suite.T(), err)
+
+	remaining, _ := suite.service.RemainingRecoveryCodes("abc")
+	assert.Equal(suite.T(), 10, remaining)
+	suite.challenge("abc", 42)
+
+	factor, err := suite.service.Verify("abc", oldCodes[0], 42)
+	assert.ErrorContains(suite.T(), err, "invalid otp")
+	assert.Nil(suite.T(), factor)
+
+	factor, err = suite.service.Verify("abc", newCodes[0], 42)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), RecoveryCodeFactor, factor.Identifier)
+	assert.Equal(suite.T(), "recovery", factor.Amr())
+}
+
+func (suite *MfaServiceTestSuite) TestRegenerateRecoveryCodes_ErrorWhenNotEnrolled() {
+	codes, err := suite.service.RegenerateRecoveryCodes("unknown")
+
+	assert.ErrorContains(suite.T(), err, "no factor enrolled")
+	assert.Nil(suite.T(), codes)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_RecoveryCodeIsSingleUse() {
+	codes, _ := suite.service.RegenerateRecoveryCodes("abc")
+	suite.challenge("abc", 42)
+
+	factor, err := suite.service.Verify("abc", codes[3], 42)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), RecoveryCodeFactor, factor.Identifier)
+
+	remaining, _ := suite.service.RemainingRecoveryCodes("abc")
+	assert.Equal(suite.T(), 9, remaining)
+
+	suite.challenge("abc", 43)
+	factor, err = suite.service.Verify("abc", codes[3], 43)
+	assert.ErrorContains(suite.T(), err, "invalid otp")
+	assert.Nil(suite.T(), factor)
+}
+
+func (suite *MfaServiceTestSuite) TestVerify_RecoveryCodeIgnoresFormatting() {
+	codes, _ := suite.service.RegenerateRecoveryCodes("abc")
+	suite.challenge("abc", 42)
+
+	factor, err := suite.service.Verify("abc", strings.ToLower(strings.ReplaceAll(codes[0], "-", "")), 42)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), RecoveryCodeFactor, factor.Identifier)
+}
+
+func (suite *MfaServiceTestSuite) TestReset_RemoveFactorsAndRecoveryCodes() {
+	suite.service.RegenerateRecoveryCodes("abc")
+
+	err := suite.service.Reset("abc")
+
+	assert.Nil(suite.T(), err)
+	enrolled, _ := suite.service.IsEnrolled("abc")
+	assert.False(suite.T(), enrolled)
+	remaining, _ := suite.service.RemainingRecoveryCodes("abc")
+	assert.Equal(suite.T(), 0, remaining)
+}
+
+func TestMfaService(t *testing.T) {
+	suite.Run(t, new(MfaServiceTestSuite))
+}
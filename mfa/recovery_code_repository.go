@@ -0,0 +1,7 @@
+package mfa
+
+type RecoveryCodeRepository interface {
+	FindByUser(userId string) ([]*RecoveryCode, error)
+	Replace(userId string, codes []*RecoveryCode) error
+	Remove(code *RecoveryCode) error
+}
@@ -0,0 +1,51 @@
+package mfa_test
+
+import (
+	"testing"
+
+	. "github.com/Untanky/go-id/mfa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecoveryCodeRepoTestSuite struct {
+	suite.Suite
+	repo RecoveryCodeRepository
+}
+
+func (suite *RecoveryCodeRepoTestSuite) SetupTest() {
+	suite.repo = new(MemoryRecoveryCodeRepository)
+}
+
+func (suite *RecoveryCodeRepoTestSuite) TestReplace_ReplaceOnlyCodesOfUser() {
+	other := &RecoveryCode{UserId: "other", Hash: "0"}
+	suite.repo.Replace("other", []*RecoveryCode{other})
+	suite.repo.Replace("abc", []*RecoveryCode{{UserId: "abc", Hash: "1"}, {UserId: "abc", Hash: "2"}})
+
+	next := []*RecoveryCode{{UserId: "abc", Hash: "3"}}
+	err := suite.repo.Replace("abc", next)
+	assert.Nil(suite.T(), err)
+
+	codes, err := suite.repo.FindByUser("abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), next, codes)
+
+	codes, err = suite.repo.FindByUser("other")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*RecoveryCode{other}, codes)
+}
+
+func (suite *RecoveryCodeRepoTestSuite) TestRemove_ErrorWhenCodeAlreadyRemoved() {
+	code := &RecoveryCode{UserId: "abc", Hash: "1"}
+	suite.repo.Replace("abc", []*RecoveryCode{code})
+
+	err := suite.repo.Remove(code)
+	assert.Nil(suite.T(), err)
+
+	err = suite.repo.Remove(code)
+	assert.ErrorContains(suite.T(), err, "no recovery code found")
+}
+
+func TestRecoveryCodeRepository(t *testing.T) {
+	suite.Run(t, new(RecoveryCodeRepoTestSuite))
+}
@@ -0,0 +1,5 @@
+package mfa
+
+type Sender interface {
+	Send(factor *Factor, otp string) error
+}
@@ -0,0 +1,15 @@
+package mfa_test
+
+import (
+	. "github.com/Untanky/go-id/mfa"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSender struct {
+	mock.Mock
+}
+
+func (m *MockSender) Send(factor *Factor, otp string) error {
+	args := m.Called(factor, otp)
+	return args.Error(0)
+}
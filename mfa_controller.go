@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/mfa"
+	"github.com/gin-gonic/gin"
+)
+
+type MfaController struct {
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	mfaService          *mfa.MfaService
+}
+
+func (controller *MfaController) Init(
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	mfaService *mfa.MfaService,
+) {
+	controller.sessionTokenService = sessionTokenService
+	controller.mfaService = mfaService
+}
+
+func (controller *MfaController) Factors(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	factors, err := controller.mfaService.Factors(payload.Sub)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "factors could not be loaded",
+		})
+		return
+	}
+
+	remaining, err := controller.mfaService.RemainingRecoveryCodes(payload.Sub)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "recovery codes could not be loaded",
+		})
+		return
+	}
+
+	factorList := make([]gin.H, len(factors))
+	for i, factor := range factors {
+		factorList[i] = gin.H{
+			"id":   factor.Identifier,
+			"type": factor.Challenge.ChallengeType,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"factors":                factorList,
+		"remainingRecoveryCodes": remaining,
+	})
+}
+
+func (controller *MfaController) RegenerateRecoveryCodes(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	codes, err := controller.mfaService.RegenerateRecoveryCodes(payload.Sub)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "recovery codes could not be generated",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": codes,
+	})
+}
+
+func authenticateBearer(c *gin.Context, tokenService auth.TokenService[*auth.RefreshTokenPayload]) (*auth.RefreshTokenPayload, bool) {
+	bearer := c.Request.Header.Get(AuthorizationHeader)
+
+	if !strings.HasPrefix(bearer, "Bearer ") {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "bearer authorization required",
+		})
+		return nil, true
+	}
+
+	payload, err := tokenService.Validate(c.Request.Context(), jwt.Jwt(strings.TrimPrefix(bearer, "Bearer ")))
+	if err != nil {
+		respondProblem(c, http.StatusUnauthorized, fmt.Errorf("invalid access token: %w", err))
+		return nil, true
+	}
+
+	return payload, false
+}
+
+func authenticateSession(c *gin.Context, tokenService auth.TokenService[*auth.RefreshTokenPayload]) (*auth.RefreshTokenPayload, bool) {
+	payload, shouldReturn := authenticateBearer(c, tokenService)
+	if shouldReturn {
+		return nil, true
+	}
+
+	if payload.ClientId != "" {
+		respondProblem(c, http.StatusForbidden, auth.ErrForbidden)
+		return nil, true
+	}
+
+	return payload, false
+}
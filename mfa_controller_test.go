@@ -0,0 +1,149 @@
+package main_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MfaControllerSuite struct {
+	suite.Suite
+
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	mfaService          *mfa.MfaService
+	controller          *MfaController
+}
+
+func (suite *MfaControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	sessionTokenService := new(auth.RefreshTokenService)
+	sessionTokenService.Init(jwtService)
+	suite.sessionTokenService = sessionTokenService
+
+	otpService := new(totp.OtpService)
+	otpService.Init(30)
+
+	suite.mfaService = new(mfa.MfaService)
+	suite.mfaService.Init(new(mfa.MemoryFactorRepository), new(mfa.MemoryRecoveryCodeRepository), otpService, noopSender{})
+	suite.mfaService.Enroll(&mfa.Factor{
+		Identifier: "totp",
+		UserId:     "mfaUser",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("secret"),
+		},
+	})
+
+	suite.controller = new(MfaController)
+	suite.controller.Init(sessionTokenService, suite.mfaService)
+}
+
+func (suite *MfaControllerSuite) authorize(context *gin.Context, sub string) {
+	token, _ := suite.sessionTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: sub})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *MfaControllerSuite) TestFactors_ListFactorsAndRemainingRecoveryCodes() {
+	w, context := buildContext()
+	suite.authorize(context, "mfaUser")
+
+	suite.controller.Factors(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body struct {
+		Factors []struct {
+			Id   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"factors"`
+		RemainingRecoveryCodes int `json:"remainingRecoveryCodes"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	assert.Len(suite.T(), body.Factors, 1)
+	assert.Equal(suite.T(), "totp", body.Factors[0].Id)
+	assert.Equal(suite.T(), string(totp.MFA_CHALLENGE), body.Factors[0].Type)
+	assert.Equal(suite.T(), 10, body.RemainingRecoveryCodes)
+}
+
+func (suite *MfaControllerSuite) TestFactors_ForbiddenWithClientToken() {
+	for _, payload := range []*auth.RefreshTokenPayload{
+		{Sid: "123", Sub: "mfaUser", ClientId: "app"},
+		{Sub: "service", ClientId: "service"},
+	} {
+		w, context := buildContext()
+		token, _ := suite.sessionTokenService.Create(context.Request.Context(), payload)
+		context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+
+		suite.controller.Factors(context)
+
+		assert.Equal(suite.T(), 403, w.Result().StatusCode)
+	}
+}
+
+func (suite *MfaControllerSuite) TestFactors_FailWithoutBearerToken() {
+	w, context := buildContext()
+
+	suite.controller.Factors(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "bearer authorization required")
+}
+
+func (suite *MfaControllerSuite) TestFactors_FailWithInvalidBearerToken() {
+	w, context := buildContext()
+	context.Request.Header.Set(AuthorizationHeader, "Bearer foo..")
+
+	suite.controller.Factors(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "invalid access token")
+}
+
+func (suite *MfaControllerSuite) TestRegenerateRecoveryCodes_ReturnNewCodes() {
+	w, context := buildContext()
+	suite.authorize(context, "mfaUser")
+
+	suite.controller.RegenerateRecoveryCodes(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	assert.Len(suite.T(), body.RecoveryCodes, 10)
+
+	assert.Nil(suite.T(), suite.mfaService.Challenge("mfaUser", 1))
+	factor, err := suite.mfaService.Verify("mfaUser", body.RecoveryCodes[0], 1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), mfa.RecoveryCodeFactor, factor.Identifier)
+}
+
+func (suite *MfaControllerSuite) TestRegenerateRecoveryCodes_FailWhenNotEnrolled() {
+	w, context := buildContext()
+	suite.authorize(context, "user")
+
+	suite.controller.RegenerateRecoveryCodes(context)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "recovery codes could not be generated")
+}
+
+func TestMfaController(t *testing.T) {
+	suite.Run(t, new(MfaControllerSuite))
+}
@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"database/sql"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+func Apply(db *sql.DB, migrations fs.FS, prefix string) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT NOT NULL PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := prefix + strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		if err := applyMigration(db, migrations, version, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, migrations fs.FS, version string, file string) error {
+	var applied int
+	err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, version).Scan(&applied)
+	if err != nil || applied > 0 {
+		return err
+	}
+
+	script, err := fs.ReadFile(migrations, file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range strings.Split(string(script), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
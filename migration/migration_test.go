@@ -0,0 +1,55 @@
+package migration_test
+
+import (
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/Untanky/go-id/migration"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func openDb(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file::memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func TestApply_RecordVersionsWithPrefix(t *testing.T) {
+	db := openDb(t)
+	defer db.Close()
+	migrations := fstest.MapFS{
+		"migrations/0001_create_items.sql": {Data: []byte(`CREATE TABLE items (id TEXT NOT NULL)`)},
+		"migrations/0002_add_name.sql":     {Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT NOT NULL DEFAULT ''`)},
+	}
+
+	assert.Nil(t, Apply(db, migrations, "items_"))
+	assert.Nil(t, Apply(db, migrations, "items_"))
+
+	rows, err := db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	assert.Nil(t, err)
+	defer rows.Close()
+	versions := []string{}
+	for rows.Next() {
+		var version string
+		rows.Scan(&version)
+		versions = append(versions, version)
+	}
+	assert.Equal(t, []string{"items_0001_create_items", "items_0002_add_name"}, versions)
+}
+
+func TestApply_RollBackFailedMigration(t *testing.T) {
+	db := openDb(t)
+	defer db.Close()
+	migrations := fstest.MapFS{
+		"migrations/0001_broken.sql": {Data: []byte(`CREATE TABLE items (id TEXT); INSERT INTO missing VALUES (1)`)},
+	}
+
+	assert.Error(t, Apply(db, migrations, ""))
+
+	var applied int
+	db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	assert.Equal(t, 0, applied)
+}
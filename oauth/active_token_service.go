@@ -0,0 +1,39 @@
+package oauth
+
+import (
+	"context"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+)
+
+type ActiveTokenService struct {
+	tokenService         auth.TokenService[*auth.RefreshTokenPayload]
+	introspectionService *IntrospectionService
+}
+
+func (service *ActiveTokenService) Init(tokenService auth.TokenService[*auth.RefreshTokenPayload], introspectionService *IntrospectionService) {
+	service.tokenService = tokenService
+	service.introspectionService = introspectionService
+}
+
+func (service *ActiveTokenService) Create(ctx context.Context, payload *auth.RefreshTokenPayload) (jwt.Jwt, error) {
+	return service.tokenService.Create(ctx, payload)
+}
+
+func (service *ActiveTokenService) Validate(ctx context.Context, token jwt.Jwt) (*auth.RefreshTokenPayload, error) {
+	payload, err := service.tokenService.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := service.introspectionService.active(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !active {
+		return nil, newError(ErrInvalidGrant, "token is not active")
+	}
+	return payload, nil
+}
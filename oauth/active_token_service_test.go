@@ -0,0 +1,84 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ActiveTokenServiceTestSuite struct {
+	suite.Suite
+	userRepo    user.UserRepository
+	consentRepo ConsentRepository
+	service     *ActiveTokenService
+}
+
+func (suite *ActiveTokenServiceTestSuite) SetupTest() {
+	accessTokenService := newTokenService("access")
+
+	suite.userRepo = new(user.MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "user", Status: user.Active})
+
+	clientRepo := new(MemoryClientRepository)
+	clientRepo.Create(context.Background(), &Client{Identifier: "app", TokenEndpointAuthMethod: NoneAuthMethod})
+
+	suite.consentRepo = new(MemoryConsentRepository)
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantId: "grant", GrantedAt: time.Now()})
+
+	introspectionService := new(IntrospectionService)
+	introspectionService.Init(accessTokenService, newTokenService("refresh"), suite.userRepo, clientRepo, suite.consentRepo)
+
+	suite.service = new(ActiveTokenService)
+	suite.service.Init(accessTokenService, introspectionService)
+}
+
+func (suite *ActiveTokenServiceTestSuite) token() jwt.Jwt {
+	token, _ := suite.service.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Gid: "grant", Scope: []string{"profile"}})
+	return token
+}
+
+func (suite *ActiveTokenServiceTestSuite) TestValidate_ActiveToken() {
+	payload, err := suite.service.Validate(context.Background(), suite.token())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", payload.Sub)
+}
+
+func (suite *ActiveTokenServiceTestSuite) TestValidate_ErrorAfterConsentRevoked() {
+	token := suite.token()
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "app")
+	consent.Revoke(time.Now())
+	suite.consentRepo.Save(context.Background(), consent)
+
+	_, err := suite.service.Validate(context.Background(), token)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *ActiveTokenServiceTestSuite) TestValidate_ErrorForSuspendedUser() {
+	token := suite.token()
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), "user")
+	found.Status = user.Suspended
+	suite.userRepo.Update(context.Background(), found)
+
+	_, err := suite.service.Validate(context.Background(), token)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *ActiveTokenServiceTestSuite) TestValidate_ErrorForInvalidToken() {
+	_, err := suite.service.Validate(context.Background(), jwt.Jwt("invalid"))
+
+	assert.NotNil(suite.T(), err)
+}
+
+func TestActiveTokenService(t *testing.T) {
+	suite.Run(t, new(ActiveTokenServiceTestSuite))
+}
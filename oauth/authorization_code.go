@@ -0,0 +1,22 @@
+package oauth
+
+import "time"
+
+type AuthorizationCode struct {
+	Code          string
+	Sid           string
+	ClientId      string
+	Sub           string
+	RedirectURI   string
+	CodeChallenge string
+	Scope         []string
+	Amr           []string
+	Nonce         string
+	GrantId       string
+	AuthTime      int64
+	ExpiresAt     time.Time
+}
+
+func (code *AuthorizationCode) Expired(now time.Time) bool {
+	return !now.Before(code.ExpiresAt)
+}
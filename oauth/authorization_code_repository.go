@@ -0,0 +1,13 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrCodeNotFound = errors.New("no authorization code found")
+
+type AuthorizationCodeRepository interface {
+	Create(ctx context.Context, code *AuthorizationCode) error
+	Consume(ctx context.Context, code string) (*AuthorizationCode, error)
+}
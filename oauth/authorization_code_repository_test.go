@@ -0,0 +1,49 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthorizationCodeRepoTestSuite struct {
+	suite.Suite
+	code *AuthorizationCode
+	repo AuthorizationCodeRepository
+}
+
+func (suite *AuthorizationCodeRepoTestSuite) SetupTest() {
+	suite.code = &AuthorizationCode{
+		Code:      "abc",
+		ClientId:  "app",
+		Sub:       "user",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	suite.repo = new(MemoryAuthorizationCodeRepository)
+}
+
+func (suite *AuthorizationCodeRepoTestSuite) TestConsume_ReturnCodeOnlyOnce() {
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), suite.code))
+
+	consumed, err := suite.repo.Consume(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.code, consumed)
+
+	consumed, err = suite.repo.Consume(context.Background(), "abc")
+	assert.ErrorIs(suite.T(), err, ErrCodeNotFound)
+	assert.Nil(suite.T(), consumed)
+}
+
+func (suite *AuthorizationCodeRepoTestSuite) TestConsume_ErrorWithUnknownCode() {
+	_, err := suite.repo.Consume(context.Background(), "unknown")
+
+	assert.ErrorIs(suite.T(), err, ErrCodeNotFound)
+}
+
+func TestMemoryAuthorizationCodeRepository(t *testing.T) {
+	suite.Run(t, new(AuthorizationCodeRepoTestSuite))
+}
@@ -0,0 +1,256 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+)
+
+const (
+	CodeResponseType           = "code"
+	ConsentPrompt              = "consent"
+	NonePrompt                 = "none"
+	AuthorizationCodeGrantType = "authorization_code"
+	ClientCredentialsGrantType = "client_credentials"
+	authorizationCodeLifetime  = time.Minute
+)
+
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientId            string
+	RedirectURI         string
+	Scope               []string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	Prompt              []string
+}
+
+func (request *AuthorizationRequest) HasPrompt(prompt string) bool {
+	return contains(request.Prompt, prompt)
+}
+
+type TokenRequest struct {
+	GrantType          string
+	Code               string
+	RedirectURI        string
+	ClientId           string
+	CodeVerifier       string
+	RefreshToken       string
+	Scope              []string
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+}
+
+type AuthorizationService struct {
+	clientRepo  ClientRepository
+	codeRepo    AuthorizationCodeRepository
+	consentRepo ConsentRepository
+}
+
+func (service *AuthorizationService) Init(clientRepo ClientRepository, codeRepo AuthorizationCodeRepository, consentRepo ConsentRepository) {
+	service.clientRepo = clientRepo
+	service.codeRepo = codeRepo
+	service.consentRepo = consentRepo
+}
+
+func (service *AuthorizationService) Client(ctx context.Context, clientId string, redirectURI string) (*Client, error) {
+	client, err := service.clientRepo.FindByIdentifier(ctx, clientId)
+	if errors.Is(err, ErrClientNotFound) {
+		return nil, newError(ErrInvalidClient, "unknown client")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.HasRedirectURI(redirectURI) {
+		return nil, newError(ErrInvalidRequest, "redirect_uri is not registered")
+	}
+	return client, nil
+}
+
+func (service *AuthorizationService) Authorize(ctx context.Context, request *AuthorizationRequest, session *auth.RefreshTokenPayload) (string, error) {
+	client, err := service.validate(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	consent, err := service.findConsent(ctx, session.Sub, client.Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	if request.HasPrompt(ConsentPrompt) || !consent.Covers(request.Scope) {
+		return "", newError(ErrConsentRequired, "user must approve the requested scope")
+	}
+	return service.issueCode(ctx, client, request, session, consent.GrantId)
+}
+
+func (service *AuthorizationService) Consent(ctx context.Context, request *AuthorizationRequest, session *auth.RefreshTokenPayload) (string, error) {
+	client, err := service.validate(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	consent, err := service.findConsent(ctx, session.Sub, client.Identifier)
+	if err != nil {
+		return "", err
+	}
+
+	if !consent.Active() {
+		grantId, err := randomString()
+		if err != nil {
+			return "", err
+		}
+		consent.GrantId = grantId
+	}
+
+	consent.Grant(request.Scope, time.Now())
+	if err := service.consentRepo.Save(ctx, consent); err != nil {
+		return "", err
+	}
+	return service.issueCode(ctx, client, request, session, consent.GrantId)
+}
+
+func (service *AuthorizationService) PendingConsent(ctx context.Context, request *AuthorizationRequest, session *auth.RefreshTokenPayload) (*Client, *Consent, error) {
+	client, err := service.validate(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	consent, err := service.findConsent(ctx, session.Sub, client.Identifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, consent, nil
+}
+
+func (service *AuthorizationService) findConsent(ctx context.Context, sub string, clientId string) (*Consent, error) {
+	consent, err := service.consentRepo.Find(ctx, sub, clientId)
+	if errors.Is(err, ErrConsentNotFound) {
+		return &Consent{Sub: sub, ClientId: clientId, Scope: []string{}}, nil
+	}
+	return consent, err
+}
+
+func (service *AuthorizationService) validate(ctx context.Context, request *AuthorizationRequest) (*Client, error) {
+	client, err := service.Client(ctx, request.ClientId, request.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsGrant(AuthorizationCodeGrantType) {
+		return nil, newError(ErrUnauthorizedClient, "client is not allowed to use authorization_code")
+	}
+
+	if request.ResponseType != CodeResponseType {
+		return nil, newError(ErrUnsupportedResponseType, "response_type must be code")
+	}
+
+	if request.CodeChallengeMethod != S256 {
+		return nil, newError(ErrInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	if !challengePattern.MatchString(request.CodeChallenge) {
+		return nil, newError(ErrInvalidRequest, "code_challenge is invalid")
+	}
+
+	if ContainsPrivileged(request.Scope) || !client.AllowsScope(request.Scope) {
+		return nil, newError(ErrInvalidScope, "scope is not allowed for client")
+	}
+	return client, nil
+}
+
+func (service *AuthorizationService) issueCode(ctx context.Context, client *Client, request *AuthorizationRequest, session *auth.RefreshTokenPayload, grantId string) (string, error) {
+	code, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	sid, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	err = service.codeRepo.Create(ctx, &AuthorizationCode{
+		Code:          code,
+		Sid:           sid,
+		ClientId:      client.Identifier,
+		Sub:           session.Sub,
+		RedirectURI:   request.RedirectURI,
+		CodeChallenge: request.CodeChallenge,
+		Scope:         request.Scope,
+		Amr:           session.Amr,
+		Nonce:         request.Nonce,
+		GrantId:       grantId,
+		AuthTime:      session.AuthTime,
+		ExpiresAt:     time.Now().Add(authorizationCodeLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func (service *AuthorizationService) Exchange(ctx context.Context, request *TokenRequest) (*AuthorizationCode, error) {
+	if request.GrantType != AuthorizationCodeGrantType {
+		return nil, newError(ErrUnsupportedGrantType, "grant_type must be authorization_code")
+	}
+
+	if request.Code == "" || request.CodeVerifier == "" || request.ClientId == "" {
+		return nil, newError(ErrInvalidRequest, "code, code_verifier and client_id are required")
+	}
+
+	code, err := service.codeRepo.Consume(ctx, request.Code)
+	if errors.Is(err, ErrCodeNotFound) {
+		return nil, newError(ErrInvalidGrant, "authorization code is invalid or already used")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if code.Expired(time.Now()) {
+		return nil, newError(ErrInvalidGrant, "authorization code is expired")
+	}
+
+	if code.ClientId != request.ClientId || code.RedirectURI != request.RedirectURI {
+		return nil, newError(ErrInvalidGrant, "authorization code was issued to another client or redirect_uri")
+	}
+
+	if !VerifyCodeChallenge(request.CodeVerifier, code.CodeChallenge) {
+		return nil, newError(ErrInvalidGrant, "code_verifier does not match code_challenge")
+	}
+	return code, nil
+}
+
+func (service *AuthorizationService) ClientCredentials(client *Client, request *TokenRequest) ([]string, error) {
+	if request.GrantType != ClientCredentialsGrantType {
+		return nil, newError(ErrUnsupportedGrantType, "grant_type must be client_credentials")
+	}
+
+	if client.IsPublic() || !client.AllowsGrant(ClientCredentialsGrantType) {
+		return nil, newError(ErrUnauthorizedClient, "client is not allowed to use client_credentials")
+	}
+
+	if len(request.Scope) == 0 {
+		var scope []string
+		for _, granted := range client.Scopes {
+			if !isPrivileged(granted) {
+				scope = append(scope, granted)
+			}
+		}
+		return scope, nil
+	}
+
+	if !client.AllowsScope(request.Scope) {
+		return nil, newError(ErrInvalidScope, "scope is not allowed for client")
+	}
+	return request.Scope, nil
+}
@@ -0,0 +1,259 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	redirectURI   = "https://app.example.com/callback"
+	codeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	codeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+type AuthorizationServiceTestSuite struct {
+	suite.Suite
+	clientRepo  ClientRepository
+	codeRepo    AuthorizationCodeRepository
+	consentRepo ConsentRepository
+	service     *AuthorizationService
+}
+
+func (suite *AuthorizationServiceTestSuite) SetupTest() {
+	suite.clientRepo = new(MemoryClientRepository)
+	suite.clientRepo.Create(context.Background(), &Client{
+		Identifier:              "app",
+		RedirectURIs:            []string{redirectURI},
+		GrantTypes:              []string{AuthorizationCodeGrantType},
+		TokenEndpointAuthMethod: NoneAuthMethod,
+		Scopes:                  []string{"openid", "profile", auth.AdminScope},
+	})
+	suite.codeRepo = new(MemoryAuthorizationCodeRepository)
+	suite.consentRepo = new(MemoryConsentRepository)
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantedAt: time.Now()})
+
+	suite.service = new(AuthorizationService)
+	suite.service.Init(suite.clientRepo, suite.codeRepo, suite.consentRepo)
+}
+
+var session = &auth.RefreshTokenPayload{Sid: "123", Sub: "user"}
+
+func authorizationRequest() *AuthorizationRequest {
+	return &AuthorizationRequest{
+		ResponseType:        CodeResponseType,
+		ClientId:            "app",
+		RedirectURI:         redirectURI,
+		Scope:               []string{"profile"},
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: S256,
+		Nonce:               "n-0S6_WzA2Mj",
+	}
+}
+
+func tokenRequest(code string) *TokenRequest {
+	return &TokenRequest{
+		GrantType:    AuthorizationCodeGrantType,
+		Code:         code,
+		RedirectURI:  redirectURI,
+		ClientId:     "app",
+		CodeVerifier: codeVerifier,
+	}
+}
+
+func (suite *AuthorizationServiceTestSuite) TestVerifyCodeChallenge_AcceptRfcExample() {
+	assert.True(suite.T(), VerifyCodeChallenge(codeVerifier, codeChallenge))
+	assert.False(suite.T(), VerifyCodeChallenge(codeVerifier+"x", codeChallenge))
+	assert.False(suite.T(), VerifyCodeChallenge("short", codeChallenge))
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_ExchangeCodeOnce() {
+	code, err := suite.service.Authorize(context.Background(), authorizationRequest(), &auth.RefreshTokenPayload{Sub: "user", Amr: []string{"pwd"}, AuthTime: 1700000000, Iat: 1700000600})
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), code)
+
+	exchanged, err := suite.service.Exchange(context.Background(), tokenRequest(code))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", exchanged.Sub)
+	assert.Equal(suite.T(), "app", exchanged.ClientId)
+	assert.Equal(suite.T(), []string{"profile"}, exchanged.Scope)
+	assert.Equal(suite.T(), []string{"pwd"}, exchanged.Amr)
+	assert.Equal(suite.T(), "n-0S6_WzA2Mj", exchanged.Nonce)
+	assert.Equal(suite.T(), int64(1700000000), exchanged.AuthTime)
+
+	_, err = suite.service.Exchange(context.Background(), tokenRequest(code))
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_ErrorWithUnknownClient() {
+	request := authorizationRequest()
+	request.ClientId = "unknown"
+
+	_, err := suite.service.Authorize(context.Background(), request, session)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidClient)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_ErrorWhenRedirectUriDoesNotMatchExactly() {
+	for _, uri := range []string{redirectURI + "/", redirectURI + "?next=1", "https://APP.example.com/callback", ""} {
+		request := authorizationRequest()
+		request.RedirectURI = uri
+
+		_, err := suite.service.Authorize(context.Background(), request, session)
+
+		assert.ErrorIs(suite.T(), err, ErrInvalidRequest, uri)
+	}
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_RequirePkceWithS256() {
+	request := authorizationRequest()
+	request.CodeChallengeMethod = "plain"
+	_, err := suite.service.Authorize(context.Background(), request, session)
+	assert.ErrorIs(suite.T(), err, ErrInvalidRequest)
+
+	request = authorizationRequest()
+	request.CodeChallenge = ""
+	_, err = suite.service.Authorize(context.Background(), request, session)
+	assert.ErrorIs(suite.T(), err, ErrInvalidRequest)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_ErrorWithUnsupportedResponseType() {
+	request := authorizationRequest()
+	request.ResponseType = "token"
+
+	_, err := suite.service.Authorize(context.Background(), request, session)
+
+	assert.ErrorIs(suite.T(), err, ErrUnsupportedResponseType)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_ErrorWhenGrantNotAllowedForClient() {
+	suite.clientRepo.Create(context.Background(), &Client{
+		Identifier:   "service",
+		RedirectURIs: []string{redirectURI},
+	})
+	request := authorizationRequest()
+	request.ClientId = "service"
+
+	_, err := suite.service.Authorize(context.Background(), request, session)
+
+	assert.ErrorIs(suite.T(), err, ErrUnauthorizedClient)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_ErrorWithScopeNotAllowed() {
+	for _, scope := range [][]string{{"email"}, {auth.AdminScope}} {
+		request := authorizationRequest()
+		request.Scope = scope
+
+		_, err := suite.service.Authorize(context.Background(), request, session)
+
+		assert.ErrorIs(suite.T(), err, ErrInvalidScope)
+	}
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_RequireConsentForNewUser() {
+	_, err := suite.service.Authorize(context.Background(), authorizationRequest(), &auth.RefreshTokenPayload{Sub: "newUser"})
+
+	assert.ErrorIs(suite.T(), err, ErrConsentRequired)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_RequireConsentWhenScopeExpands() {
+	request := authorizationRequest()
+	request.Scope = []string{"openid", "profile"}
+
+	_, err := suite.service.Authorize(context.Background(), request, session)
+
+	assert.ErrorIs(suite.T(), err, ErrConsentRequired)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestAuthorize_RequireConsentWhenPrompted() {
+	request := authorizationRequest()
+	request.Prompt = []string{ConsentPrompt}
+
+	_, err := suite.service.Authorize(context.Background(), request, session)
+
+	assert.ErrorIs(suite.T(), err, ErrConsentRequired)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestConsent_PersistGrantAndIssueCode() {
+	request := authorizationRequest()
+	request.Scope = []string{"openid"}
+
+	code, err := suite.service.Consent(context.Background(), request, session)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), code)
+
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "app")
+	assert.Equal(suite.T(), []string{"profile", "openid"}, consent.Scope)
+
+	request.Scope = []string{"openid", "profile"}
+	_, err = suite.service.Authorize(context.Background(), request, session)
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestConsent_ValidateRequest() {
+	request := authorizationRequest()
+	request.Scope = []string{auth.AdminScope}
+
+	_, err := suite.service.Consent(context.Background(), request, session)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidScope)
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "app")
+	assert.Equal(suite.T(), []string{"profile"}, consent.Scope)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestExchange_ErrorWithWrongVerifier() {
+	code, _ := suite.service.Authorize(context.Background(), authorizationRequest(), session)
+	request := tokenRequest(code)
+	request.CodeVerifier = "Ed7tTlHe4lhf7VeoNDL7wqbT9pRPrqHbn6jTYz1X4o8aNOT"
+
+	_, err := suite.service.Exchange(context.Background(), request)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestExchange_ErrorWithOtherRedirectUriOrClient() {
+	code, _ := suite.service.Authorize(context.Background(), authorizationRequest(), session)
+	request := tokenRequest(code)
+	request.RedirectURI = "https://evil.example.com/callback"
+	_, err := suite.service.Exchange(context.Background(), request)
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+
+	code, _ = suite.service.Authorize(context.Background(), authorizationRequest(), session)
+	request = tokenRequest(code)
+	request.ClientId = "other"
+	_, err = suite.service.Exchange(context.Background(), request)
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestExchange_ErrorWhenCodeExpired() {
+	suite.codeRepo.Create(context.Background(), &AuthorizationCode{
+		Code:          "expired",
+		ClientId:      "app",
+		Sub:           "user",
+		RedirectURI:   redirectURI,
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(-time.Second),
+	})
+
+	_, err := suite.service.Exchange(context.Background(), tokenRequest("expired"))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *AuthorizationServiceTestSuite) TestExchange_ErrorWithUnsupportedGrantType() {
+	request := tokenRequest("code")
+	request.GrantType = "password"
+
+	_, err := suite.service.Exchange(context.Background(), request)
+
+	assert.ErrorIs(suite.T(), err, ErrUnsupportedGrantType)
+}
+
+func TestAuthorizationService(t *testing.T) {
+	suite.Run(t, new(AuthorizationServiceTestSuite))
+}
@@ -0,0 +1,88 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+)
+
+const (
+	NoneAuthMethod              = "none"
+	ClientSecretBasicAuthMethod = "client_secret_basic"
+	ClientSecretPostAuthMethod  = "client_secret_post"
+	PrivateKeyJwtAuthMethod     = "private_key_jwt"
+)
+
+var privilegedScopes = []string{auth.AdminScope, auth.ImpersonateScope}
+
+type Client struct {
+	Identifier              string
+	SecretHash              string
+	Name                    string
+	RedirectURIs            []string
+	GrantTypes              []string
+	Scopes                  []string
+	TokenEndpointAuthMethod string
+	Jwks                    []jwt.Jwk
+	AccessTokenLifetime     time.Duration
+	RefreshTokenLifetime    time.Duration
+	IdTokenSigningAlg       string
+	Trusted                 bool
+	CreatedAt               time.Time
+}
+
+func (client *Client) IsPublic() bool {
+	return client.TokenEndpointAuthMethod == NoneAuthMethod
+}
+
+func (client *Client) UsesSecret() bool {
+	return client.TokenEndpointAuthMethod == ClientSecretBasicAuthMethod || client.TokenEndpointAuthMethod == ClientSecretPostAuthMethod
+}
+
+func (client *Client) VerifySecret(secret string) bool {
+	if !client.UsesSecret() || client.SecretHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.SecretHash)) == 1
+}
+
+func (client *Client) HasRedirectURI(redirectURI string) bool {
+	return contains(client.RedirectURIs, redirectURI)
+}
+
+func (client *Client) AllowsGrant(grantType string) bool {
+	return contains(client.GrantTypes, grantType)
+}
+
+func (client *Client) AllowsScope(scope []string) bool {
+	for _, requested := range scope {
+		if !contains(client.Scopes, requested) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPrivileged(scope string) bool {
+	return contains(privilegedScopes, scope)
+}
+
+func ContainsPrivileged(scope []string) bool {
+	for _, requested := range scope {
+		if isPrivileged(requested) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Untanky/go-id/jwt"
+)
+
+const JwtBearerAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+type ClientAuthentication struct {
+	Method        string
+	ClientId      string
+	ClientSecret  string
+	AssertionType string
+	Assertion     string
+}
+
+type ClientAuthenticator struct {
+	clientRepo ClientRepository
+	audiences  []string
+
+	mu         sync.Mutex
+	assertions map[string]time.Time
+}
+
+func (authenticator *ClientAuthenticator) Init(clientRepo ClientRepository, audiences []string) {
+	authenticator.clientRepo = clientRepo
+	authenticator.audiences = audiences
+	authenticator.assertions = make(map[string]time.Time)
+}
+
+func (authenticator *ClientAuthenticator) Authenticate(ctx context.Context, credentials *ClientAuthentication) (*Client, error) {
+	clientId := credentials.ClientId
+	if clientId == "" && credentials.Method == PrivateKeyJwtAuthMethod {
+		token := jwt.Jwt(credentials.Assertion)
+		if payload, err := token.Payload(); err == nil {
+			clientId, _ = payload["sub"].(string)
+		}
+	}
+
+	if clientId == "" {
+		return nil, newError(ErrInvalidClient, "client authentication is required")
+	}
+
+	client, err := authenticator.clientRepo.FindByIdentifier(ctx, clientId)
+	if errors.Is(err, ErrClientNotFound) {
+		return nil, newError(ErrInvalidClient, "unknown client")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if client.TokenEndpointAuthMethod != credentials.Method {
+		return nil, newError(ErrInvalidClient, "client must authenticate with "+client.TokenEndpointAuthMethod)
+	}
+
+	switch credentials.Method {
+	case NoneAuthMethod:
+		return client, nil
+	case ClientSecretBasicAuthMethod, ClientSecretPostAuthMethod:
+		if !client.VerifySecret(credentials.ClientSecret) {
+			return nil, newError(ErrInvalidClient, "client authentication failed")
+		}
+		return client, nil
+	case PrivateKeyJwtAuthMethod:
+		if err := authenticator.verifyAssertion(client, credentials); err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, newError(ErrInvalidClient, "unsupported client authentication method")
+	}
+}
+
+func (authenticator *ClientAuthenticator) verifyAssertion(client *Client, credentials *ClientAuthentication) error {
+	if credentials.AssertionType != JwtBearerAssertionType {
+		return newError(ErrInvalidClient, "client_assertion_type must be "+JwtBearerAssertionType)
+	}
+
+	var claims map[string]interface{}
+	for _, key := range client.Jwks {
+		verified, err := key.Verify(jwt.Jwt(credentials.Assertion))
+		if err == nil {
+			claims = verified
+			break
+		}
+	}
+
+	if claims == nil {
+		return newError(ErrInvalidClient, "client_assertion signature is invalid")
+	}
+
+	if claims["iss"] != client.Identifier || claims["sub"] != client.Identifier {
+		return newError(ErrInvalidClient, "client_assertion must be issued by the client")
+	}
+
+	if !authenticator.hasAudience(claims["aud"]) {
+		return newError(ErrInvalidClient, "client_assertion audience is invalid")
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return newError(ErrInvalidClient, "client_assertion must expire")
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return newError(ErrInvalidClient, "client_assertion must have a jti")
+	}
+
+	if !authenticator.consumeAssertion(client.Identifier+" "+jti, time.Unix(int64(exp), 0)) {
+		return newError(ErrInvalidClient, "client_assertion was already used")
+	}
+	return nil
+}
+
+func (authenticator *ClientAuthenticator) hasAudience(claim interface{}) bool {
+	switch aud := claim.(type) {
+	case string:
+		return contains(authenticator.audiences, aud)
+	case []interface{}:
+		for _, value := range aud {
+			if audience, ok := value.(string); ok && contains(authenticator.audiences, audience) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (authenticator *ClientAuthenticator) consumeAssertion(key string, expiresAt time.Time) bool {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+
+	now := time.Now()
+	for stored, storedExpiresAt := range authenticator.assertions {
+		if now.After(storedExpiresAt) {
+			delete(authenticator.assertions, stored)
+		}
+	}
+
+	if _, ok := authenticator.assertions[key]; ok {
+		return false
+	}
+
+	authenticator.assertions[key] = expiresAt
+	return true
+}
@@ -0,0 +1,110 @@
+package oauth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	tokenEndpoint = "https://id.example.com/token"
+	secretHash    = "K7gNU3sdo-OL0wNhqoVWhr3g6s1xYv72ol_pe_Unols"
+)
+
+type ClientAuthenticatorTestSuite struct {
+	suite.Suite
+	privateKey    string
+	clientRepo    ClientRepository
+	authenticator *ClientAuthenticator
+}
+
+func (suite *ClientAuthenticatorTestSuite) SetupTest() {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	private, _ := x509.MarshalPKCS8PrivateKey(key)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	suite.privateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}))
+	jwk, _ := jwt.PublicJwk(jwt.ES256, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})))
+
+	suite.clientRepo = new(MemoryClientRepository)
+	suite.clientRepo.Create(context.Background(), &Client{Identifier: "public", TokenEndpointAuthMethod: NoneAuthMethod})
+	suite.clientRepo.Create(context.Background(), &Client{Identifier: "basic", SecretHash: secretHash, TokenEndpointAuthMethod: ClientSecretBasicAuthMethod})
+	suite.clientRepo.Create(context.Background(), &Client{Identifier: "post", SecretHash: secretHash, TokenEndpointAuthMethod: ClientSecretPostAuthMethod})
+	suite.clientRepo.Create(context.Background(), &Client{Identifier: "jwt", Jwks: []jwt.Jwk{*jwk}, TokenEndpointAuthMethod: PrivateKeyJwtAuthMethod})
+
+	suite.authenticator = new(ClientAuthenticator)
+	suite.authenticator.Init(suite.clientRepo, []string{tokenEndpoint})
+}
+
+func (suite *ClientAuthenticatorTestSuite) assertion(jti string) string {
+	token, _ := jwt.CreateJwt(jwt.ES256, map[string]interface{}{
+		"iss": "jwt",
+		"sub": "jwt",
+		"aud": []string{tokenEndpoint},
+		"jti": jti,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}, suite.privateKey)
+	return string(token)
+}
+
+func (suite *ClientAuthenticatorTestSuite) TestAuthenticate_AcceptValidCredentials() {
+	for _, credentials := range []*ClientAuthentication{
+		{Method: NoneAuthMethod, ClientId: "public"},
+		{Method: ClientSecretBasicAuthMethod, ClientId: "basic", ClientSecret: "secret"},
+		{Method: ClientSecretPostAuthMethod, ClientId: "post", ClientSecret: "secret"},
+		{Method: PrivateKeyJwtAuthMethod, ClientId: "jwt", AssertionType: JwtBearerAssertionType, Assertion: suite.assertion("1")},
+		{Method: PrivateKeyJwtAuthMethod, AssertionType: JwtBearerAssertionType, Assertion: suite.assertion("2")},
+	} {
+		client, err := suite.authenticator.Authenticate(context.Background(), credentials)
+
+		assert.Nil(suite.T(), err, credentials.Method)
+		if client != nil {
+			assert.Equal(suite.T(), credentials.Method, client.TokenEndpointAuthMethod)
+		}
+	}
+}
+
+func (suite *ClientAuthenticatorTestSuite) TestAuthenticate_ErrorWithInvalidCredentials() {
+	for _, credentials := range []*ClientAuthentication{
+		{Method: NoneAuthMethod},
+		{Method: NoneAuthMethod, ClientId: "unknown"},
+		{Method: NoneAuthMethod, ClientId: "basic"},
+		{Method: ClientSecretBasicAuthMethod, ClientId: "basic", ClientSecret: "wrong"},
+		{Method: ClientSecretPostAuthMethod, ClientId: "basic", ClientSecret: "secret"},
+		{Method: ClientSecretBasicAuthMethod, ClientId: "public", ClientSecret: ""},
+		{Method: PrivateKeyJwtAuthMethod, ClientId: "jwt", AssertionType: "urn:other", Assertion: suite.assertion("3")},
+		{Method: PrivateKeyJwtAuthMethod, ClientId: "jwt", AssertionType: JwtBearerAssertionType, Assertion: "invalid"},
+	} {
+		_, err := suite.authenticator.Authenticate(context.Background(), credentials)
+
+		assert.ErrorIs(suite.T(), err, ErrInvalidClient, credentials)
+	}
+}
+
+func (suite *ClientAuthenticatorTestSuite) TestAuthenticate_RejectReplayedAssertion() {
+	credentials := &ClientAuthentication{
+		Method:        PrivateKeyJwtAuthMethod,
+		ClientId:      "jwt",
+		AssertionType: JwtBearerAssertionType,
+		Assertion:     suite.assertion("replayed"),
+	}
+
+	_, err := suite.authenticator.Authenticate(context.Background(), credentials)
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.authenticator.Authenticate(context.Background(), credentials)
+	assert.ErrorIs(suite.T(), err, ErrInvalidClient)
+}
+
+func TestClientAuthenticator(t *testing.T) {
+	suite.Run(t, new(ClientAuthenticatorTestSuite))
+}
@@ -0,0 +1,19 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrClientNotFound = errors.New("no client found")
+	ErrClientExists   = errors.New("client already exists")
+)
+
+type ClientRepository interface {
+	FindByIdentifier(ctx context.Context, identifier string) (*Client, error)
+	List(ctx context.Context) ([]*Client, error)
+	Create(ctx context.Context, client *Client) error
+	Update(ctx context.Context, client *Client) error
+	Remove(ctx context.Context, identifier string) error
+}
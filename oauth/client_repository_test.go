@@ -0,0 +1,148 @@
+package oauth_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/oauth"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+)
+
+type ClientRepoTestSuite struct {
+	suite.Suite
+	newRepo func() ClientRepository
+	client  *Client
+	repo    ClientRepository
+}
+
+func (suite *ClientRepoTestSuite) SetupTest() {
+	suite.client = &Client{
+		Identifier:              "app",
+		SecretHash:              "hash",
+		Name:                    "App",
+		RedirectURIs:            []string{"https://app.example.com/callback"},
+		GrantTypes:              []string{AuthorizationCodeGrantType},
+		Scopes:                  []string{"openid", "profile"},
+		TokenEndpointAuthMethod: ClientSecretBasicAuthMethod,
+		Jwks:                    []jwt.Jwk{{Kty: "EC", Crv: "P-256", X: "x", Y: "y"}},
+		AccessTokenLifetime:     5 * time.Minute,
+		RefreshTokenLifetime:    24 * time.Hour,
+		IdTokenSigningAlg:       "RS256",
+		Trusted:                 true,
+	}
+	suite.repo = suite.newRepo()
+}
+
+func (suite *ClientRepoTestSuite) TestCreate_FindByIdentifier() {
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), suite.client))
+	assert.False(suite.T(), suite.client.CreatedAt.IsZero())
+
+	found, err := suite.repo.FindByIdentifier(context.Background(), "app")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.client, found)
+}
+
+func (suite *ClientRepoTestSuite) TestCreate_ErrorWhenIdentifierAlreadyExists() {
+	suite.repo.Create(context.Background(), suite.client)
+
+	err := suite.repo.Create(context.Background(), suite.client)
+
+	assert.ErrorIs(suite.T(), err, ErrClientExists)
+}
+
+func (suite *ClientRepoTestSuite) TestFindByIdentifier_ErrorWhenNotFound() {
+	_, err := suite.repo.FindByIdentifier(context.Background(), "unknown")
+
+	assert.ErrorIs(suite.T(), err, ErrClientNotFound)
+}
+
+func (suite *ClientRepoTestSuite) TestList_SortedByIdentifier() {
+	other := *suite.client
+	other.Identifier = "another"
+	suite.repo.Create(context.Background(), suite.client)
+	suite.repo.Create(context.Background(), &other)
+
+	clients, err := suite.repo.List(context.Background())
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), clients, 2)
+	assert.Equal(suite.T(), "another", clients[0].Identifier)
+	assert.Equal(suite.T(), "app", clients[1].Identifier)
+}
+
+func (suite *ClientRepoTestSuite) TestUpdate_PersistChanges() {
+	suite.repo.Create(context.Background(), suite.client)
+	suite.client.Name = "Renamed"
+	suite.client.Scopes = []string{"openid"}
+	suite.client.AccessTokenLifetime = time.Minute
+
+	assert.Nil(suite.T(), suite.repo.Update(context.Background(), suite.client))
+
+	found, _ := suite.repo.FindByIdentifier(context.Background(), "app")
+	assert.Equal(suite.T(), suite.client, found)
+}
+
+func (suite *ClientRepoTestSuite) TestUpdate_ErrorWhenNotFound() {
+	err := suite.repo.Update(context.Background(), suite.client)
+
+	assert.ErrorIs(suite.T(), err, ErrClientNotFound)
+}
+
+func (suite *ClientRepoTestSuite) TestRemove_DeleteClient() {
+	suite.repo.Create(context.Background(), suite.client)
+
+	assert.Nil(suite.T(), suite.repo.Remove(context.Background(), "app"))
+
+	_, err := suite.repo.FindByIdentifier(context.Background(), "app")
+	assert.ErrorIs(suite.T(), err, ErrClientNotFound)
+	assert.ErrorIs(suite.T(), suite.repo.Remove(context.Background(), "app"), ErrClientNotFound)
+}
+
+func openMigratedDb(t *testing.T, driver string, dsn string) *sql.DB {
+	db, err := sql.Open(driver, dsn)
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	for _, table := range []string{"oauth_clients", "oauth_consents", "schema_migrations"} {
+		_, err = db.Exec(`DROP TABLE IF EXISTS ` + table)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, Migrate(db))
+	return db
+}
+
+func newSqlClientRepository(t *testing.T, driver string, dsn string) func() ClientRepository {
+	return func() ClientRepository {
+		repo := new(SqlClientRepository)
+		repo.Init(openMigratedDb(t, driver, dsn))
+		return repo
+	}
+}
+
+func TestMemoryClientRepository(t *testing.T) {
+	suite.Run(t, &ClientRepoTestSuite{newRepo: func() ClientRepository {
+		return new(MemoryClientRepository)
+	}})
+}
+
+func TestSqlClientRepository_Sqlite(t *testing.T) {
+	suite.Run(t, &ClientRepoTestSuite{newRepo: newSqlClientRepository(t, "sqlite", "file::memory:")})
+}
+
+func TestSqlClientRepository_Postgres(t *testing.T) {
+	dsn := os.Getenv("GO_ID_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GO_ID_POSTGRES_DSN not set")
+	}
+
+	suite.Run(t, &ClientRepoTestSuite{newRepo: newSqlClientRepository(t, "postgres", dsn)})
+}
@@ -0,0 +1,286 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Untanky/go-id/jwt"
+)
+
+var (
+	grantTypes     = []string{AuthorizationCodeGrantType, RefreshTokenGrantType, ClientCredentialsGrantType, DeviceCodeGrantType, TokenExchangeGrantType}
+	openGrantTypes = []string{AuthorizationCodeGrantType, RefreshTokenGrantType, DeviceCodeGrantType}
+	authMethods    = []string{NoneAuthMethod, ClientSecretBasicAuthMethod, ClientSecretPostAuthMethod, PrivateKeyJwtAuthMethod}
+)
+
+type JwkSet struct {
+	Keys []jwt.Jwk `json:"keys"`
+}
+
+type ClientMetadata struct {
+	ClientName               string   `json:"client_name,omitempty"`
+	RedirectURIs             []string `json:"redirect_uris"`
+	GrantTypes               []string `json:"grant_types"`
+	ResponseTypes            []string `json:"response_types"`
+	TokenEndpointAuthMethod  string   `json:"token_endpoint_auth_method"`
+	Jwks                     *JwkSet  `json:"jwks,omitempty"`
+	Scope                    string   `json:"scope,omitempty"`
+	IdTokenSignedResponseAlg string   `json:"id_token_signed_response_alg,omitempty"`
+	AccessTokenLifetime      int64    `json:"access_token_lifetime,omitempty"`
+	RefreshTokenLifetime     int64    `json:"refresh_token_lifetime,omitempty"`
+}
+
+func NewClientMetadata(client *Client) *ClientMetadata {
+	metadata := &ClientMetadata{
+		ClientName:               client.Name,
+		RedirectURIs:             client.RedirectURIs,
+		GrantTypes:               client.GrantTypes,
+		ResponseTypes:            responseTypesFor(client.GrantTypes),
+		TokenEndpointAuthMethod:  client.TokenEndpointAuthMethod,
+		Scope:                    strings.Join(client.Scopes, " "),
+		IdTokenSignedResponseAlg: client.IdTokenSigningAlg,
+		AccessTokenLifetime:      int64(client.AccessTokenLifetime / time.Second),
+		RefreshTokenLifetime:     int64(client.RefreshTokenLifetime / time.Second),
+	}
+
+	if len(client.Jwks) > 0 {
+		metadata.Jwks = &JwkSet{Keys: client.Jwks}
+	}
+	return metadata
+}
+
+type ClientService struct {
+	clientRepo  ClientRepository
+	signingAlgs []string
+}
+
+func (service *ClientService) Init(clientRepo ClientRepository, signingAlgs []string) {
+	service.clientRepo = clientRepo
+	service.signingAlgs = signingAlgs
+}
+
+func (service *ClientService) Find(ctx context.Context, identifier string) (*Client, error) {
+	return service.clientRepo.FindByIdentifier(ctx, identifier)
+}
+
+func (service *ClientService) List(ctx context.Context) ([]*Client, error) {
+	return service.clientRepo.List(ctx)
+}
+
+func (service *ClientService) Register(ctx context.Context, metadata *ClientMetadata) (*Client, string, error) {
+	return service.register(ctx, metadata, false)
+}
+
+func (service *ClientService) RegisterTrusted(ctx context.Context, metadata *ClientMetadata) (*Client, string, error) {
+	return service.register(ctx, metadata, true)
+}
+
+func (service *ClientService) register(ctx context.Context, metadata *ClientMetadata, trusted bool) (*Client, string, error) {
+	identifier, err := randomString()
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &Client{Identifier: identifier, Trusted: trusted}
+	if err := service.apply(client, metadata, trusted); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := service.assignSecret(client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := service.clientRepo.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+func (service *ClientService) Update(ctx context.Context, identifier string, metadata *ClientMetadata) (*Client, error) {
+	client, err := service.clientRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	wasPublic, usedSecret := client.IsPublic(), client.UsesSecret()
+	if err := service.apply(client, metadata, true); err != nil {
+		return nil, err
+	}
+
+	if wasPublic != client.IsPublic() || usedSecret != client.UsesSecret() {
+		return nil, newError(ErrInvalidClientMetadata, "token_endpoint_auth_method cannot change the type of client credential")
+	}
+
+	if err := service.clientRepo.Update(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (service *ClientService) RotateSecret(ctx context.Context, identifier string) (string, error) {
+	client, err := service.clientRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return "", err
+	}
+
+	if !client.UsesSecret() {
+		return "", newError(ErrInvalidClientMetadata, "client does not authenticate with a secret")
+	}
+
+	secret, err := service.assignSecret(client)
+	if err != nil {
+		return "", err
+	}
+	return secret, service.clientRepo.Update(ctx, client)
+}
+
+func (service *ClientService) Remove(ctx context.Context, identifier string) error {
+	return service.clientRepo.Remove(ctx, identifier)
+}
+
+func (service *ClientService) apply(client *Client, metadata *ClientMetadata, trusted bool) error {
+	authMethod := metadata.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = ClientSecretBasicAuthMethod
+	}
+
+	if !contains(authMethods, authMethod) {
+		return newError(ErrInvalidClientMetadata, "token_endpoint_auth_method is not supported")
+	}
+
+	requestedGrants := metadata.GrantTypes
+	if len(requestedGrants) == 0 {
+		requestedGrants = []string{AuthorizationCodeGrantType}
+	}
+
+	for _, grantType := range requestedGrants {
+		if !contains(grantTypes, grantType) {
+			return newError(ErrInvalidClientMetadata, "grant_type "+grantType+" is not supported")
+		}
+
+		if !trusted && !contains(openGrantTypes, grantType) {
+			return newError(ErrInvalidClientMetadata, "grant_type "+grantType+" requires registration by an administrator")
+		}
+	}
+
+	if contains(requestedGrants, RefreshTokenGrantType) && !contains(requestedGrants, AuthorizationCodeGrantType) && !contains(requestedGrants, DeviceCodeGrantType) {
+		return newError(ErrInvalidClientMetadata, "refresh_token requires authorization_code or device_code")
+	}
+
+	if authMethod == NoneAuthMethod && contains(requestedGrants, ClientCredentialsGrantType) {
+		return newError(ErrInvalidClientMetadata, "public clients cannot use client_credentials")
+	}
+
+	if authMethod == NoneAuthMethod && contains(requestedGrants, TokenExchangeGrantType) {
+		return newError(ErrInvalidClientMetadata, "public clients cannot exchange tokens")
+	}
+
+	var keys []jwt.Jwk
+	if metadata.Jwks != nil {
+		keys = metadata.Jwks.Keys
+	}
+
+	if authMethod == PrivateKeyJwtAuthMethod && len(keys) == 0 {
+		return newError(ErrInvalidClientMetadata, "jwks are required for private_key_jwt")
+	}
+
+	for _, key := range keys {
+		if _, err := key.PublicKey(); err != nil {
+			return newError(ErrInvalidClientMetadata, "jwks contain an invalid key")
+		}
+	}
+
+	responseTypes := metadata.ResponseTypes
+	if len(responseTypes) == 0 {
+		responseTypes = responseTypesFor(requestedGrants)
+	}
+
+	for _, responseType := range responseTypes {
+		if responseType != CodeResponseType || !contains(requestedGrants, AuthorizationCodeGrantType) {
+			return newError(ErrInvalidClientMetadata, "response_types do not match grant_types")
+		}
+	}
+
+	if contains(requestedGrants, AuthorizationCodeGrantType) && len(metadata.RedirectURIs) == 0 {
+		return newError(ErrInvalidRedirectURI, "redirect_uris are required for authorization_code")
+	}
+
+	for _, redirectURI := range metadata.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return newError(ErrInvalidRedirectURI, redirectURI+" is not a valid redirect_uri")
+		}
+	}
+
+	scopes := strings.Fields(metadata.Scope)
+	for _, scope := range scopes {
+		if isPrivileged(scope) && !trusted {
+			return newError(ErrInvalidClientMetadata, "scope "+scope+" cannot be granted to clients")
+		}
+
+		if isPrivileged(scope) && !contains(requestedGrants, ClientCredentialsGrantType) {
+			return newError(ErrInvalidClientMetadata, "scope "+scope+" requires client_credentials")
+		}
+	}
+
+	signingAlg := metadata.IdTokenSignedResponseAlg
+	if signingAlg == "" && len(service.signingAlgs) > 0 {
+		signingAlg = service.signingAlgs[0]
+	}
+
+	if !contains(service.signingAlgs, signingAlg) {
+		return newError(ErrInvalidClientMetadata, "id_token_signed_response_alg is not supported")
+	}
+
+	if metadata.AccessTokenLifetime < 0 || metadata.RefreshTokenLifetime < 0 {
+		return newError(ErrInvalidClientMetadata, "token lifetimes must not be negative")
+	}
+
+	client.Name = metadata.ClientName
+	client.RedirectURIs = metadata.RedirectURIs
+	client.GrantTypes = requestedGrants
+	client.Scopes = scopes
+	client.TokenEndpointAuthMethod = authMethod
+	client.Jwks = keys
+	client.IdTokenSigningAlg = signingAlg
+	client.AccessTokenLifetime = time.Duration(metadata.AccessTokenLifetime) * time.Second
+	client.RefreshTokenLifetime = time.Duration(metadata.RefreshTokenLifetime) * time.Second
+	return nil
+}
+
+func (service *ClientService) assignSecret(client *Client) (string, error) {
+	if !client.UsesSecret() {
+		return "", nil
+	}
+
+	secret, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	client.SecretHash = hashSecret(secret)
+	return secret, nil
+}
+
+func validRedirectURI(redirectURI string) bool {
+	location, err := url.Parse(redirectURI)
+	if err != nil || !location.IsAbs() || location.Fragment != "" || location.Host == "" {
+		return false
+	}
+
+	if location.Scheme == "https" {
+		return true
+	}
+
+	hostname := location.Hostname()
+	return location.Scheme == "http" && (hostname == "localhost" || hostname == "127.0.0.1" || hostname == "::1")
+}
+
+func responseTypesFor(grants []string) []string {
+	if contains(grants, AuthorizationCodeGrantType) {
+		return []string{CodeResponseType}
+	}
+	return []string{}
+}
@@ -0,0 +1,225 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientServiceTestSuite struct {
+	suite.Suite
+	clientRepo ClientRepository
+	service    *ClientService
+}
+
+func (suite *ClientServiceTestSuite) SetupTest() {
+	suite.clientRepo = new(MemoryClientRepository)
+	suite.service = new(ClientService)
+	suite.service.Init(suite.clientRepo, []string{"RS256"})
+}
+
+func clientMetadata() *ClientMetadata {
+	return &ClientMetadata{
+		ClientName:   "App",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scope:        "openid profile",
+	}
+}
+
+func (suite *ClientServiceTestSuite) TestRegister_ConfidentialClientWithDefaults() {
+	client, secret, err := suite.service.Register(context.Background(), clientMetadata())
+
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), client.Identifier)
+	assert.NotEmpty(suite.T(), secret)
+	assert.NotEqual(suite.T(), secret, client.SecretHash)
+	assert.Equal(suite.T(), ClientSecretBasicAuthMethod, client.TokenEndpointAuthMethod)
+	assert.Equal(suite.T(), []string{AuthorizationCodeGrantType}, client.GrantTypes)
+	assert.Equal(suite.T(), []string{"openid", "profile"}, client.Scopes)
+	assert.Equal(suite.T(), "RS256", client.IdTokenSigningAlg)
+	assert.False(suite.T(), client.Trusted)
+
+	stored, _ := suite.clientRepo.FindByIdentifier(context.Background(), client.Identifier)
+	assert.True(suite.T(), stored.VerifySecret(secret))
+	assert.False(suite.T(), stored.VerifySecret("wrong"))
+}
+
+func (suite *ClientServiceTestSuite) TestRegister_PublicClientWithoutSecret() {
+	metadata := clientMetadata()
+	metadata.TokenEndpointAuthMethod = NoneAuthMethod
+	metadata.RedirectURIs = []string{"http://127.0.0.1:8400/callback"}
+
+	client, secret, err := suite.service.Register(context.Background(), metadata)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), secret)
+	assert.Empty(suite.T(), client.SecretHash)
+	assert.True(suite.T(), client.IsPublic())
+	assert.False(suite.T(), client.VerifySecret(""))
+}
+
+func (suite *ClientServiceTestSuite) TestRegister_DeviceClientWithoutRedirectURIs() {
+	metadata := &ClientMetadata{
+		ClientName:              "TV",
+		GrantTypes:              []string{DeviceCodeGrantType},
+		TokenEndpointAuthMethod: NoneAuthMethod,
+		Scope:                   "profile",
+	}
+
+	client, _, err := suite.service.Register(context.Background(), metadata)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), client.AllowsGrant(DeviceCodeGrantType))
+	assert.Empty(suite.T(), client.RedirectURIs)
+}
+
+func (suite *ClientServiceTestSuite) TestRegister_ServiceClientWithPrivateKeyJwt() {
+	jwk := jwt.Jwk{Kty: "EC", Crv: "P-256", X: "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU", Y: "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}
+	metadata := &ClientMetadata{
+		GrantTypes:              []string{ClientCredentialsGrantType},
+		TokenEndpointAuthMethod: PrivateKeyJwtAuthMethod,
+		Jwks:                    &JwkSet{Keys: []jwt.Jwk{jwk}},
+		Scope:                   "reports:read",
+	}
+
+	client, secret, err := suite.service.RegisterTrusted(context.Background(), metadata)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), secret)
+	assert.Empty(suite.T(), client.RedirectURIs)
+	assert.Equal(suite.T(), []jwt.Jwk{jwk}, client.Jwks)
+	assert.Empty(suite.T(), NewClientMetadata(client).ResponseTypes)
+
+	_, err = suite.service.RotateSecret(context.Background(), client.Identifier)
+	assert.ErrorIs(suite.T(), err, ErrInvalidClientMetadata)
+}
+
+func (suite *ClientServiceTestSuite) TestRegister_ErrorWithInvalidRedirectURI() {
+	for _, redirectURIs := range [][]string{
+		nil,
+		{"/callback"},
+		{"http://app.example.com/callback"},
+		{"https://app.example.com/callback#fragment"},
+	} {
+		metadata := clientMetadata()
+		metadata.RedirectURIs = redirectURIs
+
+		_, _, err := suite.service.Register(context.Background(), metadata)
+
+		assert.ErrorIs(suite.T(), err, ErrInvalidRedirectURI, redirectURIs)
+	}
+}
+
+func (suite *ClientServiceTestSuite) TestRegister_ErrorWithInvalidMetadata() {
+	for _, modify := range []func(*ClientMetadata){
+		func(metadata *ClientMetadata) { metadata.TokenEndpointAuthMethod = "tls_client_auth" },
+		func(metadata *ClientMetadata) { metadata.GrantTypes = []string{"implicit"} },
+		func(metadata *ClientMetadata) { metadata.ResponseTypes = []string{"token"} },
+		func(metadata *ClientMetadata) { metadata.Scope = "openid " + auth.AdminScope },
+		func(metadata *ClientMetadata) { metadata.Scope = "openid " + auth.ImpersonateScope },
+		func(metadata *ClientMetadata) { metadata.IdTokenSignedResponseAlg = "HS256" },
+		func(metadata *ClientMetadata) { metadata.AccessTokenLifetime = -1 },
+		func(metadata *ClientMetadata) { metadata.GrantTypes = []string{RefreshTokenGrantType} },
+		func(metadata *ClientMetadata) { metadata.TokenEndpointAuthMethod = PrivateKeyJwtAuthMethod },
+		func(metadata *ClientMetadata) {
+			metadata.TokenEndpointAuthMethod = NoneAuthMethod
+			metadata.GrantTypes = []string{ClientCredentialsGrantType}
+		},
+		func(metadata *ClientMetadata) {
+			metadata.TokenEndpointAuthMethod = NoneAuthMethod
+			metadata.GrantTypes = []string{TokenExchangeGrantType}
+		},
+		func(metadata *ClientMetadata) { metadata.GrantTypes = []string{ClientCredentialsGrantType} },
+		func(metadata *ClientMetadata) {
+			metadata.GrantTypes = []string{AuthorizationCodeGrantType, TokenExchangeGrantType}
+		},
+		func(metadata *ClientMetadata) {
+			metadata.TokenEndpointAuthMethod = PrivateKeyJwtAuthMethod
+			metadata.Jwks = &JwkSet{Keys: []jwt.Jwk{{Kty: "RSA", N: "!", E: "AQAB"}}}
+		},
+	} {
+		metadata := clientMetadata()
+		modify(metadata)
+
+		_, _, err := suite.service.Register(context.Background(), metadata)
+
+		assert.ErrorIs(suite.T(), err, ErrInvalidClientMetadata)
+	}
+}
+
+func (suite *ClientServiceTestSuite) TestRegisterTrusted_AllowPrivilegedScopeForServiceClient() {
+	client, secret, err := suite.service.RegisterTrusted(context.Background(), &ClientMetadata{
+		ClientName: "Operator",
+		GrantTypes: []string{ClientCredentialsGrantType},
+		Scope:      auth.AdminScope,
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), secret)
+	assert.Equal(suite.T(), []string{auth.AdminScope}, client.Scopes)
+	assert.True(suite.T(), client.Trusted)
+}
+
+func (suite *ClientServiceTestSuite) TestRegisterTrusted_ErrorWithPrivilegedScopeForUserClient() {
+	metadata := clientMetadata()
+	metadata.Scope = "openid " + auth.AdminScope
+
+	_, _, err := suite.service.RegisterTrusted(context.Background(), metadata)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidClientMetadata)
+}
+
+func (suite *ClientServiceTestSuite) TestUpdate_ReplaceMetadataAndKeepSecret() {
+	client, secret, _ := suite.service.Register(context.Background(), clientMetadata())
+	metadata := clientMetadata()
+	metadata.ClientName = "Renamed"
+	metadata.AccessTokenLifetime = 300
+
+	updated, err := suite.service.Update(context.Background(), client.Identifier, metadata)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Renamed", updated.Name)
+	assert.Equal(suite.T(), 5*time.Minute, updated.AccessTokenLifetime)
+	assert.True(suite.T(), updated.VerifySecret(secret))
+}
+
+func (suite *ClientServiceTestSuite) TestUpdate_ErrorWhenSwitchingToPublic() {
+	client, _, _ := suite.service.Register(context.Background(), clientMetadata())
+	metadata := clientMetadata()
+	metadata.TokenEndpointAuthMethod = NoneAuthMethod
+
+	_, err := suite.service.Update(context.Background(), client.Identifier, metadata)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidClientMetadata)
+}
+
+func (suite *ClientServiceTestSuite) TestRotateSecret_InvalidatePreviousSecret() {
+	client, secret, _ := suite.service.Register(context.Background(), clientMetadata())
+
+	rotated, err := suite.service.RotateSecret(context.Background(), client.Identifier)
+
+	assert.Nil(suite.T(), err)
+	stored, _ := suite.clientRepo.FindByIdentifier(context.Background(), client.Identifier)
+	assert.False(suite.T(), stored.VerifySecret(secret))
+	assert.True(suite.T(), stored.VerifySecret(rotated))
+}
+
+func (suite *ClientServiceTestSuite) TestRotateSecret_ErrorForPublicClient() {
+	metadata := clientMetadata()
+	metadata.TokenEndpointAuthMethod = NoneAuthMethod
+	client, _, _ := suite.service.Register(context.Background(), metadata)
+
+	_, err := suite.service.RotateSecret(context.Background(), client.Identifier)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidClientMetadata)
+}
+
+func TestClientService(t *testing.T) {
+	suite.Run(t, new(ClientServiceTestSuite))
+}
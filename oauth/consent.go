@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+)
+
+type Consent struct {
+	Sub       string
+	ClientId  string
+	Scope     []string
+	GrantId   string
+	GrantedAt time.Time
+	RevokedAt time.Time
+}
+
+func (consent *Consent) Active() bool {
+	return !consent.GrantedAt.IsZero()
+}
+
+func (consent *Consent) Covers(scope []string) bool {
+	if !consent.Active() {
+		return false
+	}
+
+	for _, requested := range scope {
+		if !contains(consent.Scope, requested) {
+			return false
+		}
+	}
+	return true
+}
+
+func (consent *Consent) Grant(scope []string, now time.Time) {
+	if !consent.Active() {
+		consent.Scope = []string{}
+	}
+
+	for _, requested := range scope {
+		if !contains(consent.Scope, requested) {
+			consent.Scope = append(consent.Scope, requested)
+		}
+	}
+	consent.GrantedAt = now.UTC().Truncate(time.Second)
+}
+
+func (consent *Consent) Revoke(now time.Time) {
+	consent.Scope = []string{}
+	consent.GrantId = ""
+	consent.GrantedAt = time.Time{}
+	consent.RevokedAt = now.UTC().Truncate(time.Second)
+}
+
+func (consent *Consent) RevokesToken(payload *auth.RefreshTokenPayload) bool {
+	if payload.Gid != "" && payload.Gid != consent.GrantId {
+		return true
+	}
+	return !consent.RevokedAt.IsZero() && !time.Unix(payload.Iat, 0).After(consent.RevokedAt)
+}
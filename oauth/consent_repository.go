@@ -0,0 +1,15 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrConsentNotFound = errors.New("no consent found")
+
+type ConsentRepository interface {
+	Find(ctx context.Context, sub string, clientId string) (*Consent, error)
+	FindByGrantId(ctx context.Context, sub string, grantId string) (*Consent, error)
+	ListBySubject(ctx context.Context, sub string) ([]*Consent, error)
+	Save(ctx context.Context, consent *Consent) error
+}
@@ -0,0 +1,113 @@
+package oauth_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConsentRepoTestSuite struct {
+	suite.Suite
+	newRepo func() ConsentRepository
+	consent *Consent
+	repo    ConsentRepository
+}
+
+func (suite *ConsentRepoTestSuite) SetupTest() {
+	suite.consent = &Consent{
+		Sub:       "user",
+		ClientId:  "app",
+		Scope:     []string{"openid", "profile"},
+		GrantId:   "grant",
+		GrantedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	suite.repo = suite.newRepo()
+}
+
+func (suite *ConsentRepoTestSuite) TestSave_Find() {
+	assert.Nil(suite.T(), suite.repo.Save(context.Background(), suite.consent))
+
+	found, err := suite.repo.Find(context.Background(), "user", "app")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.consent, found)
+}
+
+func (suite *ConsentRepoTestSuite) TestSave_ReplaceExistingConsent() {
+	suite.repo.Save(context.Background(), suite.consent)
+	suite.consent.Revoke(time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC))
+
+	assert.Nil(suite.T(), suite.repo.Save(context.Background(), suite.consent))
+
+	found, _ := suite.repo.Find(context.Background(), "user", "app")
+	assert.Equal(suite.T(), suite.consent, found)
+	assert.False(suite.T(), found.Active())
+}
+
+func (suite *ConsentRepoTestSuite) TestFind_ErrorWhenNotFound() {
+	_, err := suite.repo.Find(context.Background(), "user", "unknown")
+
+	assert.ErrorIs(suite.T(), err, ErrConsentNotFound)
+}
+
+func (suite *ConsentRepoTestSuite) TestFindByGrantId_MatchSubjectAndGrant() {
+	suite.repo.Save(context.Background(), suite.consent)
+
+	found, err := suite.repo.FindByGrantId(context.Background(), "user", "grant")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.consent, found)
+
+	_, err = suite.repo.FindByGrantId(context.Background(), "stranger", "grant")
+	assert.ErrorIs(suite.T(), err, ErrConsentNotFound)
+	_, err = suite.repo.FindByGrantId(context.Background(), "user", "")
+	assert.ErrorIs(suite.T(), err, ErrConsentNotFound)
+}
+
+func (suite *ConsentRepoTestSuite) TestListBySubject_SortedByClient() {
+	other := *suite.consent
+	other.ClientId = "another"
+	stranger := *suite.consent
+	stranger.Sub = "stranger"
+	for _, consent := range []*Consent{suite.consent, &other, &stranger} {
+		suite.repo.Save(context.Background(), consent)
+	}
+
+	consents, err := suite.repo.ListBySubject(context.Background(), "user")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), consents, 2)
+	assert.Equal(suite.T(), "another", consents[0].ClientId)
+	assert.Equal(suite.T(), "app", consents[1].ClientId)
+}
+
+func newSqlConsentRepository(t *testing.T, driver string, dsn string) func() ConsentRepository {
+	return func() ConsentRepository {
+		repo := new(SqlConsentRepository)
+		repo.Init(openMigratedDb(t, driver, dsn))
+		return repo
+	}
+}
+
+func TestMemoryConsentRepository(t *testing.T) {
+	suite.Run(t, &ConsentRepoTestSuite{newRepo: func() ConsentRepository {
+		return new(MemoryConsentRepository)
+	}})
+}
+
+func TestSqlConsentRepository_Sqlite(t *testing.T) {
+	suite.Run(t, &ConsentRepoTestSuite{newRepo: newSqlConsentRepository(t, "sqlite", "file::memory:")})
+}
+
+func TestSqlConsentRepository_Postgres(t *testing.T) {
+	dsn := os.Getenv("GO_ID_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GO_ID_POSTGRES_DSN not set")
+	}
+
+	suite.Run(t, &ConsentRepoTestSuite{newRepo: newSqlConsentRepository(t, "postgres", dsn)})
+}
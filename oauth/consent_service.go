@@ -0,0 +1,43 @@
+package oauth
+
+import (
+	"context"
+	"time"
+)
+
+type ConsentService struct {
+	consentRepo ConsentRepository
+}
+
+func (service *ConsentService) Init(consentRepo ConsentRepository) {
+	service.consentRepo = consentRepo
+}
+
+func (service *ConsentService) List(ctx context.Context, sub string) ([]*Consent, error) {
+	consents, err := service.consentRepo.ListBySubject(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	active := []*Consent{}
+	for _, consent := range consents {
+		if consent.Active() {
+			active = append(active, consent)
+		}
+	}
+	return active, nil
+}
+
+func (service *ConsentService) Revoke(ctx context.Context, sub string, clientId string) error {
+	consent, err := service.consentRepo.Find(ctx, sub, clientId)
+	if err != nil {
+		return err
+	}
+
+	if !consent.Active() {
+		return ErrConsentNotFound
+	}
+
+	consent.Revoke(time.Now())
+	return service.consentRepo.Save(ctx, consent)
+}
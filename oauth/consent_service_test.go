@@ -0,0 +1,64 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConsentServiceTestSuite struct {
+	suite.Suite
+	consentRepo ConsentRepository
+	service     *ConsentService
+}
+
+func (suite *ConsentServiceTestSuite) SetupTest() {
+	suite.consentRepo = new(MemoryConsentRepository)
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantId: "grant", GrantedAt: time.Now()})
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "old", Scope: []string{}, RevokedAt: time.Now()})
+
+	suite.service = new(ConsentService)
+	suite.service.Init(suite.consentRepo)
+}
+
+func (suite *ConsentServiceTestSuite) TestList_OnlyActiveConsents() {
+	consents, err := suite.service.List(context.Background(), "user")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), consents, 1)
+	assert.Equal(suite.T(), "app", consents[0].ClientId)
+}
+
+func (suite *ConsentServiceTestSuite) TestRevoke_RevokeTokensIssuedBefore() {
+	issuedAt := time.Now().Unix()
+
+	assert.Nil(suite.T(), suite.service.Revoke(context.Background(), "user", "app"))
+
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "app")
+	assert.False(suite.T(), consent.Active())
+	assert.Empty(suite.T(), consent.Scope)
+	assert.True(suite.T(), consent.RevokesToken(&auth.RefreshTokenPayload{Iat: issuedAt}))
+	assert.False(suite.T(), consent.RevokesToken(&auth.RefreshTokenPayload{Iat: time.Now().Add(time.Minute).Unix()}))
+}
+
+func (suite *ConsentServiceTestSuite) TestRevoke_RevokeTokensOfGrantRegardlessOfIssuance() {
+	assert.Nil(suite.T(), suite.service.Revoke(context.Background(), "user", "app"))
+
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "app")
+	assert.Empty(suite.T(), consent.GrantId)
+	assert.True(suite.T(), consent.RevokesToken(&auth.RefreshTokenPayload{Gid: "grant", Iat: time.Now().Add(time.Minute).Unix()}))
+}
+
+func (suite *ConsentServiceTestSuite) TestRevoke_ErrorWhenNotGranted() {
+	assert.ErrorIs(suite.T(), suite.service.Revoke(context.Background(), "user", "old"), ErrConsentNotFound)
+	assert.ErrorIs(suite.T(), suite.service.Revoke(context.Background(), "user", "unknown"), ErrConsentNotFound)
+}
+
+func TestConsentService(t *testing.T) {
+	suite.Run(t, new(ConsentServiceTestSuite))
+}
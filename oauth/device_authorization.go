@@ -0,0 +1,32 @@
+package oauth
+
+import "time"
+
+type deviceStatus string
+
+const (
+	DevicePending  = deviceStatus("pending")
+	DeviceApproved = deviceStatus("approved")
+	DeviceDenied   = deviceStatus("denied")
+	DeviceConsumed = deviceStatus("consumed")
+)
+
+type DeviceAuthorization struct {
+	DeviceCode   string
+	UserCode     string
+	ClientId     string
+	Scope        []string
+	Status       deviceStatus
+	Sid          string
+	Sub          string
+	Amr          []string
+	AuthTime     int64
+	GrantId      string
+	Interval     time.Duration
+	LastPolledAt time.Time
+	ExpiresAt    time.Time
+}
+
+func (authorization *DeviceAuthorization) Expired(now time.Time) bool {
+	return !now.Before(authorization.ExpiresAt)
+}
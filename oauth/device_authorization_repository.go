@@ -0,0 +1,19 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrDeviceAuthorizationNotFound = errors.New("no device authorization found")
+	ErrUserCodeExists              = errors.New("user code already exists")
+	ErrDeviceStatusChanged         = errors.New("device authorization status changed")
+)
+
+type DeviceAuthorizationRepository interface {
+	Create(ctx context.Context, authorization *DeviceAuthorization) error
+	FindByDeviceCode(ctx context.Context, deviceCode string) (*DeviceAuthorization, error)
+	FindByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error)
+	Transition(ctx context.Context, authorization *DeviceAuthorization, from deviceStatus) error
+}
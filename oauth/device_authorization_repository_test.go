@@ -0,0 +1,89 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeviceAuthorizationRepoTestSuite struct {
+	suite.Suite
+	authorization *DeviceAuthorization
+	repo          DeviceAuthorizationRepository
+}
+
+func (suite *DeviceAuthorizationRepoTestSuite) SetupTest() {
+	suite.authorization = &DeviceAuthorization{
+		DeviceCode: "abc",
+		UserCode:   "BCDF-GHJK",
+		ClientId:   "tv",
+		Status:     DevicePending,
+		ExpiresAt:  time.Now().Add(time.Minute),
+	}
+	suite.repo = new(MemoryDeviceAuthorizationRepository)
+}
+
+func (suite *DeviceAuthorizationRepoTestSuite) TestFindByDeviceCode_ReturnAuthorization() {
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), suite.authorization))
+
+	found, err := suite.repo.FindByDeviceCode(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.authorization, found)
+}
+
+func (suite *DeviceAuthorizationRepoTestSuite) TestFindByUserCode_HideDeviceCode() {
+	suite.repo.Create(context.Background(), suite.authorization)
+
+	found, err := suite.repo.FindByUserCode(context.Background(), "BCDF-GHJK")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), found.DeviceCode)
+	assert.Equal(suite.T(), "tv", found.ClientId)
+}
+
+func (suite *DeviceAuthorizationRepoTestSuite) TestCreate_ErrorWithDuplicateUserCode() {
+	suite.repo.Create(context.Background(), suite.authorization)
+
+	err := suite.repo.Create(context.Background(), &DeviceAuthorization{
+		DeviceCode: "def",
+		UserCode:   "BCDF-GHJK",
+		ExpiresAt:  time.Now().Add(time.Minute),
+	})
+
+	assert.ErrorIs(suite.T(), err, ErrUserCodeExists)
+}
+
+func (suite *DeviceAuthorizationRepoTestSuite) TestTransition_KeepDeviceCode() {
+	suite.repo.Create(context.Background(), suite.authorization)
+
+	found, _ := suite.repo.FindByUserCode(context.Background(), "BCDF-GHJK")
+	found.Status = DeviceApproved
+	assert.Nil(suite.T(), suite.repo.Transition(context.Background(), found, DevicePending))
+
+	found, err := suite.repo.FindByDeviceCode(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), DeviceApproved, found.Status)
+}
+
+func (suite *DeviceAuthorizationRepoTestSuite) TestTransition_ErrorWhenStatusChanged() {
+	suite.repo.Create(context.Background(), suite.authorization)
+
+	approved, _ := suite.repo.FindByUserCode(context.Background(), "BCDF-GHJK")
+	approved.Status = DeviceApproved
+	suite.repo.Transition(context.Background(), approved, DevicePending)
+
+	polled, _ := suite.repo.FindByUserCode(context.Background(), "BCDF-GHJK")
+	polled.Status = DevicePending
+	err := suite.repo.Transition(context.Background(), polled, DevicePending)
+
+	assert.ErrorIs(suite.T(), err, ErrDeviceStatusChanged)
+	found, _ := suite.repo.FindByDeviceCode(context.Background(), "abc")
+	assert.Equal(suite.T(), DeviceApproved, found.Status)
+}
+
+func TestMemoryDeviceAuthorizationRepository(t *testing.T) {
+	suite.Run(t, new(DeviceAuthorizationRepoTestSuite))
+}
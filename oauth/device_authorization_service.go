@@ -0,0 +1,314 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+)
+
+const (
+	DeviceCodeGrantType         = "urn:ietf:params:oauth:grant-type:device_code"
+	deviceAuthorizationLifetime = 10 * time.Minute
+	devicePollingInterval       = 5 * time.Second
+	userCodeAlphabet            = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength              = 8
+	userCodeAttempts            = 5
+	userCodeLookupFailures      = 5
+	userCodeLookupWindow        = time.Minute
+)
+
+type lookupFailures struct {
+	count   int
+	resetAt time.Time
+}
+
+type DeviceAuthorizationService struct {
+	clientRepo      ClientRepository
+	deviceRepo      DeviceAuthorizationRepository
+	consentRepo     ConsentRepository
+	verificationURI string
+	mu              sync.Mutex
+	failures        map[string]*lookupFailures
+}
+
+func (service *DeviceAuthorizationService) Init(clientRepo ClientRepository, deviceRepo DeviceAuthorizationRepository, consentRepo ConsentRepository, verificationURI string) {
+	service.clientRepo = clientRepo
+	service.deviceRepo = deviceRepo
+	service.consentRepo = consentRepo
+	service.verificationURI = verificationURI
+}
+
+func (service *DeviceAuthorizationService) VerificationURI() string {
+	return service.verificationURI
+}
+
+func (service *DeviceAuthorizationService) Begin(ctx context.Context, client *Client, scope []string) (*DeviceAuthorization, error) {
+	if !client.AllowsGrant(DeviceCodeGrantType) {
+		return nil, newError(ErrUnauthorizedClient, "client is not allowed to use the device_code grant")
+	}
+
+	if ContainsPrivileged(scope) || !client.AllowsScope(scope) {
+		return nil, newError(ErrInvalidScope, "scope is not allowed for client")
+	}
+
+	deviceCode, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < userCodeAttempts; attempt++ {
+		userCode, err := randomUserCode()
+		if err != nil {
+			return nil, err
+		}
+
+		authorization := &DeviceAuthorization{
+			DeviceCode: deviceCode,
+			UserCode:   userCode,
+			ClientId:   client.Identifier,
+			Scope:      scope,
+			Status:     DevicePending,
+			Interval:   devicePollingInterval,
+			ExpiresAt:  time.Now().Add(deviceAuthorizationLifetime),
+		}
+
+		err = service.deviceRepo.Create(ctx, authorization)
+		if errors.Is(err, ErrUserCodeExists) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		return authorization, nil
+	}
+	return nil, ErrUserCodeExists
+}
+
+func (service *DeviceAuthorizationService) Lookup(ctx context.Context, userCode string, session *auth.RefreshTokenPayload) (*DeviceAuthorization, *Client, error) {
+	if service.throttled(session.Sub) {
+		return nil, nil, newError(ErrSlowDown, "too many invalid user codes")
+	}
+
+	authorization, err := service.deviceRepo.FindByUserCode(ctx, NormalizeUserCode(userCode))
+	if errors.Is(err, ErrDeviceAuthorizationNotFound) {
+		service.recordFailure(session.Sub)
+		return nil, nil, newError(ErrInvalidGrant, "user code is invalid")
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if authorization.Expired(time.Now()) {
+		return nil, nil, newError(ErrExpiredToken, "user code is expired")
+	}
+
+	if authorization.Status != DevicePending {
+		return nil, nil, newError(ErrInvalidGrant, "user code was already used")
+	}
+
+	client, err := service.clientRepo.FindByIdentifier(ctx, authorization.ClientId)
+	if errors.Is(err, ErrClientNotFound) {
+		return nil, nil, newError(ErrInvalidClient, "unknown client")
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+	return authorization, client, nil
+}
+
+func (service *DeviceAuthorizationService) Approve(ctx context.Context, userCode string, session *auth.RefreshTokenPayload) error {
+	authorization, _, err := service.Lookup(ctx, userCode, session)
+	if err != nil {
+		return err
+	}
+
+	sid, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	grantId, err := service.grantConsent(ctx, authorization, session.Sub)
+	if err != nil {
+		return err
+	}
+
+	authorization.Status = DeviceApproved
+	authorization.Sid = sid
+	authorization.Sub = session.Sub
+	authorization.Amr = session.Amr
+	authorization.AuthTime = session.AuthTime
+	authorization.GrantId = grantId
+	return service.decide(ctx, authorization)
+}
+
+func (service *DeviceAuthorizationService) grantConsent(ctx context.Context, authorization *DeviceAuthorization, sub string) (string, error) {
+	consent, err := service.consentRepo.Find(ctx, sub, authorization.ClientId)
+	if errors.Is(err, ErrConsentNotFound) {
+		consent = &Consent{Sub: sub, ClientId: authorization.ClientId, Scope: []string{}}
+	} else if err != nil {
+		return "", err
+	}
+
+	if !consent.Active() {
+		grantId, err := randomString()
+		if err != nil {
+			return "", err
+		}
+		consent.GrantId = grantId
+	}
+
+	consent.Grant(authorization.Scope, time.Now())
+	return consent.GrantId, service.consentRepo.Save(ctx, consent)
+}
+
+func (service *DeviceAuthorizationService) Deny(ctx context.Context, userCode string, session *auth.RefreshTokenPayload) error {
+	authorization, _, err := service.Lookup(ctx, userCode, session)
+	if err != nil {
+		return err
+	}
+
+	authorization.Status = DeviceDenied
+	return service.decide(ctx, authorization)
+}
+
+func (service *DeviceAuthorizationService) decide(ctx context.Context, authorization *DeviceAuthorization) error {
+	err := service.deviceRepo.Transition(ctx, authorization, DevicePending)
+	if errors.Is(err, ErrDeviceStatusChanged) {
+		return newError(ErrInvalidGrant, "user code was already used")
+	}
+	return err
+}
+
+func (service *DeviceAuthorizationService) Poll(ctx context.Context, client *Client, deviceCode string) (*DeviceAuthorization, error) {
+	if deviceCode == "" {
+		return nil, newError(ErrInvalidRequest, "device_code is required")
+	}
+
+	if !client.AllowsGrant(DeviceCodeGrantType) {
+		return nil, newError(ErrUnauthorizedClient, "client is not allowed to use the device_code grant")
+	}
+
+	authorization, err := service.deviceRepo.FindByDeviceCode(ctx, deviceCode)
+	if errors.Is(err, ErrDeviceAuthorizationNotFound) {
+		return nil, newError(ErrInvalidGrant, "device code is invalid or already used")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if authorization.ClientId != client.Identifier {
+		return nil, newError(ErrInvalidGrant, "device code was issued to another client")
+	}
+
+	now := time.Now()
+	if authorization.Expired(now) {
+		return nil, newError(ErrExpiredToken, "device code is expired")
+	}
+
+	switch authorization.Status {
+	case DeviceApproved:
+		authorization.Status = DeviceConsumed
+		err := service.deviceRepo.Transition(ctx, authorization, DeviceApproved)
+		if errors.Is(err, ErrDeviceStatusChanged) {
+			return nil, newError(ErrInvalidGrant, "device code is invalid or already used")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		return authorization, nil
+	case DeviceDenied:
+		return nil, newError(ErrAccessDenied, "user denied the authorization request")
+	case DeviceConsumed:
+		return nil, newError(ErrInvalidGrant, "device code is invalid or already used")
+	}
+
+	tooFast := !authorization.LastPolledAt.IsZero() && now.Sub(authorization.LastPolledAt) < authorization.Interval
+	if tooFast {
+		authorization.Interval += devicePollingInterval
+	}
+
+	authorization.LastPolledAt = now
+	err = service.deviceRepo.Transition(ctx, authorization, DevicePending)
+	if errors.Is(err, ErrDeviceStatusChanged) {
+		return nil, newError(ErrAuthorizationPending, "user has not yet approved the request")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tooFast {
+		return nil, newError(ErrSlowDown, "polling too frequently")
+	}
+	return nil, newError(ErrAuthorizationPending, "user has not yet approved the request")
+}
+
+func (service *DeviceAuthorizationService) throttled(sub string) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	failures, ok := service.failures[sub]
+	return ok && failures.count >= userCodeLookupFailures && time.Now().Before(failures.resetAt)
+}
+
+func (service *DeviceAuthorizationService) recordFailure(sub string) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.failures == nil {
+		service.failures = make(map[string]*lookupFailures)
+	}
+
+	now := time.Now()
+	for key, failures := range service.failures {
+		if !now.Before(failures.resetAt) {
+			delete(service.failures, key)
+		}
+	}
+
+	failures, ok := service.failures[sub]
+	if !ok {
+		failures = &lookupFailures{resetAt: now.Add(userCodeLookupWindow)}
+		service.failures[sub] = failures
+	}
+	failures.count++
+}
+
+func NormalizeUserCode(userCode string) string {
+	normalized := make([]byte, 0, userCodeLength+1)
+	for _, char := range strings.ToUpper(userCode) {
+		if !strings.ContainsRune(userCodeAlphabet, char) {
+			continue
+		}
+
+		if len(normalized) == userCodeLength/2 {
+			normalized = append(normalized, '-')
+		}
+		normalized = append(normalized, byte(char))
+	}
+	return string(normalized)
+}
+
+func randomUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = userCodeAlphabet[n.Int64()]
+	}
+	return NormalizeUserCode(string(b)), nil
+}
@@ -0,0 +1,256 @@
+package oauth_test
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeviceAuthorizationServiceTestSuite struct {
+	suite.Suite
+	session     *auth.RefreshTokenPayload
+	client      *Client
+	deviceRepo  DeviceAuthorizationRepository
+	consentRepo ConsentRepository
+	service     *DeviceAuthorizationService
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) SetupTest() {
+	suite.session = &auth.RefreshTokenPayload{Sub: "user", Amr: []string{"pwd"}, AuthTime: 1700000000, Iat: 1700000600}
+	suite.client = &Client{
+		Identifier:              "tv",
+		GrantTypes:              []string{DeviceCodeGrantType},
+		TokenEndpointAuthMethod: NoneAuthMethod,
+		Scopes:                  []string{"profile", auth.AdminScope},
+	}
+	clientRepo := new(MemoryClientRepository)
+	clientRepo.Create(context.Background(), suite.client)
+	suite.deviceRepo = new(MemoryDeviceAuthorizationRepository)
+	suite.consentRepo = new(MemoryConsentRepository)
+
+	suite.service = new(DeviceAuthorizationService)
+	suite.service.Init(clientRepo, suite.deviceRepo, suite.consentRepo, "https://id.example.com/device")
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) begin() *DeviceAuthorization {
+	authorization, err := suite.service.Begin(context.Background(), suite.client, []string{"profile"})
+	assert.Nil(suite.T(), err)
+	return authorization
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) rewindLastPoll(deviceCode string) {
+	found, _ := suite.deviceRepo.FindByDeviceCode(context.Background(), deviceCode)
+	found.LastPolledAt = found.LastPolledAt.Add(-found.Interval)
+	suite.deviceRepo.Transition(context.Background(), found, DevicePending)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestBegin_IssueCodes() {
+	authorization := suite.begin()
+
+	assert.NotEmpty(suite.T(), authorization.DeviceCode)
+	assert.Regexp(suite.T(), regexp.MustCompile(`^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`), authorization.UserCode)
+	assert.Equal(suite.T(), 5*time.Second, authorization.Interval)
+	assert.Equal(suite.T(), DevicePending, authorization.Status)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestBegin_ErrorWhenGrantNotAllowed() {
+	suite.client.GrantTypes = []string{AuthorizationCodeGrantType}
+
+	_, err := suite.service.Begin(context.Background(), suite.client, nil)
+
+	assert.ErrorIs(suite.T(), err, ErrUnauthorizedClient)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestBegin_ErrorWithAdminScope() {
+	_, err := suite.service.Begin(context.Background(), suite.client, []string{auth.AdminScope})
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidScope)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestNormalizeUserCode_IgnoreCaseAndSeparators() {
+	assert.Equal(suite.T(), "BCDF-GHJK", NormalizeUserCode("bcdf ghjk"))
+	assert.Equal(suite.T(), "BCDF-GHJK", NormalizeUserCode("BCDFGHJK"))
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestLookup_FindClientByUserCode() {
+	authorization := suite.begin()
+
+	found, client, err := suite.service.Lookup(context.Background(), authorization.UserCode, suite.session)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"profile"}, found.Scope)
+	assert.Equal(suite.T(), "tv", client.Identifier)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_PendingThenSlowDown() {
+	authorization := suite.begin()
+
+	_, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.ErrorIs(suite.T(), err, ErrAuthorizationPending)
+
+	_, err = suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.ErrorIs(suite.T(), err, ErrSlowDown)
+
+	found, _ := suite.deviceRepo.FindByDeviceCode(context.Background(), authorization.DeviceCode)
+	assert.Equal(suite.T(), 10*time.Second, found.Interval)
+
+	suite.rewindLastPoll(authorization.DeviceCode)
+	_, err = suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.ErrorIs(suite.T(), err, ErrAuthorizationPending)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_ReturnApprovedAuthorizationOnce() {
+	authorization := suite.begin()
+	err := suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+	assert.Nil(suite.T(), err)
+
+	approved, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", approved.Sub)
+	assert.Equal(suite.T(), []string{"pwd"}, approved.Amr)
+	assert.Equal(suite.T(), int64(1700000000), approved.AuthTime)
+	assert.NotEmpty(suite.T(), approved.Sid)
+
+	_, err = suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestApprove_RecordConsentWithGrantId() {
+	authorization := suite.begin()
+	suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+
+	approved, _ := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	consent, err := suite.consentRepo.Find(context.Background(), "user", "tv")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"profile"}, consent.Scope)
+	assert.NotEmpty(suite.T(), consent.GrantId)
+	assert.Equal(suite.T(), consent.GrantId, approved.GrantId)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestApprove_KeepGrantIdOfActiveConsent() {
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "tv", Scope: []string{"email"}, GrantId: "grant", GrantedAt: time.Now()})
+	authorization := suite.begin()
+	suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+
+	approved, _ := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "tv")
+	assert.Equal(suite.T(), "grant", approved.GrantId)
+	assert.Equal(suite.T(), []string{"email", "profile"}, consent.Scope)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_AccessDeniedAfterDeny() {
+	authorization := suite.begin()
+	assert.Nil(suite.T(), suite.service.Deny(context.Background(), authorization.UserCode, suite.session))
+
+	_, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+
+	assert.ErrorIs(suite.T(), err, ErrAccessDenied)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_ErrorForOtherClient() {
+	authorization := suite.begin()
+
+	_, err := suite.service.Poll(context.Background(), &Client{Identifier: "other", GrantTypes: []string{DeviceCodeGrantType}}, authorization.DeviceCode)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_ExpiredToken() {
+	authorization := suite.begin()
+	found, _ := suite.deviceRepo.FindByDeviceCode(context.Background(), authorization.DeviceCode)
+	found.ExpiresAt = time.Now().Add(-time.Second)
+	suite.deviceRepo.Transition(context.Background(), found, DevicePending)
+
+	_, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+
+	assert.ErrorIs(suite.T(), err, ErrExpiredToken)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestApprove_ErrorWhenAlreadyDenied() {
+	authorization := suite.begin()
+	suite.service.Deny(context.Background(), authorization.UserCode, suite.session)
+
+	err := suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestLookup_ThrottleAfterRepeatedInvalidCodes() {
+	authorization := suite.begin()
+
+	for i := 0; i < 5; i++ {
+		_, _, err := suite.service.Lookup(context.Background(), "ZZZZ-ZZZZ", suite.session)
+		assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+	}
+
+	_, _, err := suite.service.Lookup(context.Background(), authorization.UserCode, suite.session)
+	assert.ErrorIs(suite.T(), err, ErrSlowDown)
+
+	_, _, err = suite.service.Lookup(context.Background(), authorization.UserCode, &auth.RefreshTokenPayload{Sub: "other"})
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_KeepApprovalWhenPendingPollIsStale() {
+	authorization := suite.begin()
+	stale, _ := suite.deviceRepo.FindByDeviceCode(context.Background(), authorization.DeviceCode)
+	suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+
+	stale.LastPolledAt = time.Now()
+	err := suite.deviceRepo.Transition(context.Background(), stale, DevicePending)
+	assert.ErrorIs(suite.T(), err, ErrDeviceStatusChanged)
+
+	approved, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", approved.Sub)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestPoll_ConcurrentPollsConsumeApprovalOnce() {
+	authorization := suite.begin()
+	suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+	}
+	assert.Equal(suite.T(), 1, succeeded)
+}
+
+func (suite *DeviceAuthorizationServiceTestSuite) TestDeny_ErrorWhenAlreadyApproved() {
+	authorization := suite.begin()
+	suite.service.Approve(context.Background(), authorization.UserCode, suite.session)
+
+	err := suite.service.Deny(context.Background(), authorization.UserCode, suite.session)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+	approved, err := suite.service.Poll(context.Background(), suite.client, authorization.DeviceCode)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", approved.Sub)
+}
+
+func TestDeviceAuthorizationService(t *testing.T) {
+	suite.Run(t, new(DeviceAuthorizationServiceTestSuite))
+}
@@ -0,0 +1,39 @@
+package oauth
+
+type Error struct {
+	Code        string
+	Description string
+}
+
+var (
+	ErrInvalidRequest          = &Error{Code: "invalid_request"}
+	ErrInvalidClient           = &Error{Code: "invalid_client"}
+	ErrInvalidGrant            = &Error{Code: "invalid_grant"}
+	ErrInvalidScope            = &Error{Code: "invalid_scope"}
+	ErrUnauthorizedClient      = &Error{Code: "unauthorized_client"}
+	ErrUnsupportedGrantType    = &Error{Code: "unsupported_grant_type"}
+	ErrUnsupportedResponseType = &Error{Code: "unsupported_response_type"}
+	ErrAccessDenied            = &Error{Code: "access_denied"}
+	ErrInvalidRedirectURI      = &Error{Code: "invalid_redirect_uri"}
+	ErrInvalidClientMetadata   = &Error{Code: "invalid_client_metadata"}
+	ErrAuthorizationPending    = &Error{Code: "authorization_pending"}
+	ErrSlowDown                = &Error{Code: "slow_down"}
+	ErrExpiredToken            = &Error{Code: "expired_token"}
+	ErrConsentRequired         = &Error{Code: "consent_required"}
+)
+
+func (err *Error) Error() string {
+	if err.Description == "" {
+		return err.Code
+	}
+	return err.Code + ": " + err.Description
+}
+
+func (err *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other.Code == err.Code
+}
+
+func newError(kind *Error, description string) *Error {
+	return &Error{Code: kind.Code, Description: description}
+}
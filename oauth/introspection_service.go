@@ -0,0 +1,164 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/user"
+)
+
+const (
+	AccessTokenTypeHint  = "access_token"
+	RefreshTokenTypeHint = "refresh_token"
+)
+
+type Introspection struct {
+	Active    bool
+	TokenType string
+	Sub       string
+	ClientId  string
+	Scope     []string
+	Exp       int64
+	Iat       int64
+}
+
+type IntrospectionService struct {
+	accessTokenService  auth.TokenService[*auth.RefreshTokenPayload]
+	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo            user.UserRepository
+	clientRepo          ClientRepository
+	consentRepo         ConsentRepository
+}
+
+func (service *IntrospectionService) Init(
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	userRepo user.UserRepository,
+	clientRepo ClientRepository,
+	consentRepo ConsentRepository,
+) {
+	service.accessTokenService = accessTokenService
+	service.refreshTokenService = refreshTokenService
+	service.userRepo = userRepo
+	service.clientRepo = clientRepo
+	service.consentRepo = consentRepo
+}
+
+func (service *IntrospectionService) Introspect(ctx context.Context, client *Client, token string, tokenTypeHint string) (*Introspection, error) {
+	if client.IsPublic() {
+		return nil, newError(ErrInvalidClient, "public clients cannot introspect tokens")
+	}
+
+	if token == "" {
+		return nil, newError(ErrInvalidRequest, "token is required")
+	}
+
+	tokenTypes := []string{AccessTokenTypeHint, RefreshTokenTypeHint}
+	if tokenTypeHint == RefreshTokenTypeHint {
+		tokenTypes = []string{RefreshTokenTypeHint, AccessTokenTypeHint}
+	}
+
+	for _, tokenType := range tokenTypes {
+		payload, err := service.Validate(ctx, token, tokenType)
+		if errors.Is(err, ErrInvalidGrant) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Introspection{
+			Active:    true,
+			TokenType: tokenType,
+			Sub:       payload.Sub,
+			ClientId:  payload.ClientId,
+			Scope:     payload.Scope,
+			Exp:       payload.Exp,
+			Iat:       payload.Iat,
+		}, nil
+	}
+	return &Introspection{}, nil
+}
+
+func (service *IntrospectionService) Validate(ctx context.Context, token string, tokenType string) (*auth.RefreshTokenPayload, error) {
+	payload, err := service.tokenService(tokenType).Validate(ctx, jwt.Jwt(token))
+	if err != nil {
+		return nil, newError(ErrInvalidGrant, "token is invalid")
+	}
+
+	active, err := service.active(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !active {
+		return nil, newError(ErrInvalidGrant, "token is not active")
+	}
+	return payload, nil
+}
+
+func (service *IntrospectionService) tokenService(tokenType string) auth.TokenService[*auth.RefreshTokenPayload] {
+	if tokenType == RefreshTokenTypeHint {
+		return service.refreshTokenService
+	}
+	return service.accessTokenService
+}
+
+func (service *IntrospectionService) active(ctx context.Context, payload *auth.RefreshTokenPayload) (bool, error) {
+	if payload.Exp <= time.Now().Unix() {
+		return false, nil
+	}
+
+	if payload.ClientId != "" {
+		_, err := service.clientRepo.FindByIdentifier(ctx, payload.ClientId)
+		if errors.Is(err, ErrClientNotFound) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if payload.Sid == "" && payload.Sub == payload.ClientId {
+			return true, nil
+		}
+
+		consent, err := service.findConsent(ctx, payload)
+		if errors.Is(err, ErrConsentNotFound) {
+			consent = &Consent{}
+		} else if err != nil {
+			return false, err
+		}
+
+		if consent.RevokesToken(payload) {
+			return false, nil
+		}
+	}
+
+	found, err := service.userRepo.FindByIdentifier(ctx, payload.Sub)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if auth.CheckStatus(found) != nil {
+		return false, nil
+	}
+
+	revokedAt := found.SessionsRevokedAt
+	return revokedAt.IsZero() || time.Unix(payload.Iat, 0).After(revokedAt), nil
+}
+
+func (service *IntrospectionService) findConsent(ctx context.Context, payload *auth.RefreshTokenPayload) (*Consent, error) {
+	if payload.Gid != "" {
+		return service.consentRepo.FindByGrantId(ctx, payload.Sub, payload.Gid)
+	}
+	return service.consentRepo.Find(ctx, payload.Sub, payload.ClientId)
+}
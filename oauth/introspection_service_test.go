@@ -0,0 +1,182 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IntrospectionServiceTestSuite struct {
+	suite.Suite
+	accessTokenService  auth.TokenService[*auth.RefreshTokenPayload]
+	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo            user.UserRepository
+	clientRepo          ClientRepository
+	consentRepo         ConsentRepository
+	resourceServer      *Client
+	service             *IntrospectionService
+}
+
+func newTokenService(key string) auth.TokenService[*auth.RefreshTokenPayload] {
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue(key))
+	tokenService := new(auth.RefreshTokenService)
+	tokenService.Init(jwtService)
+	return tokenService
+}
+
+func (suite *IntrospectionServiceTestSuite) SetupTest() {
+	suite.accessTokenService = newTokenService("access")
+	suite.refreshTokenService = newTokenService("refresh")
+
+	suite.userRepo = new(user.MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "user", Status: user.Active})
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "suspendedUser", Status: user.Suspended})
+
+	suite.clientRepo = new(MemoryClientRepository)
+	suite.clientRepo.Create(context.Background(), &Client{Identifier: "app", TokenEndpointAuthMethod: NoneAuthMethod})
+	suite.clientRepo.Create(context.Background(), &Client{Identifier: "service", TokenEndpointAuthMethod: ClientSecretBasicAuthMethod})
+	suite.resourceServer = &Client{Identifier: "api", TokenEndpointAuthMethod: ClientSecretBasicAuthMethod}
+
+	suite.consentRepo = new(MemoryConsentRepository)
+
+	suite.service = new(IntrospectionService)
+	suite.service.Init(suite.accessTokenService, suite.refreshTokenService, suite.userRepo, suite.clientRepo, suite.consentRepo)
+}
+
+func (suite *IntrospectionServiceTestSuite) introspect(token jwt.Jwt, tokenTypeHint string) *Introspection {
+	introspection, err := suite.service.Introspect(context.Background(), suite.resourceServer, string(token), tokenTypeHint)
+	assert.Nil(suite.T(), err)
+	return introspection
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_ActiveAccessToken() {
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Scope: []string{"profile"}})
+
+	introspection := suite.introspect(token, "")
+
+	assert.True(suite.T(), introspection.Active)
+	assert.Equal(suite.T(), AccessTokenTypeHint, introspection.TokenType)
+	assert.Equal(suite.T(), "user", introspection.Sub)
+	assert.Equal(suite.T(), "app", introspection.ClientId)
+	assert.Equal(suite.T(), []string{"profile"}, introspection.Scope)
+	assert.Greater(suite.T(), introspection.Exp, time.Now().Unix())
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_FallBackToRefreshToken() {
+	token, _ := suite.refreshTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app"})
+
+	introspection := suite.introspect(token, AccessTokenTypeHint)
+
+	assert.True(suite.T(), introspection.Active)
+	assert.Equal(suite.T(), RefreshTokenTypeHint, introspection.TokenType)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_ActiveClientCredentialsToken() {
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sub: "service", ClientId: "service"})
+
+	introspection := suite.introspect(token, "")
+
+	assert.True(suite.T(), introspection.Active)
+	assert.Equal(suite.T(), "service", introspection.ClientId)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveForUnknownToken() {
+	token, _ := newTokenService("other").Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user"})
+
+	assert.False(suite.T(), suite.introspect(token, "").Active)
+	assert.False(suite.T(), suite.introspect("not-a-token", "").Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveForExpiredToken() {
+	token, _ := jwt.CreateJwt(jwt.HS256, map[string]interface{}{
+		"sid": "123",
+		"sub": "user",
+		"iat": time.Now().Add(-time.Hour).Unix(),
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	}, "access")
+
+	assert.False(suite.T(), suite.introspect(token, "").Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveAfterSessionsRevoked() {
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user"})
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), "user")
+	found.SessionsRevokedAt = time.Now()
+	suite.userRepo.Update(context.Background(), found)
+
+	assert.False(suite.T(), suite.introspect(token, "").Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveForSuspendedUser() {
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "suspendedUser"})
+
+	assert.False(suite.T(), suite.introspect(token, "").Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_ActiveForUserWithExpiredSuspension() {
+	suite.userRepo.Create(context.Background(), &user.User{Identifier: "formerlySuspended", Status: user.Suspended, SuspendedUntil: time.Now().Add(-time.Hour)})
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "formerlySuspended"})
+
+	assert.True(suite.T(), suite.introspect(token, "").Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveForRemovedClient() {
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sub: "service", ClientId: "service"})
+	suite.clientRepo.Remove(context.Background(), "service")
+
+	assert.False(suite.T(), suite.introspect(token, "").Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveAfterConsentRevoked() {
+	consent := &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantedAt: time.Now()}
+	suite.consentRepo.Save(context.Background(), consent)
+	token, _ := suite.refreshTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Scope: []string{"profile"}})
+	assert.True(suite.T(), suite.introspect(token, RefreshTokenTypeHint).Active)
+
+	consent.Revoke(time.Now())
+	suite.consentRepo.Save(context.Background(), consent)
+
+	assert.False(suite.T(), suite.introspect(token, RefreshTokenTypeHint).Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_InactiveForTokenOfPreviousGrant() {
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantId: "current", GrantedAt: time.Now()})
+	current, _ := suite.refreshTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Gid: "current"})
+	previous, _ := suite.refreshTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Gid: "previous"})
+
+	assert.True(suite.T(), suite.introspect(current, RefreshTokenTypeHint).Active)
+	assert.False(suite.T(), suite.introspect(previous, RefreshTokenTypeHint).Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_FollowGrantOfExchangedToken() {
+	consent := &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantId: "grant", GrantedAt: time.Now()}
+	suite.consentRepo.Save(context.Background(), consent)
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "service", Gid: "grant"})
+	assert.True(suite.T(), suite.introspect(token, AccessTokenTypeHint).Active)
+
+	consent.Revoke(time.Now())
+	suite.consentRepo.Save(context.Background(), consent)
+
+	assert.False(suite.T(), suite.introspect(token, AccessTokenTypeHint).Active)
+}
+
+func (suite *IntrospectionServiceTestSuite) TestIntrospect_ErrorForPublicClient() {
+	token, _ := suite.accessTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user"})
+
+	_, err := suite.service.Introspect(context.Background(), &Client{Identifier: "app", TokenEndpointAuthMethod: NoneAuthMethod}, string(token), "")
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidClient)
+}
+
+func TestIntrospectionService(t *testing.T) {
+	suite.Run(t, new(IntrospectionServiceTestSuite))
+}
@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryAuthorizationCodeRepository struct {
+	mu    sync.Mutex
+	codes map[string]*AuthorizationCode
+}
+
+func (repo *MemoryAuthorizationCodeRepository) Create(ctx context.Context, code *AuthorizationCode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.codes == nil {
+		repo.codes = make(map[string]*AuthorizationCode)
+	}
+
+	now := time.Now()
+	for key, stored := range repo.codes {
+		if stored.Expired(now) {
+			delete(repo.codes, key)
+		}
+	}
+
+	created := *code
+	created.Code = ""
+	repo.codes[hashSecret(code.Code)] = &created
+	return nil
+}
+
+func (repo *MemoryAuthorizationCodeRepository) Consume(ctx context.Context, code string) (*AuthorizationCode, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := hashSecret(code)
+	stored, ok := repo.codes[key]
+	if !ok {
+		return nil, ErrCodeNotFound
+	}
+	delete(repo.codes, key)
+
+	consumed := *stored
+	consumed.Code = code
+	return &consumed, nil
+}
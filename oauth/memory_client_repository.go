@@ -0,0 +1,108 @@
+package oauth
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemoryClientRepository struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+func (repo *MemoryClientRepository) FindByIdentifier(ctx context.Context, identifier string) (*Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	client, ok := repo.clients[identifier]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+
+	found := *client
+	return &found, nil
+}
+
+func (repo *MemoryClientRepository) List(ctx context.Context) ([]*Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(repo.clients))
+	for _, client := range repo.clients {
+		found := *client
+		clients = append(clients, &found)
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Identifier < clients[j].Identifier
+	})
+	return clients, nil
+}
+
+func (repo *MemoryClientRepository) Create(ctx context.Context, client *Client) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.clients == nil {
+		repo.clients = make(map[string]*Client)
+	}
+
+	if _, ok := repo.clients[client.Identifier]; ok {
+		return ErrClientExists
+	}
+
+	if client.CreatedAt.IsZero() {
+		client.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	created := *client
+	repo.clients[client.Identifier] = &created
+	return nil
+}
+
+func (repo *MemoryClientRepository) Update(ctx context.Context, client *Client) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.clients[client.Identifier]; !ok {
+		return ErrClientNotFound
+	}
+
+	updated := *client
+	repo.clients[client.Identifier] = &updated
+	return nil
+}
+
+func (repo *MemoryClientRepository) Remove(ctx context.Context, identifier string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.clients[identifier]; !ok {
+		return ErrClientNotFound
+	}
+
+	delete(repo.clients, identifier)
+	return nil
+}
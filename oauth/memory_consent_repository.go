@@ -0,0 +1,91 @@
+package oauth
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type consentKey struct {
+	sub      string
+	clientId string
+}
+
+type MemoryConsentRepository struct {
+	mu       sync.RWMutex
+	consents map[consentKey]*Consent
+}
+
+func (repo *MemoryConsentRepository) Find(ctx context.Context, sub string, clientId string) (*Consent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	consent, ok := repo.consents[consentKey{sub: sub, clientId: clientId}]
+	if !ok {
+		return nil, ErrConsentNotFound
+	}
+	return copyConsent(consent), nil
+}
+
+func (repo *MemoryConsentRepository) FindByGrantId(ctx context.Context, sub string, grantId string) (*Consent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for key, consent := range repo.consents {
+		if key.sub == sub && grantId != "" && consent.GrantId == grantId {
+			return copyConsent(consent), nil
+		}
+	}
+	return nil, ErrConsentNotFound
+}
+
+func (repo *MemoryConsentRepository) ListBySubject(ctx context.Context, sub string) ([]*Consent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	consents := []*Consent{}
+	for key, consent := range repo.consents {
+		if key.sub == sub {
+			consents = append(consents, copyConsent(consent))
+		}
+	}
+
+	sort.Slice(consents, func(i, j int) bool {
+		return consents[i].ClientId < consents[j].ClientId
+	})
+	return consents, nil
+}
+
+func (repo *MemoryConsentRepository) Save(ctx context.Context, consent *Consent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.consents == nil {
+		repo.consents = make(map[consentKey]*Consent)
+	}
+
+	repo.consents[consentKey{sub: consent.Sub, clientId: consent.ClientId}] = copyConsent(consent)
+	return nil
+}
+
+func copyConsent(consent *Consent) *Consent {
+	copied := *consent
+	copied.Scope = append([]string{}, consent.Scope...)
+	return &copied
+}
@@ -0,0 +1,104 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryDeviceAuthorizationRepository struct {
+	mu             sync.Mutex
+	authorizations map[string]*DeviceAuthorization
+	deviceCodes    map[string]string
+}
+
+func (repo *MemoryDeviceAuthorizationRepository) Create(ctx context.Context, authorization *DeviceAuthorization) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.authorizations == nil {
+		repo.authorizations = make(map[string]*DeviceAuthorization)
+		repo.deviceCodes = make(map[string]string)
+	}
+
+	now := time.Now()
+	for deviceCode, userCode := range repo.deviceCodes {
+		if repo.authorizations[userCode].Expired(now) {
+			delete(repo.authorizations, userCode)
+			delete(repo.deviceCodes, deviceCode)
+		}
+	}
+
+	if _, ok := repo.authorizations[authorization.UserCode]; ok {
+		return ErrUserCodeExists
+	}
+
+	created := *authorization
+	created.DeviceCode = hashSecret(authorization.DeviceCode)
+	repo.authorizations[authorization.UserCode] = &created
+	repo.deviceCodes[created.DeviceCode] = authorization.UserCode
+	return nil
+}
+
+func (repo *MemoryDeviceAuthorizationRepository) FindByDeviceCode(ctx context.Context, deviceCode string) (*DeviceAuthorization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	userCode, ok := repo.deviceCodes[hashSecret(deviceCode)]
+	if !ok {
+		return nil, ErrDeviceAuthorizationNotFound
+	}
+
+	found := *repo.authorizations[userCode]
+	found.DeviceCode = deviceCode
+	return &found, nil
+}
+
+func (repo *MemoryDeviceAuthorizationRepository) FindByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.authorizations[userCode]
+	if !ok {
+		return nil, ErrDeviceAuthorizationNotFound
+	}
+
+	found := *stored
+	found.DeviceCode = ""
+	return &found, nil
+}
+
+func (repo *MemoryDeviceAuthorizationRepository) Transition(ctx context.Context, authorization *DeviceAuthorization, from deviceStatus) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.authorizations[authorization.UserCode]
+	if !ok {
+		return ErrDeviceAuthorizationNotFound
+	}
+
+	if stored.Status != from {
+		return ErrDeviceStatusChanged
+	}
+
+	updated := *authorization
+	updated.DeviceCode = stored.DeviceCode
+	repo.authorizations[authorization.UserCode] = &updated
+	return nil
+}
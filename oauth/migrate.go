@@ -0,0 +1,15 @@
+package oauth
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/Untanky/go-id/migration"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+func Migrate(db *sql.DB) error {
+	return migration.Apply(db, migrations, "oauth_")
+}
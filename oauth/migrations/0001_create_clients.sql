@@ -0,0 +1,15 @@
+CREATE TABLE oauth_clients (
+    identifier                 TEXT      NOT NULL,
+    secret_hash                TEXT      NOT NULL DEFAULT '',
+    name                       TEXT      NOT NULL DEFAULT '',
+    redirect_uris              TEXT      NOT NULL DEFAULT '[]',
+    grant_types                TEXT      NOT NULL DEFAULT '[]',
+    scopes                     TEXT      NOT NULL DEFAULT '[]',
+    token_endpoint_auth_method TEXT      NOT NULL,
+    access_token_lifetime      INTEGER   NOT NULL DEFAULT 0,
+    refresh_token_lifetime     INTEGER   NOT NULL DEFAULT 0,
+    id_token_signing_alg       TEXT      NOT NULL DEFAULT '',
+    created_at                 TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX oauth_clients_identifier_idx ON oauth_clients (identifier);
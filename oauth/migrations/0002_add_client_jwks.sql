@@ -0,0 +1 @@
+ALTER TABLE oauth_clients ADD COLUMN jwks TEXT NOT NULL DEFAULT 'null';
@@ -0,0 +1,9 @@
+CREATE TABLE oauth_consents (
+    sub        TEXT      NOT NULL,
+    client_id  TEXT      NOT NULL,
+    scope      TEXT      NOT NULL DEFAULT '[]',
+    granted_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00',
+    revoked_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00'
+);
+
+CREATE UNIQUE INDEX oauth_consents_sub_client_id_idx ON oauth_consents (sub, client_id);
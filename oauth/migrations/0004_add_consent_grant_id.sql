@@ -0,0 +1 @@
+ALTER TABLE oauth_consents ADD COLUMN grant_id TEXT NOT NULL DEFAULT '';
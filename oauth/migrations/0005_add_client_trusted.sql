@@ -0,0 +1 @@
+ALTER TABLE oauth_clients ADD COLUMN trusted BOOLEAN NOT NULL DEFAULT FALSE;
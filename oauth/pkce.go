@@ -0,0 +1,36 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+const S256 = "S256"
+
+var (
+	verifierPattern  = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+	challengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+)
+
+func VerifyCodeChallenge(verifier string, challenge string) bool {
+	if !verifierPattern.MatchString(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(verifier)), []byte(challenge)) == 1
+}
+
+func hashSecret(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
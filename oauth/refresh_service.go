@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"context"
+
+	"github.com/Untanky/go-id/auth"
+)
+
+const RefreshTokenGrantType = "refresh_token"
+
+type RefreshService struct {
+	introspectionService *IntrospectionService
+}
+
+func (service *RefreshService) Init(introspectionService *IntrospectionService) {
+	service.introspectionService = introspectionService
+}
+
+func (service *RefreshService) Refresh(ctx context.Context, client *Client, request *TokenRequest) (*auth.RefreshTokenPayload, error) {
+	if request.GrantType != RefreshTokenGrantType {
+		return nil, newError(ErrUnsupportedGrantType, "grant_type must be refresh_token")
+	}
+
+	if !client.AllowsGrant(RefreshTokenGrantType) {
+		return nil, newError(ErrUnauthorizedClient, "client is not allowed to use refresh tokens")
+	}
+
+	if request.RefreshToken == "" {
+		return nil, newError(ErrInvalidRequest, "refresh_token is required")
+	}
+
+	session, err := service.introspectionService.Validate(ctx, request.RefreshToken, RefreshTokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.ClientId != client.Identifier {
+		return nil, newError(ErrInvalidGrant, "refresh_token was issued to another client")
+	}
+
+	scope := session.Scope
+	if len(request.Scope) > 0 {
+		for _, requested := range request.Scope {
+			if !session.HasScope(requested) {
+				return nil, newError(ErrInvalidScope, "scope "+requested+" exceeds the refresh token")
+			}
+		}
+		scope = request.Scope
+	}
+
+	return &auth.RefreshTokenPayload{
+		Sid:      session.Sid,
+		Sub:      session.Sub,
+		Amr:      session.Amr,
+		ClientId: client.Identifier,
+		Scope:    scope,
+		Gid:      session.Gid,
+		AuthTime: session.AuthTime,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const clientColumns = `identifier, secret_hash, name, redirect_uris, grant_types, scopes, token_endpoint_auth_method,
+	jwks, access_token_lifetime, refresh_token_lifetime, id_token_signing_alg, trusted, created_at`
+
+type SqlClientRepository struct {
+	db *sql.DB
+}
+
+func (repo *SqlClientRepository) Init(db *sql.DB) {
+	repo.db = db
+}
+
+func (repo *SqlClientRepository) FindByIdentifier(ctx context.Context, identifier string) (*Client, error) {
+	client, err := scanClient(repo.db.QueryRowContext(
+		ctx,
+		`SELECT `+clientColumns+` FROM oauth_clients WHERE identifier = $1`,
+		identifier,
+	))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (repo *SqlClientRepository) List(ctx context.Context) ([]*Client, error) {
+	rows, err := repo.db.QueryContext(ctx, `SELECT `+clientColumns+` FROM oauth_clients ORDER BY identifier`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []*Client{}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+func (repo *SqlClientRepository) Create(ctx context.Context, client *Client) error {
+	redirectURIs, grantTypes, scopes, err := marshalClientLists(client)
+	if err != nil {
+		return err
+	}
+
+	jwks, err := json.Marshal(client.Jwks)
+	if err != nil {
+		return err
+	}
+
+	createdAt := client.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC().Truncate(time.Second)
+	}
+
+	_, err = repo.db.ExecContext(
+		ctx,
+		`INSERT INTO oauth_clients (`+clientColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		client.Identifier, client.SecretHash, client.Name, redirectURIs, grantTypes, scopes, client.TokenEndpointAuthMethod, string(jwks),
+		int64(client.AccessTokenLifetime/time.Second), int64(client.RefreshTokenLifetime/time.Second), client.IdTokenSigningAlg, client.Trusted, createdAt,
+	)
+
+	if err != nil {
+		if found, _ := repo.FindByIdentifier(ctx, client.Identifier); found != nil {
+			return ErrClientExists
+		}
+		return err
+	}
+
+	client.CreatedAt = createdAt
+	return nil
+}
+
+func (repo *SqlClientRepository) Update(ctx context.Context, client *Client) error {
+	redirectURIs, grantTypes, scopes, err := marshalClientLists(client)
+	if err != nil {
+		return err
+	}
+
+	jwks, err := json.Marshal(client.Jwks)
+	if err != nil {
+		return err
+	}
+
+	result, err := repo.db.ExecContext(
+		ctx,
+		`UPDATE oauth_clients SET secret_hash = $2, name = $3, redirect_uris = $4, grant_types = $5, scopes = $6,
+			token_endpoint_auth_method = $7, jwks = $8, access_token_lifetime = $9, refresh_token_lifetime = $10,
+			id_token_signing_alg = $11
+		WHERE identifier = $1`,
+		client.Identifier, client.SecretHash, client.Name, redirectURIs, grantTypes, scopes, client.TokenEndpointAuthMethod, string(jwks),
+		int64(client.AccessTokenLifetime/time.Second), int64(client.RefreshTokenLifetime/time.Second), client.IdTokenSigningAlg,
+	)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+func (repo *SqlClientRepository) Remove(ctx context.Context, identifier string) error {
+	result, err := repo.db.ExecContext(ctx, `DELETE FROM oauth_clients WHERE identifier = $1`, identifier)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanClient(row rowScanner) (*Client, error) {
+	client := new(Client)
+	var redirectURIs, grantTypes, scopes, jwks string
+	var accessTokenLifetime, refreshTokenLifetime int64
+
+	err := row.Scan(
+		&client.Identifier, &client.SecretHash, &client.Name, &redirectURIs, &grantTypes, &scopes,
+		&client.TokenEndpointAuthMethod, &jwks, &accessTokenLifetime, &refreshTokenLifetime, &client.IdTokenSigningAlg,
+		&client.Trusted, &client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for target, value := range map[*[]string]string{
+		&client.RedirectURIs: redirectURIs,
+		&client.GrantTypes:   grantTypes,
+		&client.Scopes:       scopes,
+	} {
+		if err := json.Unmarshal([]byte(value), target); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal([]byte(jwks), &client.Jwks); err != nil {
+		return nil, err
+	}
+
+	client.AccessTokenLifetime = time.Duration(accessTokenLifetime) * time.Second
+	client.RefreshTokenLifetime = time.Duration(refreshTokenLifetime) * time.Second
+	client.CreatedAt = client.CreatedAt.UTC()
+	return client, nil
+}
+
+func marshalClientLists(client *Client) (string, string, string, error) {
+	encoded := make([]string, 3)
+	for i, values := range [][]string{client.RedirectURIs, client.GrantTypes, client.Scopes} {
+		value, err := json.Marshal(values)
+		if err != nil {
+			return "", "", "", err
+		}
+		encoded[i] = string(value)
+	}
+	return encoded[0], encoded[1], encoded[2], nil
+}
+
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrClientNotFound
+	}
+	return nil
+}
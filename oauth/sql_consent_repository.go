@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+const consentColumns = `sub, client_id, scope, grant_id, granted_at, revoked_at`
+
+type SqlConsentRepository struct {
+	db *sql.DB
+}
+
+func (repo *SqlConsentRepository) Init(db *sql.DB) {
+	repo.db = db
+}
+
+func (repo *SqlConsentRepository) Find(ctx context.Context, sub string, clientId string) (*Consent, error) {
+	return repo.findOne(ctx, `SELECT `+consentColumns+` FROM oauth_consents WHERE sub = $1 AND client_id = $2`, sub, clientId)
+}
+
+func (repo *SqlConsentRepository) FindByGrantId(ctx context.Context, sub string, grantId string) (*Consent, error) {
+	if grantId == "" {
+		return nil, ErrConsentNotFound
+	}
+	return repo.findOne(ctx, `SELECT `+consentColumns+` FROM oauth_consents WHERE sub = $1 AND grant_id = $2`, sub, grantId)
+}
+
+func (repo *SqlConsentRepository) findOne(ctx context.Context, query string, args ...interface{}) (*Consent, error) {
+	consent, err := scanConsent(repo.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConsentNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+func (repo *SqlConsentRepository) ListBySubject(ctx context.Context, sub string) ([]*Consent, error) {
+	rows, err := repo.db.QueryContext(ctx, `SELECT `+consentColumns+` FROM oauth_consents WHERE sub = $1 ORDER BY client_id`, sub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*Consent{}
+	for rows.Next() {
+		consent, err := scanConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
+
+func (repo *SqlConsentRepository) Save(ctx context.Context, consent *Consent) error {
+	scope, err := json.Marshal(consent.Scope)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.db.ExecContext(
+		ctx,
+		`INSERT INTO oauth_consents (`+consentColumns+`) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (sub, client_id) DO UPDATE SET scope = excluded.scope, grant_id = excluded.grant_id, granted_at = excluded.granted_at, revoked_at = excluded.revoked_at`,
+		consent.Sub, consent.ClientId, string(scope), consent.GrantId, consent.GrantedAt, consent.RevokedAt,
+	)
+	return err
+}
+
+func scanConsent(row rowScanner) (*Consent, error) {
+	consent := new(Consent)
+	var scope string
+
+	err := row.Scan(&consent.Sub, &consent.ClientId, &scope, &consent.GrantId, &consent.GrantedAt, &consent.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scope), &consent.Scope); err != nil {
+		return nil, err
+	}
+
+	if consent.Scope == nil {
+		consent.Scope = []string{}
+	}
+
+	consent.GrantedAt = consent.GrantedAt.UTC()
+	consent.RevokedAt = consent.RevokedAt.UTC()
+	return consent, nil
+}
@@ -0,0 +1,147 @@
+package oauth
+
+import (
+	"context"
+
+	"github.com/Untanky/go-id/auth"
+)
+
+const (
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	AccessTokenType        = "urn:ietf:params:oauth:token-type:access_token"
+
+	maxActorDepth = 3
+)
+
+type TokenExchange struct {
+	Subject *auth.RefreshTokenPayload
+	Actor   *auth.RefreshTokenPayload
+	Payload *auth.RefreshTokenPayload
+}
+
+func (exchange *TokenExchange) Impersonation() bool {
+	return exchange.Actor != nil
+}
+
+type TokenExchangeService struct {
+	introspectionService *IntrospectionService
+}
+
+func (service *TokenExchangeService) Init(introspectionService *IntrospectionService) {
+	service.introspectionService = introspectionService
+}
+
+func (service *TokenExchangeService) Exchange(ctx context.Context, client *Client, request *TokenRequest) (*TokenExchange, error) {
+	if request.GrantType != TokenExchangeGrantType {
+		return nil, newError(ErrUnsupportedGrantType, "grant_type must be token-exchange")
+	}
+
+	if client.IsPublic() || !client.AllowsGrant(TokenExchangeGrantType) {
+		return nil, newError(ErrUnauthorizedClient, "client is not allowed to exchange tokens")
+	}
+
+	if request.SubjectToken == "" || request.SubjectTokenType != AccessTokenType {
+		return nil, newError(ErrInvalidRequest, "subject_token must be an access token")
+	}
+
+	if (request.ActorToken != "" || request.ActorTokenType != "") && request.ActorTokenType != AccessTokenType {
+		return nil, newError(ErrInvalidRequest, "actor_token must be an access token")
+	}
+
+	if request.RequestedTokenType != "" && request.RequestedTokenType != AccessTokenType {
+		return nil, newError(ErrInvalidRequest, "only access tokens can be requested")
+	}
+
+	subject, err := service.introspectionService.Validate(ctx, request.SubjectToken, AccessTokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+
+	exchange := &TokenExchange{Subject: subject}
+	if subject.Sid == "" {
+		return exchange, newError(ErrInvalidGrant, "subject_token must belong to a user session")
+	}
+
+	if subject.ClientId != client.Identifier && !client.Trusted {
+		return exchange, newError(ErrInvalidGrant, "subject_token was issued to another client")
+	}
+
+	act := subject.Act
+	if request.ActorToken != "" {
+		actor, err := service.introspectionService.Validate(ctx, request.ActorToken, AccessTokenTypeHint)
+		if err != nil {
+			return exchange, err
+		}
+		exchange.Actor = actor
+
+		if actor.Sid != "" || actor.Sub != client.Identifier || actor.ClientId != client.Identifier {
+			return exchange, newError(ErrInvalidGrant, "actor_token must be issued to the client")
+		}
+
+		if !actor.HasScope(auth.ImpersonateScope) {
+			return exchange, newError(ErrInvalidGrant, "actor is not allowed to impersonate")
+		}
+
+		if actor.Sub == subject.Sub {
+			return exchange, newError(ErrInvalidRequest, "actor and subject must differ")
+		}
+
+		if subject.HasScope(auth.AdminScope) {
+			return exchange, newError(ErrInvalidGrant, "tokens with admin scope cannot be impersonated")
+		}
+
+		act = &auth.Actor{Sub: actor.Sub, ClientId: actor.ClientId, Act: subject.Act}
+	}
+
+	if actorDepth(act) > maxActorDepth {
+		return exchange, newError(ErrInvalidGrant, "act chain is too deep")
+	}
+
+	scope, err := exchangeScope(client, subject, request.Scope)
+	if err != nil {
+		return exchange, err
+	}
+
+	exchange.Payload = &auth.RefreshTokenPayload{
+		Sid:      subject.Sid,
+		Sub:      subject.Sub,
+		Amr:      subject.Amr,
+		ClientId: client.Identifier,
+		Scope:    scope,
+		Act:      act,
+		Gid:      subject.Gid,
+		Exp:      subject.Exp,
+	}
+	return exchange, nil
+}
+
+func exchangeScope(client *Client, subject *auth.RefreshTokenPayload, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		scope := []string{}
+		for _, granted := range subject.Scope {
+			if !isPrivileged(granted) && contains(client.Scopes, granted) {
+				scope = append(scope, granted)
+			}
+		}
+		return scope, nil
+	}
+
+	for _, scope := range requested {
+		if isPrivileged(scope) || !subject.HasScope(scope) {
+			return nil, newError(ErrInvalidScope, "scope "+scope+" exceeds the subject token")
+		}
+	}
+
+	if !client.AllowsScope(requested) {
+		return nil, newError(ErrInvalidScope, "scope is not allowed for client")
+	}
+	return requested, nil
+}
+
+func actorDepth(act *auth.Actor) int {
+	depth := 0
+	for ; act != nil; act = act.Act {
+		depth++
+	}
+	return depth
+}
@@ -0,0 +1,240 @@
+package oauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	. "github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TokenExchangeServiceTestSuite struct {
+	suite.Suite
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	gateway            *Client
+	consentRepo        ConsentRepository
+	service            *TokenExchangeService
+}
+
+func (suite *TokenExchangeServiceTestSuite) SetupTest() {
+	suite.accessTokenService = newTokenService("access")
+
+	userRepo := new(user.MemoryUserRepository)
+	for _, identifier := range []string{"user", "support", "admin"} {
+		userRepo.Create(context.Background(), &user.User{Identifier: identifier, Status: user.Active})
+	}
+
+	suite.gateway = &Client{
+		Identifier:              "gateway",
+		GrantTypes:              []string{TokenExchangeGrantType},
+		Scopes:                  []string{"profile", "email"},
+		TokenEndpointAuthMethod: ClientSecretBasicAuthMethod,
+		Trusted:                 true,
+	}
+	clientRepo := new(MemoryClientRepository)
+	clientRepo.Create(context.Background(), suite.gateway)
+	clientRepo.Create(context.Background(), &Client{Identifier: "app", TokenEndpointAuthMethod: NoneAuthMethod})
+	clientRepo.Create(context.Background(), &Client{Identifier: "service", TokenEndpointAuthMethod: ClientSecretBasicAuthMethod})
+
+	introspectionService := new(IntrospectionService)
+	suite.consentRepo = new(MemoryConsentRepository)
+	introspectionService.Init(suite.accessTokenService, newTokenService("refresh"), userRepo, clientRepo, suite.consentRepo)
+
+	suite.service = new(TokenExchangeService)
+	suite.service.Init(introspectionService)
+}
+
+func (suite *TokenExchangeServiceTestSuite) token(payload *auth.RefreshTokenPayload) string {
+	token, _ := suite.accessTokenService.Create(context.Background(), payload)
+	return string(token)
+}
+
+func (suite *TokenExchangeServiceTestSuite) userToken() string {
+	return suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Amr: []string{"pwd"}, Scope: []string{"profile", "email"}})
+}
+
+func (suite *TokenExchangeServiceTestSuite) actorToken() string {
+	return suite.token(&auth.RefreshTokenPayload{Sub: "gateway", ClientId: "gateway", Scope: []string{auth.ImpersonateScope}})
+}
+
+func exchangeRequest(subjectToken string, scope ...string) *TokenRequest {
+	return &TokenRequest{
+		GrantType:        TokenExchangeGrantType,
+		SubjectToken:     subjectToken,
+		SubjectTokenType: AccessTokenType,
+		Scope:            scope,
+	}
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_NarrowScope() {
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(suite.userToken(), "profile"))
+
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), exchange.Impersonation())
+	assert.Equal(suite.T(), "user", exchange.Payload.Sub)
+	assert.Equal(suite.T(), "123", exchange.Payload.Sid)
+	assert.Equal(suite.T(), []string{"pwd"}, exchange.Payload.Amr)
+	assert.Equal(suite.T(), "gateway", exchange.Payload.ClientId)
+	assert.Equal(suite.T(), []string{"profile"}, exchange.Payload.Scope)
+	assert.Nil(suite.T(), exchange.Payload.Act)
+	assert.Equal(suite.T(), exchange.Subject.Exp, exchange.Payload.Exp)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_DefaultToSubjectScopeAllowedForClient() {
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: []string{"profile", "orders"}})
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(subjectToken))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"profile"}, exchange.Payload.Scope)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_CarrySubjectGrant() {
+	suite.consentRepo.Save(context.Background(), &Consent{Sub: "user", ClientId: "app", Scope: []string{"profile"}, GrantId: "grant", GrantedAt: time.Now()})
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Gid: "grant", Scope: []string{"profile"}})
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(subjectToken, "profile"))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "grant", exchange.Payload.Gid)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenSubjectTokenBelongsToOtherClient() {
+	suite.gateway.Trusted = false
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(suite.userToken(), "profile"))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+	assert.Nil(suite.T(), exchange.Payload)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_AllowOwnSubjectTokenForUntrustedClient() {
+	suite.gateway.Trusted = false
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "gateway", Scope: []string{"profile"}})
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(subjectToken, "profile"))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "gateway", exchange.Payload.ClientId)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenScopeExceedsSubjectToken() {
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: []string{"profile"}})
+
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(subjectToken, "email"))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidScope)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ImpersonateWithActChain() {
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: []string{"profile"}, Act: &auth.Actor{Sub: "frontend"}})
+	request := exchangeRequest(subjectToken)
+	request.ActorToken = suite.actorToken()
+	request.ActorTokenType = AccessTokenType
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), exchange.Impersonation())
+	assert.Equal(suite.T(), "user", exchange.Payload.Sub)
+	assert.Equal(suite.T(), &auth.Actor{Sub: "gateway", ClientId: "gateway", Act: &auth.Actor{Sub: "frontend"}}, exchange.Payload.Act)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenActorMayNotImpersonate() {
+	request := exchangeRequest(suite.userToken())
+	request.ActorToken = suite.token(&auth.RefreshTokenPayload{Sub: "gateway", ClientId: "gateway", Scope: []string{"profile"}})
+	request.ActorTokenType = AccessTokenType
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+	assert.Equal(suite.T(), "gateway", exchange.Actor.Sub)
+	assert.Equal(suite.T(), "user", exchange.Subject.Sub)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenActorIsUserSession() {
+	request := exchangeRequest(suite.userToken())
+	request.ActorToken = suite.token(&auth.RefreshTokenPayload{Sid: "456", Sub: "support", ClientId: "gateway", Scope: []string{auth.ImpersonateScope}})
+	request.ActorTokenType = AccessTokenType
+
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenActorTokenBelongsToOtherClient() {
+	request := exchangeRequest(suite.userToken())
+	request.ActorToken = suite.token(&auth.RefreshTokenPayload{Sub: "service", ClientId: "service", Scope: []string{auth.ImpersonateScope}})
+	request.ActorTokenType = AccessTokenType
+
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenActChainTooDeep() {
+	act := &auth.Actor{Sub: "frontend", Act: &auth.Actor{Sub: "edge"}}
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: []string{"profile"}, Act: act})
+	request := exchangeRequest(subjectToken)
+	request.ActorToken = suite.actorToken()
+	request.ActorTokenType = AccessTokenType
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+	assert.Nil(suite.T(), err)
+
+	request.SubjectToken = suite.token(exchange.Payload)
+	_, err = suite.service.Exchange(context.Background(), suite.gateway, request)
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWhenImpersonatingAdmin() {
+	request := exchangeRequest(suite.token(&auth.RefreshTokenPayload{Sid: "789", Sub: "admin", Scope: []string{auth.AdminScope}}))
+	request.ActorToken = suite.actorToken()
+	request.ActorTokenType = AccessTokenType
+
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_NeverGrantPrivilegedScope() {
+	subjectToken := suite.token(&auth.RefreshTokenPayload{Sid: "456", Sub: "support", Scope: []string{auth.ImpersonateScope, "profile"}})
+
+	exchange, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(subjectToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"profile"}, exchange.Payload.Scope)
+
+	_, err = suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(subjectToken, auth.ImpersonateScope))
+	assert.ErrorIs(suite.T(), err, ErrInvalidScope)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWithInvalidSubjectToken() {
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest("invalid"))
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidGrant)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorWithUnsupportedTokenTypes() {
+	request := exchangeRequest(suite.userToken())
+	request.RequestedTokenType = "urn:ietf:params:oauth:token-type:refresh_token"
+
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, request)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidRequest)
+}
+
+func (suite *TokenExchangeServiceTestSuite) TestExchange_ErrorForClientWithoutGrant() {
+	suite.gateway.GrantTypes = []string{ClientCredentialsGrantType}
+
+	_, err := suite.service.Exchange(context.Background(), suite.gateway, exchangeRequest(suite.userToken()))
+
+	assert.ErrorIs(suite.T(), err, ErrUnauthorizedClient)
+}
+
+func TestTokenExchangeService(t *testing.T) {
+	suite.Run(t, new(TokenExchangeServiceTestSuite))
+}
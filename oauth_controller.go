@@ -0,0 +1,624 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+const accessTokenLifetime = time.Hour
+
+type OAuthController struct {
+	accessTokenService   auth.TokenService[*auth.RefreshTokenPayload]
+	refreshTokenService  auth.TokenService[*auth.RefreshTokenPayload]
+	sessionTokenService  auth.TokenService[*auth.RefreshTokenPayload]
+	authorizationService *oauth.AuthorizationService
+	clientAuthenticator  *oauth.ClientAuthenticator
+	idTokenService       auth.TokenService[*oidc.IdTokenPayload]
+	deviceService        *oauth.DeviceAuthorizationService
+	tokenExchangeService *oauth.TokenExchangeService
+	refreshService       *oauth.RefreshService
+	auditRepo            audit.AuditRepository
+}
+
+func (controller *OAuthController) Init(
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	authorizationService *oauth.AuthorizationService,
+	clientAuthenticator *oauth.ClientAuthenticator,
+	idTokenService auth.TokenService[*oidc.IdTokenPayload],
+	deviceService *oauth.DeviceAuthorizationService,
+	tokenExchangeService *oauth.TokenExchangeService,
+	refreshService *oauth.RefreshService,
+	auditRepo audit.AuditRepository,
+) {
+	controller.accessTokenService = accessTokenService
+	controller.refreshTokenService = refreshTokenService
+	controller.sessionTokenService = sessionTokenService
+	controller.authorizationService = authorizationService
+	controller.clientAuthenticator = clientAuthenticator
+	controller.idTokenService = idTokenService
+	controller.deviceService = deviceService
+	controller.tokenExchangeService = tokenExchangeService
+	controller.refreshService = refreshService
+	controller.auditRepo = auditRepo
+}
+
+func (controller *OAuthController) Authorize(c *gin.Context) {
+	request := authorizationRequest(c)
+
+	_, err := controller.authorizationService.Client(c.Request.Context(), request.ClientId, request.RedirectURI)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	code, err := controller.authorizationService.Authorize(c.Request.Context(), request, payload)
+	if errors.Is(err, oauth.ErrConsentRequired) && !request.HasPrompt(oauth.NonePrompt) {
+		controller.requestConsent(c, request, payload)
+		return
+	}
+	if err != nil {
+		redirectOAuthError(c, request.RedirectURI, c.Query("state"), err)
+		return
+	}
+
+	redirect(c, request.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {c.Query("state")},
+	})
+}
+
+func (controller *OAuthController) requestConsent(c *gin.Context, request *oauth.AuthorizationRequest, payload *auth.RefreshTokenPayload) {
+	client, consent, err := controller.authorizationService.PendingConsent(c.Request.Context(), request, payload)
+	if err != nil {
+		redirectOAuthError(c, request.RedirectURI, c.Query("state"), err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"clientId":     client.Identifier,
+		"clientName":   client.Name,
+		"scope":        request.Scope,
+		"grantedScope": consent.Scope,
+	})
+}
+
+func (controller *OAuthController) Consent(c *gin.Context) {
+	request := authorizationRequest(c)
+
+	_, err := controller.authorizationService.Client(c.Request.Context(), request.ClientId, request.RedirectURI)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	var body struct {
+		Approve bool `json:"approve"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondOAuthError(c, oauth.ErrInvalidRequest)
+		return
+	}
+
+	if !body.Approve {
+		redirectOAuthError(c, request.RedirectURI, c.Query("state"), oauth.ErrAccessDenied)
+		return
+	}
+
+	code, err := controller.authorizationService.Consent(c.Request.Context(), request, payload)
+	if err != nil {
+		redirectOAuthError(c, request.RedirectURI, c.Query("state"), err)
+		return
+	}
+
+	redirect(c, request.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {c.Query("state")},
+	})
+}
+
+func (controller *OAuthController) DeviceAuthorization(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, shouldReturn := authenticateClient(c, controller.clientAuthenticator)
+	if shouldReturn {
+		return
+	}
+
+	authorization, err := controller.deviceService.Begin(c.Request.Context(), client, strings.Fields(c.PostForm("scope")))
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	verificationURI := controller.deviceService.VerificationURI()
+	c.JSON(http.StatusOK, gin.H{
+		"device_code":               authorization.DeviceCode,
+		"user_code":                 authorization.UserCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?" + url.Values{"user_code": {authorization.UserCode}}.Encode(),
+		"expires_in":                int64(time.Until(authorization.ExpiresAt).Round(time.Second) / time.Second),
+		"interval":                  int64(authorization.Interval / time.Second),
+	})
+}
+
+func (controller *OAuthController) DeviceVerification(c *gin.Context) {
+	session, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	authorization, client, err := controller.deviceService.Lookup(c.Request.Context(), c.Query("user_code"), session)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userCode":   authorization.UserCode,
+		"clientId":   client.Identifier,
+		"clientName": client.Name,
+		"scope":      authorization.Scope,
+	})
+}
+
+func (controller *OAuthController) ApproveDevice(c *gin.Context) {
+	controller.verifyDevice(c, func(ctx context.Context, userCode string, session *auth.RefreshTokenPayload) error {
+		return controller.deviceService.Approve(ctx, userCode, session)
+	})
+}
+
+func (controller *OAuthController) DenyDevice(c *gin.Context) {
+	controller.verifyDevice(c, func(ctx context.Context, userCode string, session *auth.RefreshTokenPayload) error {
+		return controller.deviceService.Deny(ctx, userCode, session)
+	})
+}
+
+func (controller *OAuthController) verifyDevice(c *gin.Context, verify func(ctx context.Context, userCode string, session *auth.RefreshTokenPayload) error) {
+	session, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	var request struct {
+		UserCode string `json:"userCode"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.UserCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "userCode required",
+		})
+		return
+	}
+
+	if err := verify(c.Request.Context(), request.UserCode, session); err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (controller *OAuthController) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, shouldReturn := authenticateClient(c, controller.clientAuthenticator)
+	if shouldReturn {
+		return
+	}
+
+	request := &oauth.TokenRequest{
+		GrantType:          c.PostForm("grant_type"),
+		Code:               c.PostForm("code"),
+		RedirectURI:        c.PostForm("redirect_uri"),
+		ClientId:           client.Identifier,
+		CodeVerifier:       c.PostForm("code_verifier"),
+		RefreshToken:       c.PostForm("refresh_token"),
+		Scope:              strings.Fields(c.PostForm("scope")),
+		SubjectToken:       c.PostForm("subject_token"),
+		SubjectTokenType:   c.PostForm("subject_token_type"),
+		ActorToken:         c.PostForm("actor_token"),
+		ActorTokenType:     c.PostForm("actor_token_type"),
+		RequestedTokenType: c.PostForm("requested_token_type"),
+	}
+
+	switch request.GrantType {
+	case oauth.AuthorizationCodeGrantType:
+		controller.exchangeCode(c, client, request)
+	case oauth.RefreshTokenGrantType:
+		controller.refresh(c, client, request)
+	case oauth.ClientCredentialsGrantType:
+		controller.issueClientToken(c, client, request)
+	case oauth.DeviceCodeGrantType:
+		controller.exchangeDeviceCode(c, client)
+	case oauth.TokenExchangeGrantType:
+		controller.exchangeToken(c, client, request)
+	default:
+		respondOAuthError(c, &oauth.Error{Code: oauth.ErrUnsupportedGrantType.Code, Description: "grant_type is not supported"})
+	}
+}
+
+func (controller *OAuthController) exchangeCode(c *gin.Context, client *oauth.Client, request *oauth.TokenRequest) {
+	code, err := controller.authorizationService.Exchange(c.Request.Context(), request)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	controller.issueUserTokens(c, client, &auth.RefreshTokenPayload{
+		Sid:      code.Sid,
+		Sub:      code.Sub,
+		Amr:      code.Amr,
+		Scope:    code.Scope,
+		Gid:      code.GrantId,
+		AuthTime: code.AuthTime,
+	}, code.Nonce)
+}
+
+func (controller *OAuthController) exchangeDeviceCode(c *gin.Context, client *oauth.Client) {
+	authorization, err := controller.deviceService.Poll(c.Request.Context(), client, c.PostForm("device_code"))
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	controller.issueUserTokens(c, client, &auth.RefreshTokenPayload{
+		Sid:      authorization.Sid,
+		Sub:      authorization.Sub,
+		Amr:      authorization.Amr,
+		Scope:    authorization.Scope,
+		Gid:      authorization.GrantId,
+		AuthTime: authorization.AuthTime,
+	}, "")
+}
+
+func (controller *OAuthController) refresh(c *gin.Context, client *oauth.Client, request *oauth.TokenRequest) {
+	payload, err := controller.refreshService.Refresh(c.Request.Context(), client, request)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	lifetime := accessTokenLifetimeOf(client)
+	payload.Lifetime = lifetime
+
+	accessToken, err := controller.accessTokenService.Create(c.Request.Context(), payload)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(lifetime / time.Second),
+	}
+
+	if len(payload.Scope) > 0 {
+		response["scope"] = strings.Join(payload.Scope, " ")
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (controller *OAuthController) exchangeToken(c *gin.Context, client *oauth.Client, request *oauth.TokenRequest) {
+	exchange, err := controller.tokenExchangeService.Exchange(c.Request.Context(), client, request)
+	if controller.recordExchange(c, client, exchange, err) {
+		return
+	}
+
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	lifetime := accessTokenLifetimeOf(client)
+	if remaining := time.Until(time.Unix(exchange.Payload.Exp, 0)); remaining < lifetime {
+		lifetime = remaining
+	}
+	exchange.Payload.Lifetime = lifetime
+
+	accessToken, err := controller.accessTokenService.Create(c.Request.Context(), exchange.Payload)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"access_token":      accessToken,
+		"issued_token_type": oauth.AccessTokenType,
+		"token_type":        "Bearer",
+		"expires_in":        int64(lifetime / time.Second),
+	}
+
+	if len(exchange.Payload.Scope) > 0 {
+		response["scope"] = strings.Join(exchange.Payload.Scope, " ")
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (controller *OAuthController) recordExchange(c *gin.Context, client *oauth.Client, exchange *oauth.TokenExchange, err error) bool {
+	event := &audit.Event{
+		Actor:   client.Identifier,
+		Action:  "token.exchange",
+		Outcome: audit.Success,
+		Detail:  "client " + client.Identifier,
+	}
+
+	if exchange != nil {
+		event.Target = exchange.Subject.Sub
+		if exchange.Impersonation() {
+			event.Actor = exchange.Actor.Sub
+			event.Action = "token.impersonate"
+		}
+	}
+
+	if err != nil {
+		event.Outcome = audit.Failure
+		event.Detail += ": " + err.Error()
+	} else if len(exchange.Payload.Scope) > 0 {
+		event.Detail += " scope " + strings.Join(exchange.Payload.Scope, " ")
+	}
+
+	if err := controller.auditRepo.Record(c.Request.Context(), event); err != nil {
+		respondOAuthError(c, err)
+		return true
+	}
+	return false
+}
+
+func (controller *OAuthController) recordPrivilegedGrant(c *gin.Context, client *oauth.Client, scope []string, err error) bool {
+	event := &audit.Event{
+		Actor:   client.Identifier,
+		Action:  "token.privileged",
+		Target:  client.Identifier,
+		Outcome: audit.Success,
+		Detail:  "scope " + strings.Join(scope, " "),
+	}
+
+	if err != nil {
+		event.Outcome = audit.Failure
+		event.Detail += ": " + err.Error()
+	}
+
+	if err := controller.auditRepo.Record(c.Request.Context(), event); err != nil {
+		respondOAuthError(c, err)
+		return true
+	}
+	return false
+}
+
+func (controller *OAuthController) issueUserTokens(c *gin.Context, client *oauth.Client, payload *auth.RefreshTokenPayload, nonce string) {
+	lifetime := accessTokenLifetimeOf(client)
+	payload.ClientId = client.Identifier
+	payload.Lifetime = lifetime
+
+	accessToken, err := controller.accessTokenService.Create(c.Request.Context(), payload)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(lifetime / time.Second),
+	}
+
+	if client.AllowsGrant(oauth.RefreshTokenGrantType) {
+		payload.Lifetime = client.RefreshTokenLifetime
+		refreshToken, err := controller.refreshTokenService.Create(c.Request.Context(), payload)
+		if err != nil {
+			respondOAuthError(c, err)
+			return
+		}
+		response["refresh_token"] = refreshToken
+	}
+
+	if len(payload.Scope) > 0 {
+		response["scope"] = strings.Join(payload.Scope, " ")
+	}
+
+	if oidc.HasScope(payload.Scope, oidc.OpenIdScope) {
+		idToken, err := controller.idTokenService.Create(c.Request.Context(), &oidc.IdTokenPayload{
+			Sub:      payload.Sub,
+			Aud:      client.Identifier,
+			Nonce:    nonce,
+			AuthTime: payload.AuthTime,
+			Amr:      payload.Amr,
+			Alg:      client.IdTokenSigningAlg,
+		})
+		if err != nil {
+			respondOAuthError(c, err)
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (controller *OAuthController) issueClientToken(c *gin.Context, client *oauth.Client, request *oauth.TokenRequest) {
+	scope, err := controller.authorizationService.ClientCredentials(client, request)
+	if oauth.ContainsPrivileged(request.Scope) && controller.recordPrivilegedGrant(c, client, request.Scope, err) {
+		return
+	}
+
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	lifetime := accessTokenLifetimeOf(client)
+	accessToken, err := controller.accessTokenService.Create(c.Request.Context(), &auth.RefreshTokenPayload{
+		Sub:      client.Identifier,
+		ClientId: client.Identifier,
+		Scope:    scope,
+		Lifetime: lifetime,
+	})
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(lifetime / time.Second),
+	}
+
+	if len(scope) > 0 {
+		response["scope"] = strings.Join(scope, " ")
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func authorizationRequest(c *gin.Context) *oauth.AuthorizationRequest {
+	return &oauth.AuthorizationRequest{
+		ResponseType:        c.Query("response_type"),
+		ClientId:            c.Query("client_id"),
+		RedirectURI:         c.Query("redirect_uri"),
+		Scope:               strings.Fields(c.Query("scope")),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+		Nonce:               c.Query("nonce"),
+		Prompt:              strings.Fields(c.Query("prompt")),
+	}
+}
+
+func authenticateClient(c *gin.Context, clientAuthenticator *oauth.ClientAuthenticator) (*oauth.Client, bool) {
+	credentials := clientAuthentication(c)
+	client, err := clientAuthenticator.Authenticate(c.Request.Context(), credentials)
+	if err != nil {
+		if credentials.Method == oauth.ClientSecretBasicAuthMethod {
+			c.Header("WWW-Authenticate", `Basic realm="go-id"`)
+		}
+		respondOAuthError(c, err)
+		return nil, true
+	}
+	return client, false
+}
+
+func accessTokenLifetimeOf(client *oauth.Client) time.Duration {
+	if client.AccessTokenLifetime > 0 {
+		return client.AccessTokenLifetime
+	}
+	return accessTokenLifetime
+}
+
+func clientAuthentication(c *gin.Context) *oauth.ClientAuthentication {
+	if clientId, clientSecret, ok := c.Request.BasicAuth(); ok {
+		if unescaped, err := url.QueryUnescape(clientId); err == nil {
+			clientId = unescaped
+		}
+
+		if unescaped, err := url.QueryUnescape(clientSecret); err == nil {
+			clientSecret = unescaped
+		}
+
+		return &oauth.ClientAuthentication{
+			Method:       oauth.ClientSecretBasicAuthMethod,
+			ClientId:     clientId,
+			ClientSecret: clientSecret,
+		}
+	}
+
+	credentials := &oauth.ClientAuthentication{
+		Method:   oauth.NoneAuthMethod,
+		ClientId: c.PostForm("client_id"),
+	}
+
+	if assertion := c.PostForm("client_assertion"); assertion != "" {
+		credentials.Method = oauth.PrivateKeyJwtAuthMethod
+		credentials.AssertionType = c.PostForm("client_assertion_type")
+		credentials.Assertion = assertion
+	} else if clientSecret := c.PostForm("client_secret"); clientSecret != "" {
+		credentials.Method = oauth.ClientSecretPostAuthMethod
+		credentials.ClientSecret = clientSecret
+	}
+	return credentials
+}
+
+func respondOAuthError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	oauthErr := new(oauth.Error)
+
+	if !errors.As(err, &oauthErr) {
+		status = http.StatusInternalServerError
+		oauthErr = &oauth.Error{Code: "server_error"}
+	}
+
+	if errors.Is(oauthErr, oauth.ErrInvalidClient) {
+		status = http.StatusUnauthorized
+	}
+
+	response := gin.H{
+		"error": oauthErr.Code,
+	}
+
+	if oauthErr.Description != "" {
+		response["error_description"] = oauthErr.Description
+	}
+
+	c.JSON(status, response)
+}
+
+func redirectOAuthError(c *gin.Context, redirectURI string, state string, err error) {
+	oauthErr := new(oauth.Error)
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &oauth.Error{Code: "server_error"}
+	}
+
+	params := url.Values{
+		"error": {oauthErr.Code},
+		"state": {state},
+	}
+
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+
+	redirect(c, redirectURI, params)
+}
+
+func redirect(c *gin.Context, redirectURI string, params url.Values) {
+	location, err := url.Parse(redirectURI)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	query := location.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	location.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, location.String())
+}
@@ -0,0 +1,942 @@
+package main_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	oauthRedirectURI   = "https://app.example.com/callback"
+	oauthCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	oauthCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+type OAuthControllerSuite struct {
+	suite.Suite
+
+	accessTokenService  auth.TokenService[*auth.RefreshTokenPayload]
+	refreshTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	idTokenService      *oidc.IdTokenService
+	clientRepo          oauth.ClientRepository
+	consentRepo         oauth.ConsentRepository
+	auditRepo           audit.AuditRepository
+	controller          *OAuthController
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Scope            string `json:"scope"`
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (suite *OAuthControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	signingService := new(jwt.JwtService[secret.KeyPair])
+	signingService.Init(jwt.RS256, secret.NewSecretPair(oidcKeyPair))
+	accessTokenService := new(auth.AccessTokenService)
+	accessTokenService.Init(signingService)
+	suite.accessTokenService = accessTokenService
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	refreshTokenService := new(auth.RefreshTokenService)
+	refreshTokenService.Init(jwtService)
+	suite.refreshTokenService = refreshTokenService
+
+	userRepo := new(user.MemoryUserRepository)
+	userRepo.Create(context.Background(), &user.User{Identifier: "user", Status: user.Active})
+	userRepo.Create(context.Background(), &user.User{Identifier: "support", Status: user.Active})
+	sessionTokenService := new(auth.SessionTokenService)
+	sessionTokenService.Init(refreshTokenService, userRepo)
+	suite.sessionTokenService = sessionTokenService
+
+	suite.clientRepo = new(oauth.MemoryClientRepository)
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "app",
+		RedirectURIs:            []string{oauthRedirectURI},
+		GrantTypes:              []string{oauth.AuthorizationCodeGrantType, oauth.RefreshTokenGrantType},
+		TokenEndpointAuthMethod: oauth.NoneAuthMethod,
+		Scopes:                  []string{"openid", "profile"},
+	})
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "tv",
+		Name:                    "Living Room TV",
+		GrantTypes:              []string{oauth.DeviceCodeGrantType, oauth.RefreshTokenGrantType},
+		TokenEndpointAuthMethod: oauth.NoneAuthMethod,
+		Scopes:                  []string{"openid", "profile"},
+	})
+	suite.consentRepo = new(oauth.MemoryConsentRepository)
+	suite.grantConsent("app", "openid", "profile")
+	authorizationService := new(oauth.AuthorizationService)
+	authorizationService.Init(suite.clientRepo, new(oauth.MemoryAuthorizationCodeRepository), suite.consentRepo)
+	clientAuthenticator := new(oauth.ClientAuthenticator)
+	clientAuthenticator.Init(suite.clientRepo, []string{oidcIssuer + "/token"})
+
+	suite.idTokenService = new(oidc.IdTokenService)
+	suite.idTokenService.Init(signingService, oidcIssuer)
+
+	deviceService := new(oauth.DeviceAuthorizationService)
+	deviceService.Init(suite.clientRepo, new(oauth.MemoryDeviceAuthorizationRepository), suite.consentRepo, oidcIssuer+"/device")
+
+	introspectionService := new(oauth.IntrospectionService)
+	introspectionService.Init(accessTokenService, refreshTokenService, userRepo, suite.clientRepo, suite.consentRepo)
+	tokenExchangeService := new(oauth.TokenExchangeService)
+	tokenExchangeService.Init(introspectionService)
+	refreshService := new(oauth.RefreshService)
+	refreshService.Init(introspectionService)
+	suite.auditRepo = new(audit.MemoryAuditRepository)
+
+	suite.controller = new(OAuthController)
+	suite.controller.Init(accessTokenService, refreshTokenService, sessionTokenService, authorizationService, clientAuthenticator, suite.idTokenService, deviceService, tokenExchangeService, refreshService, suite.auditRepo)
+}
+
+func (suite *OAuthControllerSuite) session() string {
+	token, _ := suite.sessionTokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", Amr: []string{"pwd"}, AuthTime: 1700000000})
+	return string(token)
+}
+
+func (suite *OAuthControllerSuite) grantConsent(clientId string, scope ...string) {
+	suite.consentRepo.Save(context.Background(), &oauth.Consent{Sub: "user", ClientId: clientId, Scope: scope, GrantedAt: time.Now()})
+}
+
+func authorizeQuery(overrides map[string]string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"app"},
+		"redirect_uri":          {oauthRedirectURI},
+		"scope":                 {"profile"},
+		"state":                 {"xyz"},
+		"code_challenge":        {oauthCodeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	for key, value := range overrides {
+		query.Set(key, value)
+	}
+	return query.Encode()
+}
+
+func (suite *OAuthControllerSuite) authorize(query string, authenticated bool) *http.Response {
+	w, context := buildContext()
+	context.Request.Method = http.MethodGet
+	context.Request.URL = &url.URL{RawQuery: query}
+	if authenticated {
+		context.Request.Header.Set(AuthorizationHeader, "Bearer "+suite.session())
+	}
+
+	suite.controller.Authorize(context)
+
+	return w.Result()
+}
+
+func (suite *OAuthControllerSuite) consent(query string, body string) *http.Response {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{RawQuery: query}
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+suite.session())
+	context.Request.Body = io.NopCloser(strings.NewReader(body))
+
+	suite.controller.Consent(context)
+	context.Writer.WriteHeaderNow()
+
+	return w.Result()
+}
+
+func (suite *OAuthControllerSuite) token(form url.Values) (int, *tokenResponse) {
+	result, response := suite.tokenWithHeader(form, "")
+	return result.StatusCode, response
+}
+
+func (suite *OAuthControllerSuite) tokenWithHeader(form url.Values, authorization string) (*http.Response, *tokenResponse) {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{}
+	context.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if authorization != "" {
+		context.Request.Header.Set(AuthorizationHeader, authorization)
+	}
+	context.Request.Body = io.NopCloser(strings.NewReader(form.Encode()))
+
+	suite.controller.Token(context)
+
+	response := new(tokenResponse)
+	json.NewDecoder(w.Result().Body).Decode(response)
+	return w.Result(), response
+}
+
+func basicAuthorization(clientId string, clientSecret string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(clientId+":"+clientSecret))
+}
+
+func (suite *OAuthControllerSuite) createServiceClient(authMethod string) {
+	client := &oauth.Client{
+		Identifier:              "service",
+		SecretHash:              "K7gNU3sdo-OL0wNhqoVWhr3g6s1xYv72ol_pe_Unols",
+		GrantTypes:              []string{oauth.ClientCredentialsGrantType},
+		Scopes:                  []string{"reports:read", "reports:write"},
+		TokenEndpointAuthMethod: authMethod,
+	}
+
+	if authMethod == oauth.PrivateKeyJwtAuthMethod {
+		jwk, _ := jwt.PublicJwk(jwt.RS256, string(oidcKeyPair.PublicKey))
+		client.Jwks = []jwt.Jwk{*jwk}
+	}
+	suite.clientRepo.Create(context.Background(), client)
+}
+
+func clientAssertion(claims map[string]interface{}) string {
+	assertion := map[string]interface{}{
+		"iss": "service",
+		"sub": "service",
+		"aud": oidcIssuer + "/token",
+		"jti": "assertion-1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	for key, value := range claims {
+		assertion[key] = value
+	}
+
+	token, _ := jwt.CreateJwt(jwt.RS256, assertion, string(oidcKeyPair.PrivateKey))
+	return string(token)
+}
+
+func clientCredentialsForm(assertion string) url.Values {
+	return url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {oauth.JwtBearerAssertionType},
+		"client_assertion":      {assertion},
+	}
+}
+
+func (suite *OAuthControllerSuite) beginDeviceAuthorization(scope string) map[string]interface{} {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{}
+	context.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	context.Request.Body = io.NopCloser(strings.NewReader(url.Values{"client_id": {"tv"}, "scope": {scope}}.Encode()))
+
+	suite.controller.DeviceAuthorization(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var response map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&response)
+	return response
+}
+
+func (suite *OAuthControllerSuite) verifyDevice(handler func(*gin.Context), userCode string) *http.Response {
+	w, context := buildContext()
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+suite.session())
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"userCode":"` + userCode + `"}`))
+
+	handler(context)
+
+	return w.Result()
+}
+
+func deviceTokenForm(deviceCode string) url.Values {
+	return url.Values{
+		"grant_type":  {oauth.DeviceCodeGrantType},
+		"device_code": {deviceCode},
+		"client_id":   {"tv"},
+	}
+}
+
+func (suite *OAuthControllerSuite) createGatewayClient() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "gateway",
+		SecretHash:              "K7gNU3sdo-OL0wNhqoVWhr3g6s1xYv72ol_pe_Unols",
+		GrantTypes:              []string{oauth.TokenExchangeGrantType},
+		Scopes:                  []string{"profile", "email"},
+		TokenEndpointAuthMethod: oauth.ClientSecretBasicAuthMethod,
+		Trusted:                 true,
+	})
+}
+
+func (suite *OAuthControllerSuite) accessToken(payload *auth.RefreshTokenPayload) string {
+	token, _ := suite.accessTokenService.Create(context.Background(), payload)
+	return string(token)
+}
+
+func (suite *OAuthControllerSuite) refreshToken(payload *auth.RefreshTokenPayload) string {
+	token, _ := suite.refreshTokenService.Create(context.Background(), payload)
+	return string(token)
+}
+
+func tokenExchangeForm(subjectToken string, scope string) url.Values {
+	return url.Values{
+		"grant_type":         {oauth.TokenExchangeGrantType},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {oauth.AccessTokenType},
+		"scope":              {scope},
+	}
+}
+
+func (suite *OAuthControllerSuite) code() string {
+	return suite.codeFor(authorizeQuery(nil))
+}
+
+func (suite *OAuthControllerSuite) codeFor(query string) string {
+	result := suite.authorize(query, true)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	return location.Query().Get("code")
+}
+
+func tokenForm(code string) url.Values {
+	return url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"client_id":     {"app"},
+		"code_verifier": {oauthCodeVerifier},
+	}
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_RedirectWithCodeAndState() {
+	result := suite.authorize(authorizeQuery(nil), true)
+
+	assert.Equal(suite.T(), http.StatusFound, result.StatusCode)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	assert.Equal(suite.T(), "app.example.com", location.Host)
+	assert.Equal(suite.T(), "/callback", location.Path)
+	assert.NotEmpty(suite.T(), location.Query().Get("code"))
+	assert.Equal(suite.T(), "xyz", location.Query().Get("state"))
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_RedirectWithErrorWithoutPkce() {
+	result := suite.authorize(authorizeQuery(map[string]string{"code_challenge_method": "plain"}), true)
+
+	assert.Equal(suite.T(), http.StatusFound, result.StatusCode)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	assert.Equal(suite.T(), "invalid_request", location.Query().Get("error"))
+	assert.Equal(suite.T(), "xyz", location.Query().Get("state"))
+	assert.Empty(suite.T(), location.Query().Get("code"))
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_DoNotRedirectToUnregisteredUri() {
+	result := suite.authorize(authorizeQuery(map[string]string{"redirect_uri": "https://evil.example.com/callback"}), true)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, result.StatusCode)
+	assert.Empty(suite.T(), result.Header.Get("Location"))
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_FailWithoutAuthenticatedUser() {
+	result := suite.authorize(authorizeQuery(nil), false)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, result.StatusCode)
+	assert.Empty(suite.T(), result.Header.Get("Location"))
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_FailWithClientAccessToken() {
+	for _, tokenService := range []auth.TokenService[*auth.RefreshTokenPayload]{suite.accessTokenService, suite.refreshTokenService} {
+		token, _ := tokenService.Create(context.Background(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app"})
+		w, context := buildContext()
+		context.Request.Method = http.MethodGet
+		context.Request.URL = &url.URL{RawQuery: authorizeQuery(nil)}
+		context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+
+		suite.controller.Authorize(context)
+
+		assert.Contains(suite.T(), []int{http.StatusUnauthorized, http.StatusForbidden}, w.Result().StatusCode)
+		assert.Empty(suite.T(), w.Result().Header.Get("Location"))
+	}
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_RequestConsentWhenScopeExpands() {
+	suite.grantConsent("app", "profile")
+
+	result := suite.authorize(authorizeQuery(map[string]string{"scope": "openid profile"}), true)
+
+	assert.Equal(suite.T(), http.StatusAccepted, result.StatusCode)
+	assert.Empty(suite.T(), result.Header.Get("Location"))
+	var response map[string]interface{}
+	json.NewDecoder(result.Body).Decode(&response)
+	assert.Equal(suite.T(), "app", response["clientId"])
+	assert.Equal(suite.T(), []interface{}{"openid", "profile"}, response["scope"])
+	assert.Equal(suite.T(), []interface{}{"profile"}, response["grantedScope"])
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_RequestConsentWhenPrompted() {
+	result := suite.authorize(authorizeQuery(map[string]string{"prompt": "consent"}), true)
+
+	assert.Equal(suite.T(), http.StatusAccepted, result.StatusCode)
+}
+
+func (suite *OAuthControllerSuite) TestAuthorize_RedirectWithConsentRequiredForPromptNone() {
+	suite.grantConsent("app", "profile")
+
+	result := suite.authorize(authorizeQuery(map[string]string{"scope": "openid profile", "prompt": "none"}), true)
+
+	assert.Equal(suite.T(), http.StatusFound, result.StatusCode)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	assert.Equal(suite.T(), "consent_required", location.Query().Get("error"))
+	assert.Equal(suite.T(), "xyz", location.Query().Get("state"))
+}
+
+func (suite *OAuthControllerSuite) TestConsent_RedirectWithCodeWhenApproved() {
+	suite.grantConsent("app", "profile")
+	query := authorizeQuery(map[string]string{"scope": "openid profile"})
+
+	result := suite.consent(query, `{"approve":true}`)
+
+	assert.Equal(suite.T(), http.StatusFound, result.StatusCode)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	assert.NotEmpty(suite.T(), location.Query().Get("code"))
+	assert.Equal(suite.T(), "xyz", location.Query().Get("state"))
+	assert.Equal(suite.T(), http.StatusFound, suite.authorize(query, true).StatusCode)
+}
+
+func (suite *OAuthControllerSuite) TestConsent_RedirectWithAccessDeniedWhenDenied() {
+	suite.grantConsent("app", "profile")
+	query := authorizeQuery(map[string]string{"scope": "openid profile"})
+
+	result := suite.consent(query, `{"approve":false}`)
+
+	assert.Equal(suite.T(), http.StatusFound, result.StatusCode)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	assert.Equal(suite.T(), "access_denied", location.Query().Get("error"))
+	assert.Empty(suite.T(), location.Query().Get("code"))
+	assert.Equal(suite.T(), http.StatusAccepted, suite.authorize(query, true).StatusCode)
+}
+
+func (suite *OAuthControllerSuite) TestToken_IssueTokensForCode() {
+	status, response := suite.token(tokenForm(suite.code()))
+
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), "Bearer", response.TokenType)
+	assert.Equal(suite.T(), 3600, response.ExpiresIn)
+	assert.Equal(suite.T(), "profile", response.Scope)
+
+	payload, err := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", payload.Sub)
+	assert.Equal(suite.T(), []string{"pwd"}, payload.Amr)
+	assert.Equal(suite.T(), []string{"profile"}, payload.Scope)
+	assert.NotEmpty(suite.T(), response.RefreshToken)
+	assert.Empty(suite.T(), response.IdToken)
+}
+
+func (suite *OAuthControllerSuite) TestToken_UseLifetimesOfClient() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "short",
+		RedirectURIs:            []string{oauthRedirectURI},
+		GrantTypes:              []string{oauth.AuthorizationCodeGrantType, oauth.RefreshTokenGrantType},
+		TokenEndpointAuthMethod: oauth.NoneAuthMethod,
+		Scopes:                  []string{"profile"},
+		AccessTokenLifetime:     5 * time.Minute,
+		RefreshTokenLifetime:    time.Hour,
+	})
+	suite.grantConsent("short", "profile")
+	form := tokenForm(suite.codeFor(authorizeQuery(map[string]string{"client_id": "short"})))
+	form.Set("client_id", "short")
+
+	status, response := suite.token(form)
+
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), 300, response.ExpiresIn)
+	payload, _ := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.InDelta(suite.T(), time.Now().Add(5*time.Minute).Unix(), payload.Exp, 2)
+	payload, _ = suite.refreshTokenService.Validate(context.Background(), jwt.Jwt(response.RefreshToken))
+	assert.InDelta(suite.T(), time.Now().Add(time.Hour).Unix(), payload.Exp, 2)
+}
+
+func (suite *OAuthControllerSuite) TestToken_AuthenticateConfidentialClient() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "confidential",
+		SecretHash:              "K7gNU3sdo-OL0wNhqoVWhr3g6s1xYv72ol_pe_Unols",
+		RedirectURIs:            []string{oauthRedirectURI},
+		GrantTypes:              []string{oauth.AuthorizationCodeGrantType},
+		Scopes:                  []string{"profile"},
+		TokenEndpointAuthMethod: oauth.ClientSecretPostAuthMethod,
+	})
+	suite.grantConsent("confidential", "profile")
+	query := authorizeQuery(map[string]string{"client_id": "confidential"})
+
+	form := tokenForm(suite.codeFor(query))
+	form.Set("client_id", "confidential")
+	status, response := suite.token(form)
+	assert.Equal(suite.T(), http.StatusUnauthorized, status)
+	assert.Equal(suite.T(), "invalid_client", response.Error)
+
+	form = tokenForm(suite.codeFor(query))
+	form.Set("client_id", "confidential")
+	form.Set("client_secret", "secret")
+	status, _ = suite.token(form)
+	assert.Equal(suite.T(), http.StatusOK, status)
+}
+
+func (suite *OAuthControllerSuite) TestToken_IssueClientCredentialsTokenWithBasicAuthentication() {
+	suite.createServiceClient(oauth.ClientSecretBasicAuthMethod)
+
+	result, response := suite.tokenWithHeader(url.Values{"grant_type": {"client_credentials"}}, basicAuthorization("service", "secret"))
+
+	assert.Equal(suite.T(), http.StatusOK, result.StatusCode)
+	assert.Equal(suite.T(), "Bearer", response.TokenType)
+	assert.Equal(suite.T(), "reports:read reports:write", response.Scope)
+	assert.Empty(suite.T(), response.RefreshToken)
+	payload, err := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "service", payload.Sub)
+	assert.Equal(suite.T(), []string{"reports:read", "reports:write"}, payload.Scope)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailClientCredentialsWithWrongSecret() {
+	suite.createServiceClient(oauth.ClientSecretBasicAuthMethod)
+
+	result, response := suite.tokenWithHeader(url.Values{"grant_type": {"client_credentials"}}, basicAuthorization("service", "wrong"))
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, result.StatusCode)
+	assert.Equal(suite.T(), "invalid_client", response.Error)
+	assert.Contains(suite.T(), result.Header.Get("WWW-Authenticate"), "Basic")
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailClientCredentialsWithOtherAuthMethod() {
+	suite.createServiceClient(oauth.ClientSecretBasicAuthMethod)
+
+	status, response := suite.token(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"service"},
+		"client_secret": {"secret"},
+	})
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, status)
+	assert.Equal(suite.T(), "invalid_client", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_RestrictClientCredentialsScope() {
+	suite.createServiceClient(oauth.ClientSecretPostAuthMethod)
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"service"},
+		"client_secret": {"secret"},
+		"scope":         {"reports:read"},
+	}
+
+	status, response := suite.token(form)
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), "reports:read", response.Scope)
+
+	form.Set("scope", "reports:read admin")
+	status, response = suite.token(form)
+	assert.Equal(suite.T(), http.StatusBadRequest, status)
+	assert.Equal(suite.T(), "invalid_scope", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_IssueAdminTokenToTrustedClient() {
+	clientService := new(oauth.ClientService)
+	clientService.Init(suite.clientRepo, []string{"RS256"})
+	operator, secret, err := clientService.RegisterTrusted(context.Background(), &oauth.ClientMetadata{
+		ClientName: "Operator",
+		GrantTypes: []string{oauth.ClientCredentialsGrantType},
+		Scope:      auth.AdminScope,
+	})
+	assert.Nil(suite.T(), err)
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	result, response := suite.tokenWithHeader(form, basicAuthorization(operator.Identifier, secret))
+	assert.Equal(suite.T(), http.StatusOK, result.StatusCode)
+	assert.Empty(suite.T(), response.Scope)
+
+	form.Set("scope", auth.AdminScope)
+	result, response = suite.tokenWithHeader(form, basicAuthorization(operator.Identifier, secret))
+	assert.Equal(suite.T(), http.StatusOK, result.StatusCode)
+	assert.Equal(suite.T(), auth.AdminScope, response.Scope)
+
+	events, _ := suite.auditRepo.FindByTarget(context.Background(), operator.Identifier)
+	assert.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), "token.privileged", events[0].Action)
+	assert.Equal(suite.T(), audit.Success, events[0].Outcome)
+
+	clientController := new(ClientController)
+	clientController.Init(suite.accessTokenService, clientService, suite.auditRepo)
+	w, context := buildContext()
+	context.Request.URL = &url.URL{}
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+response.AccessToken)
+
+	clientController.ListClients(context)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Result().StatusCode)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailClientCredentialsForPublicClient() {
+	status, response := suite.token(url.Values{"grant_type": {"client_credentials"}, "client_id": {"app"}})
+
+	assert.Equal(suite.T(), http.StatusBadRequest, status)
+	assert.Equal(suite.T(), "unauthorized_client", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_AuthenticateWithPrivateKeyJwt() {
+	suite.createServiceClient(oauth.PrivateKeyJwtAuthMethod)
+	form := clientCredentialsForm(clientAssertion(nil))
+
+	status, response := suite.token(form)
+	assert.Equal(suite.T(), http.StatusOK, status)
+	payload, _ := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Equal(suite.T(), "service", payload.Sub)
+
+	status, response = suite.token(form)
+	assert.Equal(suite.T(), http.StatusUnauthorized, status)
+	assert.Equal(suite.T(), "invalid_client", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailWithInvalidClientAssertion() {
+	suite.createServiceClient(oauth.PrivateKeyJwtAuthMethod)
+	symmetric, _ := jwt.CreateJwt(jwt.HS256, map[string]interface{}{
+		"iss": "service", "sub": "service", "aud": oidcIssuer + "/token", "jti": "forged", "exp": time.Now().Add(time.Minute).Unix(),
+	}, string(oidcKeyPair.PublicKey))
+
+	for _, assertion := range []string{
+		clientAssertion(map[string]interface{}{"aud": "https://other.example.com/token"}),
+		clientAssertion(map[string]interface{}{"iss": "app"}),
+		clientAssertion(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}),
+		clientAssertion(map[string]interface{}{"jti": ""}),
+		string(symmetric),
+	} {
+		status, response := suite.token(clientCredentialsForm(assertion))
+
+		assert.Equal(suite.T(), http.StatusUnauthorized, status)
+		assert.Equal(suite.T(), "invalid_client", response.Error)
+	}
+}
+
+func (suite *OAuthControllerSuite) TestToken_IssueIdTokenForOpenIdScope() {
+	code := suite.codeFor(authorizeQuery(map[string]string{"scope": "openid profile", "nonce": "n-0S6_WzA2Mj"}))
+
+	status, response := suite.token(tokenForm(code))
+
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), "openid profile", response.Scope)
+	payload, err := suite.idTokenService.Validate(context.Background(), jwt.Jwt(response.IdToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", payload.Sub)
+	assert.Equal(suite.T(), "app", payload.Aud)
+	assert.Equal(suite.T(), "n-0S6_WzA2Mj", payload.Nonce)
+	assert.Equal(suite.T(), []string{"pwd"}, payload.Amr)
+	assert.Equal(suite.T(), oidc.AcrSingleFactor, payload.Acr)
+	assert.NotZero(suite.T(), payload.AuthTime)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailWhenCodeIsReused() {
+	form := tokenForm(suite.code())
+	suite.token(form)
+
+	status, response := suite.token(form)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, status)
+	assert.Equal(suite.T(), "invalid_grant", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailWithWrongVerifier() {
+	form := tokenForm(suite.code())
+	form.Set("code_verifier", strings.Repeat("a", 43))
+
+	status, response := suite.token(form)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, status)
+	assert.Equal(suite.T(), "invalid_grant", response.Error)
+	assert.Empty(suite.T(), response.AccessToken)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailWithUnsupportedGrantType() {
+	form := tokenForm(suite.code())
+	form.Set("grant_type", "password")
+
+	status, response := suite.token(form)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, status)
+	assert.Equal(suite.T(), "unsupported_grant_type", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_CarryAuthTimeFromLogin() {
+	status, response := suite.token(tokenForm(suite.code()))
+
+	assert.Equal(suite.T(), http.StatusOK, status)
+	payload, _ := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Equal(suite.T(), int64(1700000000), payload.AuthTime)
+}
+
+func (suite *OAuthControllerSuite) TestToken_RefreshAccessToken() {
+	_, tokens := suite.token(tokenForm(suite.code()))
+
+	status, response := suite.token(url.Values{
+		"grant_type":    {oauth.RefreshTokenGrantType},
+		"refresh_token": {tokens.RefreshToken},
+		"client_id":     {"app"},
+	})
+
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Equal(suite.T(), "profile", response.Scope)
+	assert.Empty(suite.T(), response.RefreshToken)
+	payload, err := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", payload.Sub)
+	assert.Equal(suite.T(), "app", payload.ClientId)
+	assert.Equal(suite.T(), int64(1700000000), payload.AuthTime)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailRefreshAfterConsentRevoked() {
+	suite.consentRepo.Save(context.Background(), &oauth.Consent{Sub: "user", ClientId: "app", Scope: []string{}})
+	result := suite.consent(authorizeQuery(nil), `{"approve":true}`)
+	location, _ := url.Parse(result.Header.Get("Location"))
+	_, tokens := suite.token(tokenForm(location.Query().Get("code")))
+
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "app")
+	payload, _ := suite.refreshTokenService.Validate(context.Background(), jwt.Jwt(tokens.RefreshToken))
+	assert.NotEmpty(suite.T(), consent.GrantId)
+	assert.Equal(suite.T(), consent.GrantId, payload.Gid)
+
+	consentService := new(oauth.ConsentService)
+	consentService.Init(suite.consentRepo)
+	assert.Nil(suite.T(), consentService.Revoke(context.Background(), "user", "app"))
+	suite.consent(authorizeQuery(nil), `{"approve":true}`)
+
+	status, response := suite.token(url.Values{
+		"grant_type":    {oauth.RefreshTokenGrantType},
+		"refresh_token": {tokens.RefreshToken},
+		"client_id":     {"app"},
+	})
+
+	assert.Equal(suite.T(), http.StatusBadRequest, status)
+	assert.Equal(suite.T(), "invalid_grant", response.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailRefreshWithTokenOfOtherClient() {
+	for _, refreshToken := range []string{
+		suite.session(),
+		suite.refreshToken(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "tv", Scope: []string{"profile"}}),
+	} {
+		status, response := suite.token(url.Values{
+			"grant_type":    {oauth.RefreshTokenGrantType},
+			"refresh_token": {refreshToken},
+			"client_id":     {"app"},
+		})
+
+		assert.Equal(suite.T(), http.StatusBadRequest, status)
+		assert.Equal(suite.T(), "invalid_grant", response.Error)
+	}
+}
+
+func (suite *OAuthControllerSuite) TestToken_OmitRefreshTokenWithoutRefreshGrant() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "noRefresh",
+		RedirectURIs:            []string{oauthRedirectURI},
+		GrantTypes:              []string{oauth.AuthorizationCodeGrantType},
+		TokenEndpointAuthMethod: oauth.NoneAuthMethod,
+		Scopes:                  []string{"profile"},
+	})
+	suite.grantConsent("noRefresh", "profile")
+	form := tokenForm(suite.codeFor(authorizeQuery(map[string]string{"client_id": "noRefresh"})))
+	form.Set("client_id", "noRefresh")
+
+	status, response := suite.token(form)
+
+	assert.Equal(suite.T(), http.StatusOK, status)
+	assert.Empty(suite.T(), response.RefreshToken)
+}
+
+func (suite *OAuthControllerSuite) TestDeviceAuthorization_IssueCodes() {
+	response := suite.beginDeviceAuthorization("profile")
+
+	assert.NotEmpty(suite.T(), response["device_code"])
+	assert.Regexp(suite.T(), `^[A-Z]{4}-[A-Z]{4}$`, response["user_code"])
+	assert.Equal(suite.T(), oidcIssuer+"/device", response["verification_uri"])
+	assert.Equal(suite.T(), oidcIssuer+"/device?user_code="+response["user_code"].(string), response["verification_uri_complete"])
+	assert.Equal(suite.T(), float64(600), response["expires_in"])
+	assert.Equal(suite.T(), float64(5), response["interval"])
+}
+
+func (suite *OAuthControllerSuite) TestDeviceAuthorization_FailForClientWithoutGrant() {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{}
+	context.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	context.Request.Body = io.NopCloser(strings.NewReader(url.Values{"client_id": {"app"}}.Encode()))
+
+	suite.controller.DeviceAuthorization(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "unauthorized_client")
+}
+
+func (suite *OAuthControllerSuite) TestDeviceVerification_ShowClientAndScope() {
+	response := suite.beginDeviceAuthorization("profile")
+	userCode := strings.ToLower(strings.ReplaceAll(response["user_code"].(string), "-", ""))
+
+	w, context := buildContext()
+	context.Request.URL = &url.URL{RawQuery: url.Values{"user_code": {userCode}}.Encode()}
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+suite.session())
+
+	suite.controller.DeviceVerification(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), `"clientName":"Living Room TV"`)
+	assert.Contains(suite.T(), string(body), `"scope":["profile"]`)
+}
+
+func (suite *OAuthControllerSuite) TestToken_PollDeviceCodeUntilApproved() {
+	response := suite.beginDeviceAuthorization("openid profile")
+	deviceCode := response["device_code"].(string)
+
+	status, pending := suite.token(deviceTokenForm(deviceCode))
+	assert.Equal(suite.T(), 400, status)
+	assert.Equal(suite.T(), "authorization_pending", pending.Error)
+
+	status, slowDown := suite.token(deviceTokenForm(deviceCode))
+	assert.Equal(suite.T(), 400, status)
+	assert.Equal(suite.T(), "slow_down", slowDown.Error)
+
+	result := suite.verifyDevice(suite.controller.ApproveDevice, response["user_code"].(string))
+	assert.Equal(suite.T(), http.StatusNoContent, result.StatusCode)
+
+	status, tokens := suite.token(deviceTokenForm(deviceCode))
+	assert.Equal(suite.T(), 200, status)
+	assert.Equal(suite.T(), "openid profile", tokens.Scope)
+	assert.NotEmpty(suite.T(), tokens.RefreshToken)
+	payload, _ := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(tokens.AccessToken))
+	assert.Equal(suite.T(), "user", payload.Sub)
+	consent, _ := suite.consentRepo.Find(context.Background(), "user", "tv")
+	assert.NotEmpty(suite.T(), payload.Gid)
+	assert.Equal(suite.T(), consent.GrantId, payload.Gid)
+	idToken, err := suite.idTokenService.Validate(context.Background(), jwt.Jwt(tokens.IdToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "tv", idToken.Aud)
+
+	status, reused := suite.token(deviceTokenForm(deviceCode))
+	assert.Equal(suite.T(), 400, status)
+	assert.Equal(suite.T(), "invalid_grant", reused.Error)
+}
+
+func (suite *OAuthControllerSuite) TestToken_FailDeviceCodeWhenDenied() {
+	response := suite.beginDeviceAuthorization("profile")
+
+	result := suite.verifyDevice(suite.controller.DenyDevice, response["user_code"].(string))
+	assert.Equal(suite.T(), http.StatusNoContent, result.StatusCode)
+
+	status, denied := suite.token(deviceTokenForm(response["device_code"].(string)))
+	assert.Equal(suite.T(), 400, status)
+	assert.Equal(suite.T(), "access_denied", denied.Error)
+}
+
+func (suite *OAuthControllerSuite) TestApproveDevice_FailWithUnknownUserCode() {
+	result := suite.verifyDevice(suite.controller.ApproveDevice, "BCDF-GHJK")
+
+	assert.Equal(suite.T(), 400, result.StatusCode)
+	body, _ := io.ReadAll(result.Body)
+	assert.Contains(suite.T(), string(body), "invalid_grant")
+}
+
+func (suite *OAuthControllerSuite) TestToken_ExchangeForNarrowerToken() {
+	suite.createGatewayClient()
+	subjectToken := suite.accessToken(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Scope: []string{"profile", "email"}})
+
+	result, response := suite.tokenWithHeader(tokenExchangeForm(subjectToken, "profile"), basicAuthorization("gateway", "secret"))
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	assert.Equal(suite.T(), "profile", response.Scope)
+	assert.Empty(suite.T(), response.RefreshToken)
+	payload, err := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", payload.Sub)
+	assert.Equal(suite.T(), "gateway", payload.ClientId)
+	assert.Equal(suite.T(), []string{"profile"}, payload.Scope)
+
+	events, _ := suite.auditRepo.FindByTarget(context.Background(), "user")
+	assert.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), "gateway", events[0].Actor)
+	assert.Equal(suite.T(), "token.exchange", events[0].Action)
+	assert.Equal(suite.T(), audit.Success, events[0].Outcome)
+}
+
+func (suite *OAuthControllerSuite) TestToken_ImpersonateUserWithActorToken() {
+	clientService := new(oauth.ClientService)
+	clientService.Init(suite.clientRepo, []string{"RS256"})
+	gateway, secret, err := clientService.RegisterTrusted(context.Background(), &oauth.ClientMetadata{
+		ClientName: "Support Gateway",
+		GrantTypes: []string{oauth.ClientCredentialsGrantType, oauth.TokenExchangeGrantType},
+		Scope:      "profile " + auth.ImpersonateScope,
+	})
+	assert.Nil(suite.T(), err)
+
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {auth.ImpersonateScope}}
+	result, response := suite.tokenWithHeader(form, basicAuthorization(gateway.Identifier, secret))
+	assert.Equal(suite.T(), 200, result.StatusCode)
+
+	form = tokenExchangeForm(suite.accessToken(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: []string{"profile"}}), "")
+	form.Set("actor_token", response.AccessToken)
+	form.Set("actor_token_type", oauth.AccessTokenType)
+
+	result, response = suite.tokenWithHeader(form, basicAuthorization(gateway.Identifier, secret))
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	payload, _ := suite.accessTokenService.Validate(context.Background(), jwt.Jwt(response.AccessToken))
+	assert.Equal(suite.T(), "user", payload.Sub)
+	assert.Equal(suite.T(), &auth.Actor{Sub: gateway.Identifier, ClientId: gateway.Identifier}, payload.Act)
+
+	events, _ := suite.auditRepo.FindByTarget(context.Background(), "user")
+	assert.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), gateway.Identifier, events[0].Actor)
+	assert.Equal(suite.T(), "token.impersonate", events[0].Action)
+}
+
+func (suite *OAuthControllerSuite) TestToken_AuditRejectedImpersonation() {
+	suite.createGatewayClient()
+	form := tokenExchangeForm(suite.accessToken(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: []string{"profile"}}), "")
+	form.Set("actor_token", suite.accessToken(&auth.RefreshTokenPayload{Sub: "gateway", ClientId: "gateway", Scope: []string{"profile"}}))
+	form.Set("actor_token_type", oauth.AccessTokenType)
+
+	result, response := suite.tokenWithHeader(form, basicAuthorization("gateway", "secret"))
+
+	assert.Equal(suite.T(), 400, result.StatusCode)
+	assert.Equal(suite.T(), "invalid_grant", response.Error)
+	events, _ := suite.auditRepo.FindByTarget(context.Background(), "user")
+	assert.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), "gateway", events[0].Actor)
+	assert.Equal(suite.T(), audit.Failure, events[0].Outcome)
+}
+
+func (suite *OAuthControllerSuite) TestToken_ErrorWhenExchangingTokenOfAnotherClient() {
+	suite.clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "gateway",
+		SecretHash:              "K7gNU3sdo-OL0wNhqoVWhr3g6s1xYv72ol_pe_Unols",
+		GrantTypes:              []string{oauth.TokenExchangeGrantType},
+		Scopes:                  []string{"profile", "email"},
+		TokenEndpointAuthMethod: oauth.ClientSecretBasicAuthMethod,
+	})
+	subjectToken := suite.accessToken(&auth.RefreshTokenPayload{Sid: "123", Sub: "user", ClientId: "app", Scope: []string{"profile", "email"}})
+
+	result, response := suite.tokenWithHeader(tokenExchangeForm(subjectToken, "profile"), basicAuthorization("gateway", "secret"))
+
+	assert.Equal(suite.T(), 400, result.StatusCode)
+	assert.Equal(suite.T(), "invalid_grant", response.Error)
+	assert.Empty(suite.T(), response.AccessToken)
+}
+
+func TestOAuthController(t *testing.T) {
+	suite.Run(t, new(OAuthControllerSuite))
+}
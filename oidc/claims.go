@@ -0,0 +1,62 @@
+package oidc
+
+import "github.com/Untanky/go-id/user"
+
+const (
+	OpenIdScope  = "openid"
+	ProfileScope = "profile"
+	EmailScope   = "email"
+	PhoneScope   = "phone"
+)
+
+var (
+	Scopes = []string{OpenIdScope, ProfileScope, EmailScope, PhoneScope}
+	Claims = []string{
+		"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "acr", "amr",
+		"name", "locale", "zoneinfo", "updated_at",
+		"email", "email_verified",
+		"phone_number", "phone_number_verified",
+	}
+)
+
+func UserInfo(found *user.User, scope []string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": found.Identifier,
+	}
+
+	if HasScope(scope, ProfileScope) {
+		if found.DisplayName != "" {
+			claims["name"] = found.DisplayName
+		}
+		if found.Locale != "" {
+			claims["locale"] = found.Locale
+		}
+		if found.Timezone != "" {
+			claims["zoneinfo"] = found.Timezone
+		}
+		if !found.UpdatedAt.IsZero() {
+			claims["updated_at"] = found.UpdatedAt.Unix()
+		}
+	}
+
+	if HasScope(scope, EmailScope) && found.Email != "" {
+		claims["email"] = found.Email
+		claims["email_verified"] = found.EmailVerified
+	}
+
+	if HasScope(scope, PhoneScope) && found.Phone != "" {
+		claims["phone_number"] = found.Phone
+		claims["phone_number_verified"] = found.PhoneVerified
+	}
+
+	return claims
+}
+
+func HasScope(scope []string, wanted string) bool {
+	for _, granted := range scope {
+		if granted == wanted {
+			return true
+		}
+	}
+	return false
+}
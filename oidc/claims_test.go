@@ -0,0 +1,49 @@
+package oidc_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+)
+
+var profile = &user.User{
+	Identifier:    "user",
+	Email:         "user@example.com",
+	EmailVerified: true,
+	Phone:         "+4912345",
+	DisplayName:   "User",
+	Locale:        "de-DE",
+	Timezone:      "Europe/Berlin",
+	UpdatedAt:     time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func TestUserInfo_OnlySubjectForOpenIdScope(t *testing.T) {
+	claims := UserInfo(profile, []string{OpenIdScope})
+
+	assert.Equal(t, map[string]interface{}{"sub": "user"}, claims)
+}
+
+func TestUserInfo_ClaimsPerScope(t *testing.T) {
+	claims := UserInfo(profile, []string{OpenIdScope, ProfileScope, EmailScope, PhoneScope})
+
+	assert.Equal(t, map[string]interface{}{
+		"sub":                   "user",
+		"name":                  "User",
+		"locale":                "de-DE",
+		"zoneinfo":              "Europe/Berlin",
+		"updated_at":            int64(1640995200),
+		"email":                 "user@example.com",
+		"email_verified":        true,
+		"phone_number":          "+4912345",
+		"phone_number_verified": false,
+	}, claims)
+}
+
+func TestUserInfo_OmitUnsetClaims(t *testing.T) {
+	claims := UserInfo(&user.User{Identifier: "user"}, []string{OpenIdScope, ProfileScope, EmailScope})
+
+	assert.Equal(t, map[string]interface{}{"sub": "user"}, claims)
+}
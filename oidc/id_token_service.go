@@ -0,0 +1,116 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	jwt "github.com/Untanky/go-id/jwt"
+	secret "github.com/Untanky/go-id/secret"
+)
+
+const (
+	AcrSingleFactor = "urn:go-id:acr:sfa"
+	AcrMultiFactor  = "urn:go-id:acr:mfa"
+	idTokenLifetime = time.Hour
+)
+
+type IdTokenPayload struct {
+	Iss      string
+	Sub      string
+	Aud      string
+	Nonce    string
+	AuthTime int64
+	Acr      string
+	Amr      []string
+	Alg      string
+	Iat      int64
+	Exp      int64
+}
+
+type IdTokenService struct {
+	jwtService *jwt.JwtService[secret.KeyPair]
+	issuer     string
+}
+
+func (service *IdTokenService) Init(jwtService *jwt.JwtService[secret.KeyPair], issuer string) {
+	service.jwtService = jwtService
+	service.issuer = issuer
+}
+
+func (service *IdTokenService) Create(ctx context.Context, payload *IdTokenPayload) (jwt.Jwt, error) {
+	if payload.Alg != "" && payload.Alg != service.jwtService.Method() {
+		return jwt.Jwt(""), errors.New("id token signing algorithm is not supported")
+	}
+
+	now := time.Now()
+
+	payloadMap := make(map[string]interface{})
+	payloadMap["iss"] = service.issuer
+	payloadMap["sub"] = payload.Sub
+	payloadMap["aud"] = payload.Aud
+	payloadMap["auth_time"] = payload.AuthTime
+	payloadMap["acr"] = Acr(payload.Amr)
+	payloadMap["amr"] = payload.Amr
+	payloadMap["iat"] = now.Unix()
+	payloadMap["exp"] = now.Add(idTokenLifetime).Unix()
+	if payload.Nonce != "" {
+		payloadMap["nonce"] = payload.Nonce
+	}
+
+	return service.jwtService.Create(payloadMap)
+}
+
+func (service *IdTokenService) Validate(ctx context.Context, token jwt.Jwt) (*IdTokenPayload, error) {
+	payload, err := token.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.jwtService.Validate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload["iss"] != service.issuer {
+		return nil, errors.New("id token was issued by another issuer")
+	}
+
+	sub, subOk := payload["sub"].(string)
+	aud, audOk := payload["aud"].(string)
+	authTime, authTimeOk := payload["auth_time"].(float64)
+	iat, iatOk := payload["iat"].(float64)
+	exp, expOk := payload["exp"].(float64)
+	if !subOk || !audOk || !authTimeOk || !iatOk || !expOk {
+		return nil, errors.New("id token is missing required claims")
+	}
+
+	validated := &IdTokenPayload{
+		Iss:      service.issuer,
+		Sub:      sub,
+		Aud:      aud,
+		AuthTime: int64(authTime),
+		Iat:      int64(iat),
+		Exp:      int64(exp),
+	}
+	validated.Nonce, _ = payload["nonce"].(string)
+	validated.Acr, _ = payload["acr"].(string)
+
+	if amr, ok := payload["amr"].([]interface{}); ok {
+		for _, method := range amr {
+			if value, ok := method.(string); ok {
+				validated.Amr = append(validated.Amr, value)
+			}
+		}
+	}
+	return validated, nil
+}
+
+func Acr(amr []string) string {
+	for _, method := range amr {
+		if method == "mfa" {
+			return AcrMultiFactor
+		}
+	}
+	return AcrSingleFactor
+}
@@ -0,0 +1,143 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	jwt "github.com/Untanky/go-id/jwt"
+	. "github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const issuer = "https://id.example.com"
+
+type IdTokenTestSuite struct {
+	suite.Suite
+	keyPair secret.KeyPair
+	service *IdTokenService
+}
+
+func generateKeyPair() secret.KeyPair {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	private, _ := x509.MarshalPKCS8PrivateKey(key)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+	return secret.KeyPair{
+		PrivateKey: secret.SecretString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+		PublicKey:  secret.SecretString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+	}
+}
+
+func newIdTokenService(keyPair secret.KeyPair, issuer string) *IdTokenService {
+	jwtService := new(jwt.JwtService[secret.KeyPair])
+	jwtService.Init(jwt.RS256, secret.NewSecretPair(keyPair))
+
+	service := new(IdTokenService)
+	service.Init(jwtService, issuer)
+	return service
+}
+
+func (suite *IdTokenTestSuite) SetupSuite() {
+	suite.keyPair = generateKeyPair()
+}
+
+func (suite *IdTokenTestSuite) SetupTest() {
+	suite.service = newIdTokenService(suite.keyPair, issuer)
+}
+
+func (suite *IdTokenTestSuite) TestCreate_IncludeRequiredClaims() {
+	token, err := suite.service.Create(context.Background(), &IdTokenPayload{
+		Sub:      "user",
+		Aud:      "app",
+		Nonce:    "abc",
+		AuthTime: 1700000000,
+		Amr:      []string{"pwd"},
+	})
+	assert.Nil(suite.T(), err)
+
+	assert.Nil(suite.T(), token.Validate(jwt.RS256, string(suite.keyPair.PublicKey)))
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), issuer, payload["iss"])
+	assert.Equal(suite.T(), "user", payload["sub"])
+	assert.Equal(suite.T(), "app", payload["aud"])
+	assert.Equal(suite.T(), "abc", payload["nonce"])
+	assert.Equal(suite.T(), float64(1700000000), payload["auth_time"])
+	assert.Equal(suite.T(), AcrSingleFactor, payload["acr"])
+	assert.Equal(suite.T(), []interface{}{"pwd"}, payload["amr"])
+	assert.Equal(suite.T(), float64(time.Now().Add(time.Hour).Unix()), payload["exp"])
+}
+
+func (suite *IdTokenTestSuite) TestCreate_OmitEmptyNonce() {
+	token, _ := suite.service.Create(context.Background(), &IdTokenPayload{Sub: "user", Aud: "app"})
+
+	payload, _ := token.Payload()
+	assert.NotContains(suite.T(), payload, "nonce")
+}
+
+func (suite *IdTokenTestSuite) TestValidate_RoundTrip() {
+	token, _ := suite.service.Create(context.Background(), &IdTokenPayload{
+		Sub:      "user",
+		Aud:      "app",
+		Nonce:    "abc",
+		AuthTime: 1700000000,
+		Amr:      []string{"pwd", "mfa", "otp"},
+	})
+
+	payload, err := suite.service.Validate(context.Background(), token)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), issuer, payload.Iss)
+	assert.Equal(suite.T(), "user", payload.Sub)
+	assert.Equal(suite.T(), "app", payload.Aud)
+	assert.Equal(suite.T(), "abc", payload.Nonce)
+	assert.Equal(suite.T(), int64(1700000000), payload.AuthTime)
+	assert.Equal(suite.T(), AcrMultiFactor, payload.Acr)
+	assert.Equal(suite.T(), []string{"pwd", "mfa", "otp"}, payload.Amr)
+}
+
+func (suite *IdTokenTestSuite) TestValidate_ErrorForOtherIssuer() {
+	other := newIdTokenService(suite.keyPair, "https://other.example.com")
+	token, _ := other.Create(context.Background(), &IdTokenPayload{Sub: "user", Aud: "app"})
+
+	_, err := suite.service.Validate(context.Background(), token)
+
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *IdTokenTestSuite) TestValidate_ErrorForOtherKey() {
+	other := newIdTokenService(generateKeyPair(), issuer)
+	token, _ := other.Create(context.Background(), &IdTokenPayload{Sub: "user", Aud: "app"})
+
+	_, err := suite.service.Validate(context.Background(), token)
+
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *IdTokenTestSuite) TestValidate_ErrorForMissingClaims() {
+	jwtService := new(jwt.JwtService[secret.KeyPair])
+	jwtService.Init(jwt.RS256, secret.NewSecretPair(suite.keyPair))
+	token, _ := jwtService.Create(map[string]interface{}{"iss": issuer, "sub": "user"})
+
+	payload, err := suite.service.Validate(context.Background(), token)
+
+	assert.Nil(suite.T(), payload)
+	assert.ErrorContains(suite.T(), err, "missing required claims")
+}
+
+func (suite *IdTokenTestSuite) TestCreate_ErrorForUnsupportedSigningAlg() {
+	token, err := suite.service.Create(context.Background(), &IdTokenPayload{Sub: "user", Aud: "app", Alg: "ES256"})
+
+	assert.Empty(suite.T(), token)
+	assert.ErrorContains(suite.T(), err, "signing algorithm")
+}
+
+func TestIdTokenService(t *testing.T) {
+	suite.Run(t, new(IdTokenTestSuite))
+}
@@ -0,0 +1,300 @@
+package main_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/audit"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const oidcNonce = "n-0S6_WzA2Mj"
+
+type OidcConformanceSuite struct {
+	suite.Suite
+
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	consentService      *oauth.ConsentService
+	oauthController     *OAuthController
+	oidcController      *OidcController
+}
+
+func (suite *OidcConformanceSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := new(user.MemoryUserRepository)
+	userRepo.Create(context.Background(), &user.User{
+		Identifier:    "user",
+		Status:        user.Active,
+		Email:         "user@example.com",
+		EmailVerified: true,
+		DisplayName:   "User",
+	})
+
+	signingService := new(jwt.JwtService[secret.KeyPair])
+	signingService.Init(jwt.RS256, secret.NewSecretPair(oidcKeyPair))
+	accessTokenService := new(auth.AccessTokenService)
+	accessTokenService.Init(signingService)
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	refreshTokenService := new(auth.RefreshTokenService)
+	refreshTokenService.Init(jwtService)
+	sessionTokenService := new(auth.SessionTokenService)
+	sessionTokenService.Init(refreshTokenService, userRepo)
+	suite.sessionTokenService = sessionTokenService
+
+	clientRepo := new(oauth.MemoryClientRepository)
+	clientRepo.Create(context.Background(), &oauth.Client{
+		Identifier:              "app",
+		RedirectURIs:            []string{oauthRedirectURI},
+		GrantTypes:              []string{oauth.AuthorizationCodeGrantType},
+		TokenEndpointAuthMethod: oauth.NoneAuthMethod,
+		Scopes:                  []string{"openid", "profile", "email"},
+	})
+	consentRepo := new(oauth.MemoryConsentRepository)
+	suite.consentService = new(oauth.ConsentService)
+	suite.consentService.Init(consentRepo)
+	authorizationService := new(oauth.AuthorizationService)
+	authorizationService.Init(clientRepo, new(oauth.MemoryAuthorizationCodeRepository), consentRepo)
+	clientAuthenticator := new(oauth.ClientAuthenticator)
+	clientAuthenticator.Init(clientRepo, []string{oidcIssuer + "/token"})
+
+	idTokenService := new(oidc.IdTokenService)
+	idTokenService.Init(signingService, oidcIssuer)
+
+	suite.oauthController = new(OAuthController)
+	suite.oauthController.Init(accessTokenService, refreshTokenService, sessionTokenService, authorizationService, clientAuthenticator, idTokenService, new(oauth.DeviceAuthorizationService), new(oauth.TokenExchangeService), new(oauth.RefreshService), new(audit.MemoryAuditRepository))
+	introspectionService := new(oauth.IntrospectionService)
+	introspectionService.Init(accessTokenService, refreshTokenService, userRepo, clientRepo, consentRepo)
+	activeTokenService := new(oauth.ActiveTokenService)
+	activeTokenService.Init(accessTokenService, introspectionService)
+
+	suite.oidcController = new(OidcController)
+	suite.oidcController.Init(oidcIssuer, signingService, activeTokenService, userRepo)
+}
+
+func (suite *OidcConformanceSuite) discover() *oidc.Configuration {
+	w, context := buildContext()
+	suite.oidcController.Discovery(context)
+
+	configuration := new(oidc.Configuration)
+	json.NewDecoder(w.Result().Body).Decode(configuration)
+	return configuration
+}
+
+func (suite *OidcConformanceSuite) signingKey() string {
+	w, context := buildContext()
+	suite.oidcController.Jwks(context)
+
+	var jwks struct {
+		Keys []jwt.Jwk `json:"keys"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&jwks)
+	suite.Require().Len(jwks.Keys, 1)
+
+	n, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].N)
+	e, _ := base64.RawURLEncoding.DecodeString(jwks.Keys[0].E)
+	encoded, _ := x509.MarshalPKIXPublicKey(&rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	})
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encoded}))
+}
+
+func (suite *OidcConformanceSuite) signIn(query string) *url.URL {
+	w, context := buildContext()
+	context.Request.Method = http.MethodGet
+	context.Request.URL = &url.URL{RawQuery: query}
+	token, _ := suite.sessionTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", Amr: []string{"pwd", "otp", "mfa"}, AuthTime: time.Now().Unix()})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+
+	suite.oauthController.Authorize(context)
+
+	if w.Result().StatusCode == http.StatusAccepted {
+		return suite.approveConsent(query, string(token))
+	}
+
+	suite.Require().Equal(http.StatusFound, w.Result().StatusCode)
+	location, _ := url.Parse(w.Result().Header.Get("Location"))
+	return location
+}
+
+func (suite *OidcConformanceSuite) approveConsent(query string, token string) *url.URL {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{RawQuery: query}
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+token)
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"approve":true}`))
+
+	suite.oauthController.Consent(context)
+	context.Writer.WriteHeaderNow()
+
+	suite.Require().Equal(http.StatusFound, w.Result().StatusCode)
+	location, _ := url.Parse(w.Result().Header.Get("Location"))
+	return location
+}
+
+func (suite *OidcConformanceSuite) exchange(code string) (*http.Response, *tokenResponse) {
+	w, context := buildContext()
+	context.Request.Method = http.MethodPost
+	context.Request.URL = &url.URL{}
+	context.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	context.Request.Body = io.NopCloser(strings.NewReader(tokenForm(code).Encode()))
+
+	suite.oauthController.Token(context)
+
+	response := new(tokenResponse)
+	json.NewDecoder(w.Result().Body).Decode(response)
+	return w.Result(), response
+}
+
+func (suite *OidcConformanceSuite) userInfo(accessToken string) (int, map[string]interface{}) {
+	w, context := buildContext()
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+accessToken)
+
+	suite.oidcController.UserInfo(context)
+
+	var claims map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&claims)
+	return w.Result().StatusCode, claims
+}
+
+func (suite *OidcConformanceSuite) TestDiscovery_RequiredMetadata() {
+	configuration := suite.discover()
+
+	assert.Equal(suite.T(), oidcIssuer, configuration.Issuer)
+	assert.NotEmpty(suite.T(), configuration.AuthorizationEndpoint)
+	assert.NotEmpty(suite.T(), configuration.TokenEndpoint)
+	assert.NotEmpty(suite.T(), configuration.UserinfoEndpoint)
+	assert.NotEmpty(suite.T(), configuration.JwksURI)
+	assert.Contains(suite.T(), configuration.ResponseTypesSupported, "code")
+	assert.Contains(suite.T(), configuration.SubjectTypesSupported, "public")
+	assert.Contains(suite.T(), configuration.IdTokenSigningAlgValuesSupported, "RS256")
+	assert.Contains(suite.T(), configuration.ScopesSupported, oidc.OpenIdScope)
+}
+
+func (suite *OidcConformanceSuite) TestCodeFlow_IssueValidIdToken() {
+	before := time.Now().Add(-time.Second)
+	location := suite.signIn(authorizeQuery(map[string]string{"scope": "openid profile email", "nonce": oidcNonce}))
+	assert.Equal(suite.T(), "xyz", location.Query().Get("state"))
+
+	result, response := suite.exchange(location.Query().Get("code"))
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	assert.Equal(suite.T(), "no-store", result.Header.Get("Cache-Control"))
+	assert.Equal(suite.T(), "Bearer", response.TokenType)
+	assert.NotEmpty(suite.T(), response.IdToken)
+
+	token := jwt.Jwt(response.IdToken)
+	assert.Nil(suite.T(), token.Validate(jwt.RS256, suite.signingKey()))
+	header, _ := token.Header()
+	assert.Equal(suite.T(), "RS256", header.Alg)
+	accessToken := jwt.Jwt(response.AccessToken)
+	accessHeader, _ := accessToken.Header()
+	assert.Equal(suite.T(), auth.AccessTokenJwtType, accessHeader.Typ)
+
+	claims, _ := token.Payload()
+	assert.Equal(suite.T(), suite.discover().Issuer, claims["iss"])
+	assert.Equal(suite.T(), "user", claims["sub"])
+	assert.Equal(suite.T(), "app", claims["aud"])
+	assert.Equal(suite.T(), oidcNonce, claims["nonce"])
+	assert.Equal(suite.T(), oidc.AcrMultiFactor, claims["acr"])
+	assert.Equal(suite.T(), []interface{}{"pwd", "otp", "mfa"}, claims["amr"])
+	assert.Greater(suite.T(), claims["exp"], float64(time.Now().Unix()))
+	assert.GreaterOrEqual(suite.T(), claims["iat"], float64(before.Unix()))
+	assert.GreaterOrEqual(suite.T(), claims["auth_time"], float64(before.Unix()))
+	assert.LessOrEqual(suite.T(), claims["auth_time"], claims["iat"])
+}
+
+func (suite *OidcConformanceSuite) TestCodeFlow_UserInfoMatchesIdToken() {
+	location := suite.signIn(authorizeQuery(map[string]string{"scope": "openid email", "nonce": oidcNonce}))
+	_, response := suite.exchange(location.Query().Get("code"))
+	idToken := jwt.Jwt(response.IdToken)
+	claims, _ := idToken.Payload()
+
+	status, userInfo := suite.userInfo(response.AccessToken)
+
+	assert.Equal(suite.T(), 200, status)
+	assert.Equal(suite.T(), claims["sub"], userInfo["sub"])
+	assert.Equal(suite.T(), "user@example.com", userInfo["email"])
+	assert.Equal(suite.T(), true, userInfo["email_verified"])
+	assert.NotContains(suite.T(), userInfo, "name")
+}
+
+func (suite *OidcConformanceSuite) TestCodeFlow_RejectIdTokenAsBearer() {
+	location := suite.signIn(authorizeQuery(map[string]string{"scope": "openid email", "nonce": oidcNonce}))
+	_, response := suite.exchange(location.Query().Get("code"))
+
+	status, _ := suite.userInfo(response.IdToken)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, status)
+}
+
+func (suite *OidcConformanceSuite) TestCodeFlow_RejectAccessTokenAfterConsentRevoked() {
+	location := suite.signIn(authorizeQuery(map[string]string{"scope": "openid email", "nonce": oidcNonce}))
+	_, response := suite.exchange(location.Query().Get("code"))
+
+	assert.Nil(suite.T(), suite.consentService.Revoke(context.Background(), "user", "app"))
+	status, _ := suite.userInfo(response.AccessToken)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, status)
+}
+
+func (suite *OidcConformanceSuite) TestUserInfo_RejectTokenSignedWithPublishedKey() {
+	forged, _ := jwt.CreateTypedJwt(jwt.HS256, auth.AccessTokenJwtType, map[string]interface{}{
+		"sid":   "123",
+		"sub":   "user",
+		"scope": "openid email " + auth.AdminScope,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}, suite.signingKey())
+
+	status, _ := suite.userInfo(string(forged))
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, status)
+}
+
+func (suite *OidcConformanceSuite) TestCodeFlow_OmitIdTokenWithoutOpenIdScope() {
+	location := suite.signIn(authorizeQuery(map[string]string{"scope": "profile"}))
+	_, response := suite.exchange(location.Query().Get("code"))
+
+	assert.Empty(suite.T(), response.IdToken)
+	status, _ := suite.userInfo(response.AccessToken)
+	assert.Equal(suite.T(), http.StatusForbidden, status)
+}
+
+func (suite *OidcConformanceSuite) TestCodeFlow_RejectReusedCode() {
+	location := suite.signIn(authorizeQuery(map[string]string{"scope": "openid", "nonce": oidcNonce}))
+	code := location.Query().Get("code")
+	suite.exchange(code)
+
+	result, response := suite.exchange(code)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, result.StatusCode)
+	assert.Equal(suite.T(), "invalid_grant", response.Error)
+}
+
+func TestOidcConformance(t *testing.T) {
+	suite.Run(t, new(OidcConformanceSuite))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+)
+
+type OidcController struct {
+	issuer             string
+	signingService     *jwt.JwtService[secret.KeyPair]
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo           user.UserRepository
+}
+
+func (controller *OidcController) Init(
+	issuer string,
+	signingService *jwt.JwtService[secret.KeyPair],
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	userRepo user.UserRepository,
+) {
+	controller.issuer = issuer
+	controller.signingService = signingService
+	controller.accessTokenService = accessTokenService
+	controller.userRepo = userRepo
+}
+
+func (controller *OidcController) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, oidc.NewConfiguration(controller.issuer, controller.signingService.Method()))
+}
+
+func (controller *OidcController) Jwks(c *gin.Context) {
+	jwk, err := controller.signingService.PublicJwk()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "signing key could not be published",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys": []*jwt.Jwk{jwk},
+	})
+}
+
+func (controller *OidcController) UserInfo(c *gin.Context) {
+	payload, shouldReturn := authenticateBearer(c, controller.accessTokenService)
+	if shouldReturn {
+		return
+	}
+
+	if !oidc.HasScope(payload.Scope, oidc.OpenIdScope) {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		respondProblem(c, http.StatusForbidden, auth.ErrForbidden)
+		return
+	}
+
+	found, err := controller.userRepo.FindByIdentifier(c.Request.Context(), payload.Sub)
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, oidc.UserInfo(found, payload.Scope))
+}
@@ -0,0 +1,144 @@
+package main_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"testing"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oidc"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const oidcIssuer = "https://id.example.com"
+
+var oidcKeyPair = generateKeyPair()
+
+func generateKeyPair() secret.KeyPair {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	private, _ := x509.MarshalPKCS8PrivateKey(key)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+	return secret.KeyPair{
+		PrivateKey: secret.SecretString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+		PublicKey:  secret.SecretString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+	}
+}
+
+type OidcControllerSuite struct {
+	suite.Suite
+
+	accessTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	controller         *OidcController
+}
+
+func (suite *OidcControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := new(user.MemoryUserRepository)
+	userRepo.Create(context.Background(), &user.User{
+		Identifier:    "user",
+		Status:        user.Active,
+		Email:         "user@example.com",
+		EmailVerified: true,
+		DisplayName:   "User",
+	})
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	accessTokenService := new(auth.RefreshTokenService)
+	accessTokenService.Init(jwtService)
+	suite.accessTokenService = accessTokenService
+
+	signingService := new(jwt.JwtService[secret.KeyPair])
+	signingService.Init(jwt.RS256, secret.NewSecretPair(oidcKeyPair))
+
+	suite.controller = new(OidcController)
+	suite.controller.Init(oidcIssuer, signingService, accessTokenService, userRepo)
+}
+
+func (suite *OidcControllerSuite) authorize(context *gin.Context, scope ...string) {
+	token, _ := suite.accessTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: "user", Scope: scope})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *OidcControllerSuite) TestDiscovery_PublishEndpoints() {
+	w, context := buildContext()
+
+	suite.controller.Discovery(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var configuration oidc.Configuration
+	json.NewDecoder(w.Result().Body).Decode(&configuration)
+	assert.Equal(suite.T(), oidcIssuer, configuration.Issuer)
+	assert.Equal(suite.T(), oidcIssuer+"/authorize", configuration.AuthorizationEndpoint)
+	assert.Equal(suite.T(), oidcIssuer+"/token", configuration.TokenEndpoint)
+	assert.Equal(suite.T(), oidcIssuer+"/userinfo", configuration.UserinfoEndpoint)
+	assert.Equal(suite.T(), oidcIssuer+"/device_authorization", configuration.DeviceAuthorizationEndpoint)
+	assert.Equal(suite.T(), oidcIssuer+"/introspect", configuration.IntrospectionEndpoint)
+	assert.Equal(suite.T(), []string{"RS256"}, configuration.IdTokenSigningAlgValuesSupported)
+}
+
+func (suite *OidcControllerSuite) TestJwks_PublishSigningKey() {
+	w, context := buildContext()
+
+	suite.controller.Jwks(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var jwks struct {
+		Keys []jwt.Jwk `json:"keys"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&jwks)
+	assert.Len(suite.T(), jwks.Keys, 1)
+	assert.Equal(suite.T(), "RSA", jwks.Keys[0].Kty)
+	assert.Equal(suite.T(), "RS256", jwks.Keys[0].Alg)
+}
+
+func (suite *OidcControllerSuite) TestUserInfo_ReturnClaimsForScope() {
+	w, context := buildContext()
+	suite.authorize(context, oidc.OpenIdScope, oidc.EmailScope)
+
+	suite.controller.UserInfo(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var claims map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&claims)
+	assert.Equal(suite.T(), map[string]interface{}{
+		"sub":            "user",
+		"email":          "user@example.com",
+		"email_verified": true,
+	}, claims)
+}
+
+func (suite *OidcControllerSuite) TestUserInfo_ForbiddenWithoutOpenIdScope() {
+	w, context := buildContext()
+	suite.authorize(context, oidc.ProfileScope)
+
+	suite.controller.UserInfo(context)
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Result().StatusCode)
+	assert.Contains(suite.T(), w.Result().Header.Get("WWW-Authenticate"), "insufficient_scope")
+}
+
+func (suite *OidcControllerSuite) TestUserInfo_FailWithoutBearerToken() {
+	w, context := buildContext()
+
+	suite.controller.UserInfo(context)
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Result().StatusCode)
+}
+
+func TestOidcController(t *testing.T) {
+	suite.Run(t, new(OidcControllerSuite))
+}
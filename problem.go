@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/federation"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/oauth"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+type Problem struct {
+	Type       string   `json:"type"`
+	Title      string   `json:"title"`
+	Status     int      `json:"status"`
+	Detail     string   `json:"detail,omitempty"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+type problemType struct {
+	err    error
+	uri    string
+	title  string
+	status int
+}
+
+var problemTypes = []problemType{
+	{user.ErrUserNotFound, "/problems/user-not-found", "User not found", http.StatusNotFound},
+	{user.ErrUserExists, "/problems/user-exists", "User already exists", http.StatusConflict},
+	{user.ErrInvalidIdentifier, "/problems/invalid-identifier", "Identifier is invalid", http.StatusBadRequest},
+	{user.ErrConflict, "/problems/conflict", "User was modified concurrently", http.StatusConflict},
+	{user.ErrInvalidProfile, "/problems/invalid-profile", "Profile is invalid", http.StatusBadRequest},
+	{user.ErrInvalidTransition, "/problems/invalid-transition", "Invalid status transition", http.StatusConflict},
+	{user.ErrInvalidCursor, "/problems/invalid-cursor", "Cursor is invalid", http.StatusBadRequest},
+	{user.ErrIdentityLinked, "/problems/identity-linked", "Identity is already linked", http.StatusConflict},
+	{user.ErrIdentityNotFound, "/problems/identity-not-found", "Linked identity not found", http.StatusNotFound},
+	{user.ErrLastIdentity, "/problems/last-identity", "Cannot unlink the only way to sign in", http.StatusConflict},
+	{auth.ErrUnauthorized, "/problems/unauthorized", "Unauthorized", http.StatusUnauthorized},
+	{auth.ErrForbidden, "/problems/forbidden", "Forbidden", http.StatusForbidden},
+	{auth.ErrPendingVerification, "/problems/pending-verification", "User is pending verification", http.StatusForbidden},
+	{auth.ErrSuspended, "/problems/suspended", "User is suspended", http.StatusForbidden},
+	{auth.ErrLocked, "/problems/locked", "User is locked", http.StatusForbidden},
+	{auth.ErrDeleted, "/problems/deleted", "User is deleted", http.StatusForbidden},
+	{auth.ErrPasswordResetRequired, "/problems/password-reset-required", "Password reset required", http.StatusForbidden},
+	{auth.ErrSessionRevoked, "/problems/session-revoked", "Session has been revoked", http.StatusUnauthorized},
+	{auth.ErrReauthenticationRequired, "/problems/reauthentication-required", "Recent authentication required", http.StatusUnauthorized},
+	{auth.ErrPolicyViolation, "/problems/policy-violation", "Passkey violates policy", http.StatusBadRequest},
+	{oauth.ErrClientNotFound, "/problems/client-not-found", "Client not found", http.StatusNotFound},
+	{oauth.ErrClientExists, "/problems/client-exists", "Client already exists", http.StatusConflict},
+	{oauth.ErrInvalidClientMetadata, "/problems/invalid-client-metadata", "Client metadata is invalid", http.StatusBadRequest},
+	{oauth.ErrInvalidRedirectURI, "/problems/invalid-redirect-uri", "Redirect URI is invalid", http.StatusBadRequest},
+	{oauth.ErrConsentNotFound, "/problems/consent-not-found", "Consent not found", http.StatusNotFound},
+	{federation.ErrProviderNotFound, "/problems/provider-not-found", "Identity provider not found", http.StatusNotFound},
+	{federation.ErrInvalidState, "/problems/invalid-federation-state", "Federated login state is invalid", http.StatusUnauthorized},
+	{federation.ErrInvalidIdToken, "/problems/invalid-id-token", "Identity provider returned an invalid id token", http.StatusUnauthorized},
+	{federation.ErrAmbiguousEmail, "/problems/ambiguous-email", "Email belongs to multiple users", http.StatusConflict},
+	{federation.ErrUpstream, "/problems/identity-provider-unavailable", "Identity provider request failed", http.StatusBadGateway},
+	{jwt.ErrTokenExpired, "/problems/token-expired", "Token expired", http.StatusUnauthorized},
+}
+
+var verifiedCredentialErrors = []error{
+	auth.ErrPendingVerification,
+	auth.ErrSuspended,
+	auth.ErrLocked,
+	auth.ErrDeleted,
+	auth.ErrPasswordResetRequired,
+	auth.ErrPolicyViolation,
+}
+
+func newProblem(fallbackStatus int, err error) *Problem {
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(fallbackStatus),
+		Status: fallbackStatus,
+		Detail: err.Error(),
+	}
+
+	for _, candidate := range problemTypes {
+		if errors.Is(err, candidate.err) {
+			problem.Type = candidate.uri
+			problem.Title = candidate.title
+			problem.Status = candidate.status
+			break
+		}
+	}
+
+	var violation *auth.PolicyViolationError
+	if errors.As(err, &violation) {
+		problem.Violations = violation.Violations
+	}
+
+	var profileErr *user.ProfileError
+	if errors.As(err, &profileErr) {
+		problem.Violations = profileErr.Violations
+	}
+
+	return problem
+}
+
+func respondProblem(c *gin.Context, fallbackStatus int, err error) {
+	problem := newProblem(fallbackStatus, err)
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+func respondAuthFailure(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrSuspended) {
+		err = auth.ErrSuspended
+	}
+
+	for _, verified := range verifiedCredentialErrors {
+		if errors.Is(err, verified) {
+			respondProblem(c, http.StatusUnauthorized, err)
+			return
+		}
+	}
+
+	respondProblem(c, http.StatusUnauthorized, auth.ErrUnauthorized)
+}
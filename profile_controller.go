@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileController struct {
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	profileService      *user.ProfileService
+}
+
+type profileResponse struct {
+	Identifier    string                 `json:"identifier"`
+	Email         string                 `json:"email,omitempty"`
+	EmailVerified bool                   `json:"emailVerified"`
+	Phone         string                 `json:"phone,omitempty"`
+	PhoneVerified bool                   `json:"phoneVerified"`
+	DisplayName   string                 `json:"displayName,omitempty"`
+	Locale        string                 `json:"locale,omitempty"`
+	Timezone      string                 `json:"timezone,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	UpdatedAt     time.Time              `json:"updatedAt"`
+	LastLoginAt   *time.Time             `json:"lastLoginAt,omitempty"`
+}
+
+func (controller *ProfileController) Init(
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload],
+	profileService *user.ProfileService,
+) {
+	controller.sessionTokenService = sessionTokenService
+	controller.profileService = profileService
+}
+
+func (controller *ProfileController) Profile(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	profile, err := controller.profileService.Profile(c.Request.Context(), payload.Sub)
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newProfileResponse(profile))
+}
+
+func (controller *ProfileController) UpdateProfile(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	update := new(user.ProfileUpdate)
+	if err := c.ShouldBindJSON(update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid profile update",
+		})
+		return
+	}
+
+	profile, err := controller.profileService.UpdateProfile(c.Request.Context(), payload.Sub, update)
+	if err != nil {
+		respondProblem(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newProfileResponse(profile))
+}
+
+func newProfileResponse(profile *user.User) *profileResponse {
+	response := &profileResponse{
+		Identifier:    profile.Identifier,
+		Email:         profile.Email,
+		EmailVerified: profile.EmailVerified,
+		Phone:         profile.Phone,
+		PhoneVerified: profile.PhoneVerified,
+		DisplayName:   profile.DisplayName,
+		Locale:        profile.Locale,
+		Timezone:      profile.Timezone,
+		Attributes:    profile.Attributes,
+		CreatedAt:     profile.CreatedAt,
+		UpdatedAt:     profile.UpdatedAt,
+	}
+
+	if !profile.LastLoginAt.IsZero() {
+		response.LastLoginAt = &profile.LastLoginAt
+	}
+	return response
+}
@@ -0,0 +1,136 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProfileControllerSuite struct {
+	suite.Suite
+
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	userRepo            user.UserRepository
+	controller          *ProfileController
+}
+
+func (suite *ProfileControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	suite.userRepo = new(user.MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &user.User{
+		Identifier:    "user",
+		Status:        user.Active,
+		Email:         "user@example.com",
+		EmailVerified: true,
+	})
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	sessionTokenService := new(auth.RefreshTokenService)
+	sessionTokenService.Init(jwtService)
+	suite.sessionTokenService = sessionTokenService
+
+	profileService := new(user.ProfileService)
+	profileService.Init(suite.userRepo, user.AttributeSchema{
+		"department": {Type: user.StringAttribute},
+	})
+
+	suite.controller = new(ProfileController)
+	suite.controller.Init(sessionTokenService, profileService)
+}
+
+func (suite *ProfileControllerSuite) authorize(context *gin.Context, sub string) {
+	token, _ := suite.sessionTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: sub})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *ProfileControllerSuite) TestProfile_ReturnProfileOfLoggedInUser() {
+	w, context := buildContext()
+	suite.authorize(context, "user")
+
+	suite.controller.Profile(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	var body map[string]interface{}
+	json.NewDecoder(w.Result().Body).Decode(&body)
+	assert.Equal(suite.T(), "user", body["identifier"])
+	assert.Equal(suite.T(), "user@example.com", body["email"])
+	assert.Equal(suite.T(), true, body["emailVerified"])
+	assert.NotContains(suite.T(), body, "passkey")
+	assert.NotContains(suite.T(), body, "lastLoginAt")
+}
+
+func (suite *ProfileControllerSuite) TestProfile_FailWithoutBearerToken() {
+	w, context := buildContext()
+
+	suite.controller.Profile(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func (suite *ProfileControllerSuite) TestProfile_NotFoundForUnknownUser() {
+	w, context := buildContext()
+	suite.authorize(context, "unknown")
+
+	suite.controller.Profile(context)
+
+	assert.Equal(suite.T(), 404, w.Result().StatusCode)
+	assert.Equal(suite.T(), ProblemContentType, w.Result().Header.Get("Content-Type"))
+}
+
+func (suite *ProfileControllerSuite) TestUpdateProfile_UpdateProfileOfLoggedInUser() {
+	w, context := buildContext()
+	suite.authorize(context, "user")
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"displayName":"User","email":"new@example.com","attributes":{"department":"sales"}}`))
+
+	suite.controller.UpdateProfile(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	found, _ := suite.userRepo.FindByIdentifier(context.Request.Context(), "user")
+	assert.Equal(suite.T(), "User", found.DisplayName)
+	assert.Equal(suite.T(), "new@example.com", found.Email)
+	assert.False(suite.T(), found.EmailVerified)
+	assert.Equal(suite.T(), map[string]interface{}{"department": "sales"}, found.Attributes)
+}
+
+func (suite *ProfileControllerSuite) TestUpdateProfile_FailWithInvalidProfile() {
+	w, context := buildContext()
+	suite.authorize(context, "user")
+	context.Request.Body = io.NopCloser(strings.NewReader(`{"timezone":"Mars/Olympus","attributes":{"department":1}}`))
+
+	suite.controller.UpdateProfile(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	var problem Problem
+	json.NewDecoder(w.Result().Body).Decode(&problem)
+	assert.Equal(suite.T(), "/problems/invalid-profile", problem.Type)
+	assert.Equal(suite.T(), []string{"timezone is invalid", "attribute department must be a string"}, problem.Violations)
+}
+
+func (suite *ProfileControllerSuite) TestUpdateProfile_FailWithMalformedBody() {
+	w, context := buildContext()
+	suite.authorize(context, "user")
+	context.Request.Body = io.NopCloser(strings.NewReader(`{`))
+
+	suite.controller.UpdateProfile(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "invalid profile update")
+}
+
+func TestProfileController(t *testing.T) {
+	suite.Run(t, new(ProfileControllerSuite))
+}
@@ -0,0 +1,86 @@
+package secret
+
+import (
+	"errors"
+	"sync"
+)
+
+type DataKey struct {
+	Id          string
+	MasterKeyId string
+	WrappedKey  []byte
+}
+
+type DataKeyRepository interface {
+	FindById(id string) (*DataKey, error)
+	FindAll() ([]*DataKey, error)
+	Create(key *DataKey) error
+	Update(key *DataKey) error
+}
+
+type MemoryDataKeyRepository struct {
+	mu   sync.RWMutex
+	keys []*DataKey
+}
+
+func (repo *MemoryDataKeyRepository) FindById(id string) (*DataKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	index := repo.indexOf(id)
+	if index < 0 {
+		return nil, errors.New("no data key found")
+	}
+	return copyDataKey(repo.keys[index]), nil
+}
+
+func (repo *MemoryDataKeyRepository) FindAll() ([]*DataKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	keys := make([]*DataKey, 0, len(repo.keys))
+	for _, key := range repo.keys {
+		keys = append(keys, copyDataKey(key))
+	}
+	return keys, nil
+}
+
+func (repo *MemoryDataKeyRepository) Create(key *DataKey) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.indexOf(key.Id) >= 0 {
+		return errors.New("data key already exists")
+	}
+
+	repo.keys = append(repo.keys, copyDataKey(key))
+	return nil
+}
+
+func (repo *MemoryDataKeyRepository) Update(key *DataKey) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	index := repo.indexOf(key.Id)
+	if index < 0 {
+		return errors.New("no data key found")
+	}
+
+	repo.keys[index] = copyDataKey(key)
+	return nil
+}
+
+func (repo *MemoryDataKeyRepository) indexOf(id string) int {
+	for index, key := range repo.keys {
+		if key.Id == id {
+			return index
+		}
+	}
+	return -1
+}
+
+func copyDataKey(key *DataKey) *DataKey {
+	copied := *key
+	copied.WrappedKey = append([]byte{}, key.WrappedKey...)
+	return &copied
+}
@@ -0,0 +1,62 @@
+package secret_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/Untanky/go-id/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DataKeyRepoTestSuite struct {
+	suite.Suite
+	repo *MemoryDataKeyRepository
+}
+
+func (suite *DataKeyRepoTestSuite) SetupTest() {
+	suite.repo = new(MemoryDataKeyRepository)
+	suite.repo.Create(&DataKey{Id: "key", MasterKeyId: "master", WrappedKey: []byte{1, 2, 3}})
+}
+
+func (suite *DataKeyRepoTestSuite) TestFindById_ReturnCopy() {
+	found, _ := suite.repo.FindById("key")
+	found.MasterKeyId = "other"
+	found.WrappedKey[0] = 9
+
+	stored, err := suite.repo.FindById("key")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "master", stored.MasterKeyId)
+	assert.Equal(suite.T(), []byte{1, 2, 3}, stored.WrappedKey)
+}
+
+func (suite *DataKeyRepoTestSuite) TestFindAll_ReturnCopies() {
+	keys, _ := suite.repo.FindAll()
+	keys[0].WrappedKey[0] = 9
+
+	stored, _ := suite.repo.FindById("key")
+
+	assert.Equal(suite.T(), []byte{1, 2, 3}, stored.WrappedKey)
+}
+
+func (suite *DataKeyRepoTestSuite) TestCreate_ConcurrentKeys() {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			suite.repo.Create(&DataKey{Id: fmt.Sprint(i)})
+			suite.repo.FindAll()
+		}(i)
+	}
+	wg.Wait()
+
+	keys, _ := suite.repo.FindAll()
+	assert.Len(suite.T(), keys, 21)
+}
+
+func TestMemoryDataKeyRepository(t *testing.T) {
+	suite.Run(t, new(DataKeyRepoTestSuite))
+}
@@ -0,0 +1,271 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	envelopeVersion = "v1"
+	wrappingInfo    = "go-id data key wrapping"
+	keyIdInfo       = "go-id master key id"
+)
+
+type EnvelopeService struct {
+	masterKey *RotatingSecret[SecretString]
+	keyRepo   DataKeyRepository
+	pending   map[string]SecretString
+	currentId string
+	lock      sync.Mutex
+}
+
+func (service *EnvelopeService) Init(masterKey *RotatingSecret[SecretString], keyRepo DataKeyRepository) {
+	service.masterKey = masterKey
+	service.keyRepo = keyRepo
+	service.pending = map[string]SecretString{}
+}
+
+func (service *EnvelopeService) Seal(plaintext SecretString) (SecretString, error) {
+	id, dek, err := service.currentDataKey()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, ciphertext, err := seal(dek, []byte(plaintext), []byte(id))
+	if err != nil {
+		return "", err
+	}
+
+	return SecretString(strings.Join([]string{
+		envelopeVersion,
+		id,
+		base64.RawStdEncoding.EncodeToString(nonce),
+		base64.RawStdEncoding.EncodeToString(ciphertext),
+	}, ".")), nil
+}
+
+func (service *EnvelopeService) Open(sealed SecretString) (SecretString, error) {
+	parts := strings.Split(string(sealed), ".")
+	if len(parts) != 4 || parts[0] != envelopeVersion {
+		return "", errors.New("malformed sealed secret")
+	}
+
+	nonce, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed sealed secret")
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", errors.New("malformed sealed secret")
+	}
+
+	dek, err := service.dataKey(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dek, nonce, ciphertext, []byte(parts[1]))
+	if err != nil {
+		return "", err
+	}
+	return SecretString(plaintext), nil
+}
+
+func (service *EnvelopeService) RotateMasterKey(next Secret[SecretString]) error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	keys, err := service.keyRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	nextKey := next.GetSecret()
+	nextId, err := masterKeyId(nextKey)
+	if err != nil {
+		return err
+	}
+	service.pending[nextId] = nextKey
+
+	for _, key := range keys {
+		if key.MasterKeyId == nextId {
+			continue
+		}
+
+		dek, err := service.unwrap(key)
+		if err != nil {
+			return err
+		}
+
+		wrapped, err := wrap(dek, nextKey, key.Id)
+		if err != nil {
+			return err
+		}
+
+		if err := service.keyRepo.Update(&DataKey{Id: key.Id, MasterKeyId: nextId, WrappedKey: wrapped}); err != nil {
+			return err
+		}
+	}
+
+	service.masterKey.Rotate(next)
+	service.pending = map[string]SecretString{}
+	return nil
+}
+
+func (service *EnvelopeService) dataKey(id string) ([]byte, error) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	key, err := service.keyRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.unwrap(key)
+}
+
+func (service *EnvelopeService) currentDataKey() (string, []byte, error) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if service.currentId != "" {
+		key, err := service.keyRepo.FindById(service.currentId)
+		if err != nil {
+			return "", nil, err
+		}
+
+		dek, err := service.unwrap(key)
+		return key.Id, dek, err
+	}
+
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", nil, err
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+
+	masterKey := service.masterKey.GetSecret()
+	wrapped, err := wrap(dek, masterKey, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	masterId, err := masterKeyId(masterKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := service.keyRepo.Create(&DataKey{Id: id, MasterKeyId: masterId, WrappedKey: wrapped}); err != nil {
+		return "", nil, err
+	}
+
+	service.currentId = id
+	return id, dek, nil
+}
+
+func (service *EnvelopeService) unwrap(key *DataKey) ([]byte, error) {
+	nonceSize := 12
+	if len(key.WrappedKey) < nonceSize {
+		return nil, errors.New("malformed data key")
+	}
+
+	masterKey := service.masterKey.GetSecret()
+	if pending, ok := service.pending[key.MasterKeyId]; ok {
+		masterKey = pending
+	}
+
+	wrappingKey, err := deriveKey(masterKey, wrappingInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	dek, err := open(wrappingKey, key.WrappedKey[:nonceSize], key.WrappedKey[nonceSize:], []byte(key.Id))
+	if err != nil {
+		return nil, errors.New("cannot unwrap data key")
+	}
+	return dek, nil
+}
+
+func wrap(dek []byte, masterKey SecretString, id string) ([]byte, error) {
+	wrappingKey, err := deriveKey(masterKey, wrappingInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := seal(wrappingKey, dek, []byte(id))
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, ciphertext...), nil
+}
+
+func deriveKey(masterKey SecretString, info string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(masterKey), nil, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func masterKeyId(masterKey SecretString) (string, error) {
+	key, err := deriveKey(masterKey, keyIdInfo)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key[:8]), nil
+}
+
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, []byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errors.New("cannot decrypt secret")
+	}
+	return plaintext, nil
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
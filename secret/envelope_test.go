@@ -0,0 +1,143 @@
+package secret_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/Untanky/go-id/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type failingDataKeyRepository struct {
+	MemoryDataKeyRepository
+	updates  int
+	failFrom int
+}
+
+func (repo *failingDataKeyRepository) Update(key *DataKey) error {
+	repo.updates++
+	if repo.failFrom > 0 && repo.updates >= repo.failFrom {
+		return errors.New("storage unavailable")
+	}
+	return repo.MemoryDataKeyRepository.Update(key)
+}
+
+type EnvelopeTestSuite struct {
+	suite.Suite
+	masterKey *RotatingSecret[SecretString]
+	keyRepo   DataKeyRepository
+	service   *EnvelopeService
+}
+
+func (suite *EnvelopeTestSuite) SetupTest() {
+	suite.masterKey = NewRotatingSecret(NewSecretValue("master"))
+	suite.keyRepo = new(MemoryDataKeyRepository)
+	suite.service = new(EnvelopeService)
+	suite.service.Init(suite.masterKey, suite.keyRepo)
+}
+
+func (suite *EnvelopeTestSuite) TestSeal_OpenReturnsPlaintext() {
+	sealed, err := suite.service.Seal("JBSWY3DPEHPK3PXP")
+	assert.Nil(suite.T(), err)
+	assert.NotContains(suite.T(), string(sealed), "JBSWY3DPEHPK3PXP")
+
+	opened, err := suite.service.Open(sealed)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), SecretString("JBSWY3DPEHPK3PXP"), opened)
+}
+
+func (suite *EnvelopeTestSuite) TestSeal_ReuseDataKey() {
+	suite.service.Seal("a")
+	suite.service.Seal("b")
+
+	keys, _ := suite.keyRepo.FindAll()
+	assert.Len(suite.T(), keys, 1)
+	assert.NotContains(suite.T(), string(keys[0].WrappedKey), "master")
+}
+
+func (suite *EnvelopeTestSuite) TestOpen_ErrorWhenCiphertextTampered() {
+	sealed, _ := suite.service.Seal("secret")
+	parts := strings.Split(string(sealed), ".")
+	parts[3] = "AAAA" + parts[3][4:]
+
+	opened, err := suite.service.Open(SecretString(strings.Join(parts, ".")))
+
+	assert.ErrorContains(suite.T(), err, "cannot decrypt secret")
+	assert.Empty(suite.T(), opened)
+}
+
+func (suite *EnvelopeTestSuite) TestOpen_ErrorWhenMalformed() {
+	opened, err := suite.service.Open("plaintext")
+
+	assert.ErrorContains(suite.T(), err, "malformed sealed secret")
+	assert.Empty(suite.T(), opened)
+}
+
+func (suite *EnvelopeTestSuite) TestRotateMasterKey_RewrapDataKeysOnly() {
+	sealed, _ := suite.service.Seal("secret")
+	keysBefore, _ := suite.keyRepo.FindAll()
+	wrappedBefore := keysBefore[0].WrappedKey
+
+	err := suite.service.RotateMasterKey(NewSecretValue("next"))
+	assert.Nil(suite.T(), err)
+
+	keysAfter, _ := suite.keyRepo.FindAll()
+	assert.Equal(suite.T(), keysBefore[0].Id, keysAfter[0].Id)
+	assert.NotEqual(suite.T(), wrappedBefore, keysAfter[0].WrappedKey)
+	assert.Equal(suite.T(), SecretString("next"), suite.masterKey.GetSecret())
+
+	opened, err := suite.service.Open(sealed)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), SecretString("secret"), opened)
+}
+
+func (suite *EnvelopeTestSuite) TestRotateMasterKey_KeepDataReadableWhenUpdateFailsPartway() {
+	repo := &failingDataKeyRepository{failFrom: 2}
+	first := new(EnvelopeService)
+	first.Init(suite.masterKey, repo)
+	second := new(EnvelopeService)
+	second.Init(suite.masterKey, repo)
+	sealedFirst, _ := first.Seal("first")
+	sealedSecond, _ := second.Seal("second")
+
+	err := first.RotateMasterKey(NewSecretValue("next"))
+
+	assert.ErrorContains(suite.T(), err, "storage unavailable")
+	assert.Equal(suite.T(), SecretString("master"), suite.masterKey.GetSecret())
+	keys, _ := repo.FindAll()
+	assert.NotEqual(suite.T(), keys[0].MasterKeyId, keys[1].MasterKeyId)
+	for sealed, plaintext := range map[SecretString]SecretString{sealedFirst: "first", sealedSecond: "second"} {
+		opened, err := first.Open(sealed)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), plaintext, opened)
+	}
+
+	repo.failFrom = 0
+	err = first.RotateMasterKey(NewSecretValue("next"))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), SecretString("next"), suite.masterKey.GetSecret())
+	keys, _ = repo.FindAll()
+	assert.Equal(suite.T(), keys[0].MasterKeyId, keys[1].MasterKeyId)
+	for sealed, plaintext := range map[SecretString]SecretString{sealedFirst: "first", sealedSecond: "second"} {
+		opened, err := first.Open(sealed)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), plaintext, opened)
+	}
+}
+
+func (suite *EnvelopeTestSuite) TestOpen_ErrorWithWrongMasterKey() {
+	sealed, _ := suite.service.Seal("secret")
+	suite.masterKey.Rotate(NewSecretValue("wrong"))
+
+	opened, err := suite.service.Open(sealed)
+
+	assert.ErrorContains(suite.T(), err, "cannot unwrap data key")
+	assert.Empty(suite.T(), opened)
+}
+
+func TestEnvelope(t *testing.T) {
+	suite.Run(t, new(EnvelopeTestSuite))
+}
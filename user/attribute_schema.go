@@ -0,0 +1,73 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+)
+
+type AttributeType string
+
+const (
+	StringAttribute  = AttributeType("string")
+	NumberAttribute  = AttributeType("number")
+	BooleanAttribute = AttributeType("boolean")
+)
+
+type AttributeDefinition struct {
+	Type     AttributeType
+	Required bool
+}
+
+type AttributeSchema map[string]AttributeDefinition
+
+func (schema AttributeSchema) Violations(attributes map[string]interface{}) []string {
+	violations := []string{}
+
+	for _, name := range sortedKeys(schema) {
+		if _, ok := attributes[name]; !ok && schema[name].Required {
+			violations = append(violations, fmt.Sprintf("attribute %s is required", name))
+		}
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		definition, ok := schema[name]
+		if !ok {
+			violations = append(violations, fmt.Sprintf("attribute %s is unknown", name))
+			continue
+		}
+
+		if !definition.Type.accepts(attributes[name]) {
+			violations = append(violations, fmt.Sprintf("attribute %s must be a %s", name, definition.Type))
+		}
+	}
+
+	return violations
+}
+
+func (attributeType AttributeType) accepts(value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return attributeType == StringAttribute
+	case float64, float32, int, int32, int64:
+		return attributeType == NumberAttribute
+	case bool:
+		return attributeType == BooleanAttribute
+	default:
+		return false
+	}
+}
+
+func sortedKeys(schema AttributeSchema) []string {
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
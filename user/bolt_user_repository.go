@@ -0,0 +1,318 @@
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	usersBucket    = []byte("users")
+	emailsBucket   = []byte("users_by_email")
+	phonesBucket   = []byte("users_by_phone")
+	identityBucket = []byte("users_by_identity")
+	indexSeparator = []byte{0}
+)
+
+type BoltUserRepository struct {
+	db *bolt.DB
+}
+
+func (repo *BoltUserRepository) Init(db *bolt.DB) error {
+	repo.db = db
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{usersBucket, emailsBucket, phonesBucket, identityBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (repo *BoltUserRepository) FindByIdentifier(ctx context.Context, identifier string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var user *User
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		var err error
+		user, err = getUser(tx, identifier)
+		return err
+	})
+	return user, err
+}
+
+func (repo *BoltUserRepository) FindByEmail(ctx context.Context, email string) ([]*User, error) {
+	return repo.findByIndex(ctx, emailsBucket, email)
+}
+
+func (repo *BoltUserRepository) FindByPhone(ctx context.Context, phone string) ([]*User, error) {
+	return repo.findByIndex(ctx, phonesBucket, phone)
+}
+
+func (repo *BoltUserRepository) FindByIdentity(ctx context.Context, provider string, subject string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var user *User
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		identifier := tx.Bucket(identityBucket).Get(indexKey(provider, subject))
+		if identifier == nil {
+			return ErrUserNotFound
+		}
+
+		var err error
+		user, err = getUser(tx, string(identifier))
+		return err
+	})
+	return user, err
+}
+
+func (repo *BoltUserRepository) List(ctx context.Context, query *Query) (*Page, error) {
+	return repo.Search(ctx, "", query)
+}
+
+func (repo *BoltUserRepository) Search(ctx context.Context, text string, query *Query) (*Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	users := []*User{}
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(_, value []byte) error {
+			user := new(User)
+			if err := json.Unmarshal(value, user); err != nil {
+				return err
+			}
+			users = append(users, user)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paginate(users, text, query)
+}
+
+func (repo *BoltUserRepository) Create(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	created := *user
+	created.Version = 1
+	created.CreatedAt = timestamp()
+	created.UpdatedAt = created.CreatedAt
+
+	err := repo.db.Update(func(tx *bolt.Tx) error {
+		return createUser(tx, &created)
+	})
+	if err == nil {
+		user.Version = created.Version
+		user.CreatedAt = created.CreatedAt
+		user.UpdatedAt = created.UpdatedAt
+	}
+	return err
+}
+
+func (repo *BoltUserRepository) Update(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	updated := *user
+	updated.Version++
+	updated.UpdatedAt = timestamp()
+
+	err := repo.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getUser(tx, user.Identifier)
+		if err != nil {
+			return err
+		}
+
+		if existing.Version != user.Version {
+			return ErrConflict
+		}
+
+		if err := unindexUser(tx, existing); err != nil {
+			return err
+		}
+		return putUser(tx, &updated)
+	})
+	if err == nil {
+		user.Version = updated.Version
+		user.UpdatedAt = updated.UpdatedAt
+	}
+	return err
+}
+
+func (repo *BoltUserRepository) Remove(ctx context.Context, identifier string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return repo.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getUser(tx, identifier)
+		if err != nil {
+			return err
+		}
+
+		if err := unindexUser(tx, existing); err != nil {
+			return err
+		}
+		return tx.Bucket(usersBucket).Delete([]byte(identifier))
+	})
+}
+
+func (repo *BoltUserRepository) Export(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return repo.db.View(func(tx *bolt.Tx) error {
+		encoder := json.NewEncoder(w)
+
+		return tx.Bucket(usersBucket).ForEach(func(_, value []byte) error {
+			return encoder.Encode(json.RawMessage(value))
+		})
+	})
+}
+
+func (repo *BoltUserRepository) Import(ctx context.Context, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return repo.db.Update(func(tx *bolt.Tx) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			user := new(User)
+			if err := json.Unmarshal(scanner.Bytes(), user); err != nil {
+				return err
+			}
+
+			if user.Version == 0 {
+				user.Version = 1
+			}
+
+			if err := createUser(tx, user); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	})
+}
+
+func (repo *BoltUserRepository) findByIndex(ctx context.Context, bucket []byte, value string) ([]*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	users := []*User{}
+
+	err := repo.db.View(func(tx *bolt.Tx) error {
+		prefix := indexKey(value, "")
+		cursor := tx.Bucket(bucket).Cursor()
+
+		for key, identifier := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, identifier = cursor.Next() {
+			user, err := getUser(tx, string(identifier))
+			if err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+		return nil
+	})
+	return users, err
+}
+
+func getUser(tx *bolt.Tx, identifier string) (*User, error) {
+	value := tx.Bucket(usersBucket).Get([]byte(identifier))
+	if value == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user := new(User)
+	if err := json.Unmarshal(value, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func createUser(tx *bolt.Tx, user *User) error {
+	if tx.Bucket(usersBucket).Get([]byte(user.Identifier)) != nil {
+		return ErrUserExists
+	}
+	return putUser(tx, user)
+}
+
+func putUser(tx *bolt.Tx, user *User) error {
+	for _, identity := range user.Identities {
+		key := indexKey(identity.Provider, identity.Subject)
+		if linked := tx.Bucket(identityBucket).Get(key); linked != nil && string(linked) != user.Identifier {
+			return ErrIdentityLinked
+		}
+
+		if err := tx.Bucket(identityBucket).Put(key, []byte(user.Identifier)); err != nil {
+			return err
+		}
+	}
+
+	value, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(usersBucket).Put([]byte(user.Identifier), value); err != nil {
+		return err
+	}
+
+	if user.Email != "" {
+		if err := tx.Bucket(emailsBucket).Put(indexKey(user.Email, user.Identifier), []byte(user.Identifier)); err != nil {
+			return err
+		}
+	}
+
+	if user.Phone != "" {
+		if err := tx.Bucket(phonesBucket).Put(indexKey(user.Phone, user.Identifier), []byte(user.Identifier)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unindexUser(tx *bolt.Tx, user *User) error {
+	for _, identity := range user.Identities {
+		if err := tx.Bucket(identityBucket).Delete(indexKey(identity.Provider, identity.Subject)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Bucket(emailsBucket).Delete(indexKey(user.Email, user.Identifier)); err != nil {
+		return err
+	}
+	return tx.Bucket(phonesBucket).Delete(indexKey(user.Phone, user.Identifier))
+}
+
+func indexKey(value string, identifier string) []byte {
+	key := append([]byte(value), indexSeparator...)
+	return append(key, identifier...)
+}
@@ -0,0 +1,158 @@
+package user_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	. "github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	bolt "go.etcd.io/bbolt"
+)
+
+type BoltUserRepoTestSuite struct {
+	suite.Suite
+	path string
+	db   *bolt.DB
+	repo *BoltUserRepository
+}
+
+func (suite *BoltUserRepoTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "users.db")
+	suite.open()
+
+	suite.repo.Create(context.Background(), &User{Identifier: "abc", Passkey: "abc", Status: Active, Email: "abc@example.com", Phone: "+491234"})
+	suite.repo.Create(context.Background(), &User{Identifier: "def", Passkey: "def", Status: Active, Email: "abc@example.com"})
+}
+
+func (suite *BoltUserRepoTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *BoltUserRepoTestSuite) open() {
+	db, err := bolt.Open(suite.path, 0600, nil)
+	assert.Nil(suite.T(), err)
+	suite.db = db
+
+	suite.repo = new(BoltUserRepository)
+	assert.Nil(suite.T(), suite.repo.Init(db))
+}
+
+func (suite *BoltUserRepoTestSuite) TestFindByEmail_ReturnAllUsersWithEmail() {
+	users, err := suite.repo.FindByEmail(context.Background(), "abc@example.com")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), users, 2)
+	assert.Equal(suite.T(), "abc", users[0].Identifier)
+	assert.Equal(suite.T(), "def", users[1].Identifier)
+}
+
+func (suite *BoltUserRepoTestSuite) TestFindByEmail_DoNotMatchPrefix() {
+	users, err := suite.repo.FindByEmail(context.Background(), "abc@example")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), users, 0)
+}
+
+func (suite *BoltUserRepoTestSuite) TestFindByPhone_ReturnUser() {
+	users, err := suite.repo.FindByPhone(context.Background(), "+491234")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), users, 1)
+	assert.Equal(suite.T(), "abc", users[0].Identifier)
+}
+
+func (suite *BoltUserRepoTestSuite) TestUpdate_MaintainIndexes() {
+	err := suite.repo.Update(context.Background(), &User{Identifier: "abc", Passkey: "abc", Status: Active, Email: "new@example.com", Version: 1})
+	assert.Nil(suite.T(), err)
+
+	users, _ := suite.repo.FindByEmail(context.Background(), "abc@example.com")
+	assert.Len(suite.T(), users, 1)
+	users, _ = suite.repo.FindByEmail(context.Background(), "new@example.com")
+	assert.Len(suite.T(), users, 1)
+	users, _ = suite.repo.FindByPhone(context.Background(), "+491234")
+	assert.Len(suite.T(), users, 0)
+}
+
+func (suite *BoltUserRepoTestSuite) TestRemove_RemoveFromIndexes() {
+	err := suite.repo.Remove(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+
+	users, _ := suite.repo.FindByEmail(context.Background(), "abc@example.com")
+	assert.Len(suite.T(), users, 1)
+	users, _ = suite.repo.FindByPhone(context.Background(), "+491234")
+	assert.Len(suite.T(), users, 0)
+}
+
+func (suite *BoltUserRepoTestSuite) TestReopen_KeepCommittedUsers() {
+	suite.db.Close()
+	suite.open()
+
+	user, err := suite.repo.FindByIdentifier(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "abc@example.com", user.Email)
+}
+
+func (suite *BoltUserRepoTestSuite) TestFindByIdentifier_UpgradeLegacyInactiveStatus() {
+	suite.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("users")).Put([]byte("legacy"), []byte(`{"Identifier":"legacy","Passkey":"abc","Status":"inactive"}`))
+	})
+
+	user, err := suite.repo.FindByIdentifier(context.Background(), "legacy")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), PendingVerification, user.Status)
+}
+
+func (suite *BoltUserRepoTestSuite) TestImport_UpgradeLegacyInactiveStatus() {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{"Identifier":"legacy","Passkey":"abc","Status":"inactive"}` + "\n")
+
+	err := suite.repo.Import(context.Background(), &buffer)
+
+	assert.Nil(suite.T(), err)
+	user, _ := suite.repo.FindByIdentifier(context.Background(), "legacy")
+	assert.Equal(suite.T(), PendingVerification, user.Status)
+}
+
+func (suite *BoltUserRepoTestSuite) TestExportImport_RoundTrip() {
+	var buffer bytes.Buffer
+	err := suite.repo.Export(context.Background(), &buffer)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, bytes.Count(buffer.Bytes(), []byte("\n")))
+
+	db, err := bolt.Open(filepath.Join(suite.T().TempDir(), "imported.db"), 0600, nil)
+	assert.Nil(suite.T(), err)
+	defer db.Close()
+	imported := new(BoltUserRepository)
+	imported.Init(db)
+
+	err = imported.Import(context.Background(), &buffer)
+	assert.Nil(suite.T(), err)
+
+	original, _ := suite.repo.FindByIdentifier(context.Background(), "abc")
+	user, err := imported.FindByIdentifier(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), original, user)
+	users, _ := imported.FindByEmail(context.Background(), "abc@example.com")
+	assert.Len(suite.T(), users, 2)
+}
+
+func (suite *BoltUserRepoTestSuite) TestImport_RollbackOnDuplicate() {
+	var buffer bytes.Buffer
+	buffer.WriteString(`{"Identifier":"new","Passkey":"new","Status":"active"}` + "\n")
+	buffer.WriteString(`{"Identifier":"abc","Passkey":"abc","Status":"active"}` + "\n")
+
+	err := suite.repo.Import(context.Background(), &buffer)
+
+	assert.ErrorContains(suite.T(), err, "already exists")
+	user, err := suite.repo.FindByIdentifier(context.Background(), "new")
+	assert.ErrorContains(suite.T(), err, "no user found")
+	assert.Nil(suite.T(), user)
+}
+
+func TestBoltUserRepositoryIndexes(t *testing.T) {
+	suite.Run(t, new(BoltUserRepoTestSuite))
+}
@@ -0,0 +1,44 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUserNotFound      = errors.New("no user found")
+	ErrUserExists        = errors.New("user already exists")
+	ErrInvalidIdentifier = errors.New("identifier is invalid")
+	ErrConflict          = errors.New("user was modified concurrently")
+	ErrInvalidProfile    = errors.New("profile is invalid")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrIdentityLinked    = errors.New("identity is already linked")
+	ErrIdentityNotFound  = errors.New("no linked identity found")
+	ErrLastIdentity      = errors.New("cannot unlink the only way to sign in")
+)
+
+type ProfileError struct {
+	Violations []string
+}
+
+func (err *ProfileError) Error() string {
+	return "profile is invalid: " + strings.Join(err.Violations, ", ")
+}
+
+func (err *ProfileError) Is(target error) bool {
+	return target == ErrInvalidProfile
+}
+
+type TransitionError struct {
+	From status
+	To   status
+}
+
+func (err *TransitionError) Error() string {
+	return fmt.Sprintf("cannot transition user from %s to %s", err.From, err.To)
+}
+
+func (err *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
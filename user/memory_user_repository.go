@@ -1,64 +1,206 @@
 package user
 
-import "errors"
+import (
+	"context"
+	"sort"
+	"sync"
+)
 
 type MemoryUserRepository struct {
-	users []*User
+	users map[string]*User
+	lock  sync.RWMutex
 }
 
-func (repo *MemoryUserRepository) FindByIdentifier(identifier string) (*User, error) {
-	if repo.users == nil {
-		repo.users = []*User{}
+func (repo *MemoryUserRepository) FindByIdentifier(ctx context.Context, identifier string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	for _, user := range repo.users {
-		if user.Identifier == identifier {
-			return user, nil
-		}
+	repo.lock.RLock()
+	defer repo.lock.RUnlock()
+
+	user, ok := repo.users[identifier]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return user.clone(), nil
+}
+
+func (repo *MemoryUserRepository) FindByEmail(ctx context.Context, email string) ([]*User, error) {
+	return repo.findBy(ctx, func(user *User) bool {
+		return user.Email == email
+	})
+}
+
+func (repo *MemoryUserRepository) FindByPhone(ctx context.Context, phone string) ([]*User, error) {
+	return repo.findBy(ctx, func(user *User) bool {
+		return user.Phone == phone
+	})
+}
+
+func (repo *MemoryUserRepository) FindByIdentity(ctx context.Context, provider string, subject string) (*User, error) {
+	users, err := repo.findBy(ctx, func(user *User) bool {
+		return user.HasIdentity(provider, subject)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, ErrUserNotFound
 	}
-	return nil, errors.New("no user found")
+	return users[0], nil
 }
 
-func (repo *MemoryUserRepository) Create(user *User) error {
-	foundUser, err := repo.FindByIdentifier(user.Identifier)
+func (repo *MemoryUserRepository) List(ctx context.Context, query *Query) (*Page, error) {
+	return repo.Search(ctx, "", query)
+}
 
-	if foundUser != nil {
-		return errors.New("user already exists")
+func (repo *MemoryUserRepository) Search(ctx context.Context, text string, query *Query) (*Page, error) {
+	users, err := repo.findBy(ctx, func(user *User) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err != nil && err.Error() != "no user found" {
+	return paginate(users, text, query)
+}
+
+func (repo *MemoryUserRepository) Create(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	repo.users = append(repo.users, user)
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if _, ok := repo.users[user.Identifier]; ok {
+		return ErrUserExists
+	}
+
+	if repo.identityLinkedElsewhere(user) {
+		return ErrIdentityLinked
+	}
+
+	if repo.users == nil {
+		repo.users = map[string]*User{}
+	}
+
+	user.Version = 1
+	user.CreatedAt = timestamp()
+	user.UpdatedAt = user.CreatedAt
+	repo.users[user.Identifier] = user.clone()
 	return nil
 }
 
-func (repo *MemoryUserRepository) Update(user *User) error {
-	if repo.users == nil {
-		repo.users = []*User{}
+func (repo *MemoryUserRepository) Update(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	existing, ok := repo.users[user.Identifier]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	if existing.Version != user.Version {
+		return ErrConflict
 	}
 
-	for index, u := range repo.users {
-		if u.Identifier == user.Identifier {
-			repo.users[index] = user
-			return nil
+	if repo.identityLinkedElsewhere(user) {
+		return ErrIdentityLinked
+	}
+
+	user.Version++
+	user.UpdatedAt = timestamp()
+	repo.users[user.Identifier] = user.clone()
+	return nil
+}
+
+func (repo *MemoryUserRepository) Remove(ctx context.Context, identifier string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if _, ok := repo.users[identifier]; !ok {
+		return ErrUserNotFound
+	}
+
+	delete(repo.users, identifier)
+	return nil
+}
+
+func (repo *MemoryUserRepository) identityLinkedElsewhere(user *User) bool {
+	for _, identity := range user.Identities {
+		for _, other := range repo.users {
+			if other.Identifier != user.Identifier && other.HasIdentity(identity.Provider, identity.Subject) {
+				return true
+			}
 		}
 	}
-	return errors.New("no user found")
+	return false
 }
 
-func (repo *MemoryUserRepository) Remove(identifier string) error {
-	if repo.users == nil {
-		repo.users = []*User{}
+func (repo *MemoryUserRepository) findBy(ctx context.Context, match func(user *User) bool) ([]*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	for index, user := range repo.users {
-		if user.Identifier == identifier {
-			repo.users[index] = repo.users[len(repo.users)-1]
-			repo.users = repo.users[:len(repo.users)-1]
-			return nil
+	repo.lock.RLock()
+	defer repo.lock.RUnlock()
+
+	users := []*User{}
+	for _, user := range repo.users {
+		if match(user) {
+			users = append(users, user.clone())
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Identifier < users[j].Identifier
+	})
+	return users, nil
+}
+
+func (user *User) clone() *User {
+	clone := *user
+
+	if user.Attributes != nil {
+		clone.Attributes = make(map[string]interface{}, len(user.Attributes))
+		for key, value := range user.Attributes {
+			clone.Attributes[key] = cloneAttribute(value)
+		}
+	}
+
+	if user.Identities != nil {
+		clone.Identities = append([]Identity{}, user.Identities...)
+	}
+	return &clone
+}
+
+func cloneAttribute(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			clone[key] = cloneAttribute(nested)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(value))
+		for index, nested := range value {
+			clone[index] = cloneAttribute(nested)
 		}
+		return clone
+	case []string:
+		return append([]string{}, value...)
 	}
-	return errors.New("no user found")
+	return value
 }
@@ -0,0 +1,145 @@
+package user_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryUserRepoTestSuite struct {
+	suite.Suite
+	repo *MemoryUserRepository
+}
+
+func (suite *MemoryUserRepoTestSuite) SetupTest() {
+	suite.repo = new(MemoryUserRepository)
+}
+
+func (suite *MemoryUserRepoTestSuite) TestCreate_StoreCopyOfUser() {
+	user := &User{Identifier: "abc", Passkey: "abc", Status: Active}
+	suite.repo.Create(context.Background(), user)
+
+	user.Status = Locked
+
+	found, err := suite.repo.FindByIdentifier(context.Background(), "abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Active, found.Status)
+}
+
+func (suite *MemoryUserRepoTestSuite) TestFindByIdentifier_ReturnCopyOfUser() {
+	suite.repo.Create(context.Background(), &User{Identifier: "abc", Passkey: "abc", Status: Active})
+
+	found, _ := suite.repo.FindByIdentifier(context.Background(), "abc")
+	found.Passkey = "changed"
+
+	foundAgain, _ := suite.repo.FindByIdentifier(context.Background(), "abc")
+	assert.Equal(suite.T(), "abc", foundAgain.Passkey)
+}
+
+func (suite *MemoryUserRepoTestSuite) TestFindByIdentifier_ReturnDeepCopyOfAttributes() {
+	suite.repo.Create(context.Background(), &User{
+		Identifier: "abc",
+		Status:     Active,
+		Attributes: map[string]interface{}{
+			"address": map[string]interface{}{"city": "Berlin"},
+			"tags":    []interface{}{"beta"},
+		},
+	})
+
+	found, _ := suite.repo.FindByIdentifier(context.Background(), "abc")
+	found.Attributes["address"].(map[string]interface{})["city"] = "Paris"
+	found.Attributes["tags"].([]interface{})[0] = "alpha"
+
+	foundAgain, _ := suite.repo.FindByIdentifier(context.Background(), "abc")
+	assert.Equal(suite.T(), "Berlin", foundAgain.Attributes["address"].(map[string]interface{})["city"])
+	assert.Equal(suite.T(), "beta", foundAgain.Attributes["tags"].([]interface{})[0])
+}
+
+func (suite *MemoryUserRepoTestSuite) TestConcurrentCreateUpdateFindRemove() {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		identifier := fmt.Sprintf("user%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			assert.Nil(suite.T(), suite.repo.Create(context.Background(), &User{Identifier: identifier, Passkey: "abc", Status: Active}))
+			for j := 0; j < 20; j++ {
+				found, err := suite.repo.FindByIdentifier(context.Background(), identifier)
+				assert.Nil(suite.T(), err)
+				found.Passkey = fmt.Sprintf("abc%d", j)
+				assert.Nil(suite.T(), suite.repo.Update(context.Background(), found))
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			suite.repo.Create(context.Background(), &User{Identifier: "shared", Passkey: "abc", Status: Active})
+			suite.repo.FindByIdentifier(context.Background(), "shared")
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		found, err := suite.repo.FindByIdentifier(context.Background(), fmt.Sprintf("user%d", i))
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), "abc19", found.Passkey)
+	}
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suite.repo.Remove(context.Background(), "shared")
+		}()
+	}
+	wg.Wait()
+
+	_, err := suite.repo.FindByIdentifier(context.Background(), "shared")
+	assert.ErrorContains(suite.T(), err, "no user found")
+}
+
+func (suite *MemoryUserRepoTestSuite) TestConcurrentUpdatesOfSameVersion_OnlyOneWins() {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	results := []error{}
+	suite.repo.Create(context.Background(), &User{Identifier: "abc", Passkey: "abc", Status: Active})
+
+	for i := 0; i < 20; i++ {
+		found, _ := suite.repo.FindByIdentifier(context.Background(), "abc")
+		found.Passkey = fmt.Sprintf("abc%d", i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := suite.repo.Update(context.Background(), found)
+			lock.Lock()
+			results = append(results, err)
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range results {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(suite.T(), err, ErrConflict)
+		}
+	}
+	assert.Equal(suite.T(), 1, succeeded)
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	suite.Run(t, new(MemoryUserRepoTestSuite))
+}
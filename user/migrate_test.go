@@ -0,0 +1,43 @@
+package user_test
+
+import (
+	"database/sql"
+	"testing"
+
+	. "github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestMigrate_ApplyMigrationsOnlyOnce(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	assert.Nil(t, Migrate(db))
+	var versions int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions)
+	assert.Nil(t, err)
+	assert.Greater(t, versions, 0)
+
+	assert.Nil(t, Migrate(db))
+	var versionsAfterRerun int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versionsAfterRerun)
+	assert.Nil(t, err)
+	assert.Equal(t, versions, versionsAfterRerun)
+}
+
+func TestMigrate_EnforceUniqueIdentifier(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	assert.Nil(t, Migrate(db))
+
+	insert := `INSERT INTO users (identifier, passkey, status) VALUES ('abc', 'abc', 'active')`
+	_, err = db.Exec(insert)
+	assert.Nil(t, err)
+	_, err = db.Exec(insert)
+	assert.Error(t, err)
+}
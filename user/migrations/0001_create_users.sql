@@ -0,0 +1,7 @@
+CREATE TABLE users (
+    identifier TEXT NOT NULL,
+    passkey    TEXT NOT NULL,
+    status     TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX users_identifier_idx ON users (identifier);
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN phone TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX users_email_idx ON users (email);
+CREATE INDEX users_phone_idx ON users (phone);
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN attributes TEXT NOT NULL DEFAULT 'null';
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00';
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00';
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00';
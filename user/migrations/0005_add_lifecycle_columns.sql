@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00';
+
+UPDATE users SET status = 'pending_verification' WHERE status = 'inactive';
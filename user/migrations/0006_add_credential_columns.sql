@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00';
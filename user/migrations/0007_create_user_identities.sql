@@ -0,0 +1,9 @@
+CREATE TABLE user_identities (
+    provider   TEXT      NOT NULL,
+    subject    TEXT      NOT NULL,
+    identifier TEXT      NOT NULL,
+    linked_at  TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00'
+);
+
+CREATE UNIQUE INDEX user_identities_provider_subject_idx ON user_identities (provider, subject);
+CREATE INDEX user_identities_identifier_idx ON user_identities (identifier);
@@ -0,0 +1,178 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"regexp"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	phonePattern  = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+)
+
+const maxDisplayNameLength = 128
+
+type ProfileUpdate struct {
+	DisplayName *string                `json:"displayName"`
+	Email       *string                `json:"email"`
+	Phone       *string                `json:"phone"`
+	Locale      *string                `json:"locale"`
+	Timezone    *string                `json:"timezone"`
+	Attributes  map[string]interface{} `json:"attributes"`
+}
+
+type ProfileService struct {
+	userRepo UserRepository
+	schema   AttributeSchema
+}
+
+func (service *ProfileService) Init(userRepo UserRepository, schema AttributeSchema) {
+	service.userRepo = userRepo
+	service.schema = schema
+}
+
+func (service *ProfileService) Profile(ctx context.Context, identifier string) (*User, error) {
+	return service.userRepo.FindByIdentifier(ctx, identifier)
+}
+
+func (service *ProfileService) UpdateProfile(ctx context.Context, identifier string, update *ProfileUpdate) (*User, error) {
+	if err := service.validate(update); err != nil {
+		return nil, err
+	}
+
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.DisplayName != nil {
+		user.DisplayName = *update.DisplayName
+	}
+
+	if update.Email != nil && *update.Email != user.Email {
+		user.Email = *update.Email
+		user.EmailVerified = false
+	}
+
+	if update.Phone != nil && *update.Phone != user.Phone {
+		user.Phone = *update.Phone
+		user.PhoneVerified = false
+	}
+
+	if update.Locale != nil {
+		user.Locale = *update.Locale
+	}
+
+	if update.Timezone != nil {
+		user.Timezone = *update.Timezone
+	}
+
+	if update.Attributes != nil {
+		user.Attributes = update.Attributes
+	}
+
+	if err := service.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (service *ProfileService) VerifyEmail(ctx context.Context, identifier string, email string) error {
+	return service.verify(ctx, identifier, func(user *User) error {
+		if user.Email != email {
+			return errors.New("email address has changed")
+		}
+
+		user.EmailVerified = true
+		return nil
+	})
+}
+
+func (service *ProfileService) VerifyPhone(ctx context.Context, identifier string, phone string) error {
+	return service.verify(ctx, identifier, func(user *User) error {
+		if user.Phone != phone {
+			return errors.New("phone number has changed")
+		}
+
+		user.PhoneVerified = true
+		return nil
+	})
+}
+
+func (service *ProfileService) verify(ctx context.Context, identifier string, mark func(user *User) error) error {
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return err
+	}
+
+	if err := mark(user); err != nil {
+		return err
+	}
+
+	return service.userRepo.Update(ctx, user)
+}
+
+func (service *ProfileService) validate(update *ProfileUpdate) error {
+	violations := []string{}
+
+	if update.DisplayName != nil && !validDisplayName(*update.DisplayName) {
+		violations = append(violations, "displayName is invalid")
+	}
+
+	if update.Email != nil && *update.Email != "" && !validEmail(*update.Email) {
+		violations = append(violations, "email is invalid")
+	}
+
+	if update.Phone != nil && *update.Phone != "" && !phonePattern.MatchString(*update.Phone) {
+		violations = append(violations, "phone must be in E.164 format")
+	}
+
+	if update.Locale != nil && *update.Locale != "" && !localePattern.MatchString(*update.Locale) {
+		violations = append(violations, "locale is invalid")
+	}
+
+	if update.Timezone != nil && *update.Timezone != "" && !validTimezone(*update.Timezone) {
+		violations = append(violations, "timezone is invalid")
+	}
+
+	if update.Attributes != nil {
+		violations = append(violations, service.schema.Violations(update.Attributes)...)
+	}
+
+	if len(violations) > 0 {
+		return &ProfileError{Violations: violations}
+	}
+	return nil
+}
+
+func validDisplayName(displayName string) bool {
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return false
+	}
+
+	for _, r := range displayName {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func validTimezone(timezone string) bool {
+	if timezone == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
@@ -0,0 +1,130 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/Untanky/go-id/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProfileServiceTestSuite struct {
+	suite.Suite
+	userRepo UserRepository
+	service  *ProfileService
+}
+
+func (suite *ProfileServiceTestSuite) SetupTest() {
+	suite.userRepo = new(MemoryUserRepository)
+	suite.userRepo.Create(context.Background(), &User{
+		Identifier:    knownUserId,
+		Status:        Active,
+		Email:         "known@example.com",
+		EmailVerified: true,
+		Phone:         "+491234",
+		PhoneVerified: true,
+	})
+
+	suite.service = new(ProfileService)
+	suite.service.Init(suite.userRepo, AttributeSchema{
+		"department": {Type: StringAttribute, Required: true},
+		"seats":      {Type: NumberAttribute},
+		"newsletter": {Type: BooleanAttribute},
+	})
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func (suite *ProfileServiceTestSuite) TestUpdateProfile_UpdateGivenFields() {
+	profile, err := suite.service.UpdateProfile(context.Background(), knownUserId, &ProfileUpdate{
+		DisplayName: stringPointer("Known User"),
+		Locale:      stringPointer("de-DE"),
+		Timezone:    stringPointer("Europe/Berlin"),
+		Attributes:  map[string]interface{}{"department": "sales", "seats": float64(3), "newsletter": true},
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Known User", profile.DisplayName)
+	assert.Equal(suite.T(), "known@example.com", profile.Email)
+	assert.True(suite.T(), profile.EmailVerified)
+
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), knownUserId)
+	assert.Equal(suite.T(), profile, found)
+	assert.Equal(suite.T(), int64(2), found.Version)
+}
+
+func (suite *ProfileServiceTestSuite) TestUpdateProfile_ResetVerificationWhenContactChanges() {
+	profile, err := suite.service.UpdateProfile(context.Background(), knownUserId, &ProfileUpdate{
+		Email: stringPointer("new@example.com"),
+		Phone: stringPointer("+491234"),
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "new@example.com", profile.Email)
+	assert.False(suite.T(), profile.EmailVerified)
+	assert.True(suite.T(), profile.PhoneVerified)
+}
+
+func (suite *ProfileServiceTestSuite) TestUpdateProfile_ErrorWithEveryViolation() {
+	profile, err := suite.service.UpdateProfile(context.Background(), knownUserId, &ProfileUpdate{
+		Email:      stringPointer("not an email"),
+		Phone:      stringPointer("01234"),
+		Locale:     stringPointer("german"),
+		Timezone:   stringPointer("Mars/Olympus"),
+		Attributes: map[string]interface{}{"seats": "three", "color": "red"},
+	})
+
+	assert.Nil(suite.T(), profile)
+	assert.ErrorIs(suite.T(), err, ErrInvalidProfile)
+	var profileErr *ProfileError
+	assert.ErrorAs(suite.T(), err, &profileErr)
+	assert.Equal(suite.T(), []string{
+		"email is invalid",
+		"phone must be in E.164 format",
+		"locale is invalid",
+		"timezone is invalid",
+		"attribute department is required",
+		"attribute color is unknown",
+		"attribute seats must be a number",
+	}, profileErr.Violations)
+
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), knownUserId)
+	assert.Equal(suite.T(), int64(1), found.Version)
+}
+
+func (suite *ProfileServiceTestSuite) TestUpdateProfile_ErrorWhenUserNotFound() {
+	profile, err := suite.service.UpdateProfile(context.Background(), unknownUserId, &ProfileUpdate{})
+
+	assert.Nil(suite.T(), profile)
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func (suite *ProfileServiceTestSuite) TestVerifyEmail_MarkCurrentAddressVerified() {
+	suite.service.UpdateProfile(context.Background(), knownUserId, &ProfileUpdate{Email: stringPointer("new@example.com")})
+
+	err := suite.service.VerifyEmail(context.Background(), knownUserId, "known@example.com")
+	assert.ErrorContains(suite.T(), err, "email address has changed")
+
+	err = suite.service.VerifyEmail(context.Background(), knownUserId, "new@example.com")
+	assert.Nil(suite.T(), err)
+
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), knownUserId)
+	assert.True(suite.T(), found.EmailVerified)
+}
+
+func (suite *ProfileServiceTestSuite) TestVerifyPhone_MarkCurrentNumberVerified() {
+	suite.service.UpdateProfile(context.Background(), knownUserId, &ProfileUpdate{Phone: stringPointer("+4999")})
+
+	err := suite.service.VerifyPhone(context.Background(), knownUserId, "+4999")
+	assert.Nil(suite.T(), err)
+
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), knownUserId)
+	assert.True(suite.T(), found.PhoneVerified)
+}
+
+func TestProfileService(t *testing.T) {
+	suite.Run(t, new(ProfileServiceTestSuite))
+}
@@ -0,0 +1,196 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type SortField string
+
+const (
+	SortByIdentifier = SortField("identifier")
+	SortByCreatedAt  = SortField("created_at")
+)
+
+type Query struct {
+	Status        status
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Attributes    map[string]string
+	SortBy        SortField
+	Descending    bool
+	Limit         int
+	Cursor        string
+}
+
+type Page struct {
+	Users      []*User
+	NextCursor string
+}
+
+type cursor struct {
+	CreatedAt  time.Time `json:"c"`
+	Identifier string    `json:"i"`
+}
+
+func (query *Query) normalize() (*Query, *cursor, error) {
+	normalized := *query
+
+	if normalized.SortBy == "" {
+		normalized.SortBy = SortByIdentifier
+	}
+
+	if normalized.SortBy != SortByIdentifier && normalized.SortBy != SortByCreatedAt {
+		return nil, nil, fmt.Errorf("cannot sort by %s", normalized.SortBy)
+	}
+
+	if normalized.Limit <= 0 {
+		normalized.Limit = defaultPageLimit
+	}
+
+	if normalized.Limit > maxPageLimit {
+		normalized.Limit = maxPageLimit
+	}
+
+	normalized.CreatedAfter = normalized.CreatedAfter.UTC()
+	normalized.CreatedBefore = normalized.CreatedBefore.UTC()
+
+	if normalized.Cursor == "" {
+		return &normalized, nil, nil
+	}
+
+	after, err := decodeCursor(normalized.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &normalized, after, nil
+}
+
+func (query *Query) matches(user *User, text string) bool {
+	if query.Status != "" && user.Status != query.Status {
+		return false
+	}
+
+	if !query.CreatedAfter.IsZero() && !user.CreatedAt.After(query.CreatedAfter) {
+		return false
+	}
+
+	if !query.CreatedBefore.IsZero() && !user.CreatedAt.Before(query.CreatedBefore) {
+		return false
+	}
+
+	if !matchesAttributes(user, query.Attributes) {
+		return false
+	}
+
+	if text == "" {
+		return true
+	}
+
+	text = strings.ToLower(text)
+	for _, field := range []string{user.Identifier, user.Email, user.DisplayName} {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (query *Query) less(a *User, b *User) bool {
+	if query.SortBy == SortByCreatedAt && !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt) != query.Descending
+	}
+
+	if a.Identifier == b.Identifier {
+		return false
+	}
+	return (a.Identifier < b.Identifier) != query.Descending
+}
+
+func (query *Query) isAfter(user *User, after *cursor) bool {
+	return query.less(&User{Identifier: after.Identifier, CreatedAt: after.CreatedAt}, user)
+}
+
+func matchesAttributes(user *User, attributes map[string]string) bool {
+	for name, expected := range attributes {
+		value, ok := user.Attributes[name]
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func paginate(users []*User, text string, query *Query) (*Page, error) {
+	normalized, after, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []*User{}
+	for _, user := range users {
+		if !normalized.matches(user, text) {
+			continue
+		}
+
+		if after != nil && !normalized.isAfter(user, after) {
+			continue
+		}
+		matching = append(matching, user)
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		return normalized.less(matching[i], matching[j])
+	})
+
+	return newPage(matching, normalized), nil
+}
+
+func newPage(users []*User, query *Query) *Page {
+	if len(users) <= query.Limit {
+		return &Page{Users: users}
+	}
+
+	users = users[:query.Limit]
+	return &Page{
+		Users:      users,
+		NextCursor: encodeCursor(users[len(users)-1], query.SortBy),
+	}
+}
+
+func encodeCursor(user *User, sortBy SortField) string {
+	value := cursor{Identifier: user.Identifier}
+	if sortBy == SortByCreatedAt {
+		value.CreatedAt = user.CreatedAt
+	}
+
+	encoded, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	value := new(cursor)
+	if err := json.Unmarshal(decoded, value); err != nil || value.Identifier == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	value.CreatedAt = value.CreatedAt.UTC()
+	return value, nil
+}
@@ -0,0 +1,408 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const userColumns = `identifier, passkey, password_reset_required, sessions_revoked_at, status, suspension_reason, suspended_until, email, email_verified,
+	phone, phone_verified, display_name, locale, timezone, attributes, created_at, updated_at, last_login_at, version`
+
+type SqlUserRepository struct {
+	db *sql.DB
+}
+
+func (repo *SqlUserRepository) Init(db *sql.DB) {
+	repo.db = db
+}
+
+func (repo *SqlUserRepository) FindByIdentifier(ctx context.Context, identifier string) (*User, error) {
+	user, err := scanUser(repo.db.QueryRowContext(
+		ctx,
+		`SELECT `+userColumns+` FROM users WHERE identifier = $1`,
+		identifier,
+	))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return user, repo.loadIdentities(ctx, []*User{user})
+}
+
+func (repo *SqlUserRepository) FindByEmail(ctx context.Context, email string) ([]*User, error) {
+	return repo.findBy(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1 ORDER BY identifier`, email)
+}
+
+func (repo *SqlUserRepository) FindByPhone(ctx context.Context, phone string) ([]*User, error) {
+	return repo.findBy(ctx, `SELECT `+userColumns+` FROM users WHERE phone = $1 ORDER BY identifier`, phone)
+}
+
+func (repo *SqlUserRepository) FindByIdentity(ctx context.Context, provider string, subject string) (*User, error) {
+	var identifier string
+	err := repo.db.QueryRowContext(
+		ctx,
+		`SELECT identifier FROM user_identities WHERE provider = $1 AND subject = $2`,
+		provider, subject,
+	).Scan(&identifier)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return repo.FindByIdentifier(ctx, identifier)
+}
+
+func (repo *SqlUserRepository) List(ctx context.Context, query *Query) (*Page, error) {
+	return repo.Search(ctx, "", query)
+}
+
+func (repo *SqlUserRepository) Search(ctx context.Context, text string, query *Query) (*Page, error) {
+	normalized, after, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+	bind := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if normalized.Status != "" {
+		conditions = append(conditions, "status = "+bind(string(normalized.Status)))
+	}
+
+	if !normalized.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > "+bind(normalized.CreatedAfter))
+	}
+
+	if !normalized.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+bind(normalized.CreatedBefore))
+	}
+
+	if text != "" {
+		pattern := bind("%" + escapeLike(strings.ToLower(text)) + "%")
+		conditions = append(conditions, fmt.Sprintf(
+			`(LOWER(identifier) LIKE %[1]s ESCAPE '\' OR LOWER(email) LIKE %[1]s ESCAPE '\' OR LOWER(display_name) LIKE %[1]s ESCAPE '\')`,
+			pattern,
+		))
+	}
+
+	comparison, direction := ">", "ASC"
+	if normalized.Descending {
+		comparison, direction = "<", "DESC"
+	}
+
+	if after != nil {
+		identifier := bind(after.Identifier)
+		if normalized.SortBy == SortByCreatedAt {
+			createdAt := bind(after.CreatedAt)
+			conditions = append(conditions, fmt.Sprintf(
+				"(created_at %[1]s %[2]s OR (created_at = %[2]s AND identifier %[1]s %[3]s))",
+				comparison, createdAt, identifier,
+			))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("identifier %s %s", comparison, identifier))
+		}
+	}
+
+	statement := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		statement += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	if normalized.SortBy == SortByCreatedAt {
+		statement += fmt.Sprintf(" ORDER BY created_at %[1]s, identifier %[1]s", direction)
+	} else {
+		statement += " ORDER BY identifier " + direction
+	}
+
+	if len(normalized.Attributes) == 0 {
+		statement += " LIMIT " + bind(normalized.Limit+1)
+	}
+
+	rows, err := repo.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for len(users) <= normalized.Limit && rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if matchesAttributes(user, normalized.Attributes) {
+			users = append(users, user)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := repo.loadIdentities(ctx, users); err != nil {
+		return nil, err
+	}
+	return newPage(users, normalized), nil
+}
+
+func (repo *SqlUserRepository) Create(ctx context.Context, user *User) error {
+	attributes, err := json.Marshal(user.Attributes)
+	if err != nil {
+		return err
+	}
+
+	createdAt := timestamp()
+
+	err = repo.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, 1)`,
+			user.Identifier, user.Passkey, user.PasswordResetRequired, user.SessionsRevokedAt, string(user.Status), user.SuspensionReason, user.SuspendedUntil, user.Email, user.EmailVerified,
+			user.Phone, user.PhoneVerified, user.DisplayName, user.Locale, user.Timezone, string(attributes), createdAt, createdAt,
+			user.LastLoginAt,
+		)
+		if err != nil {
+			return err
+		}
+		return saveIdentities(ctx, tx, user)
+	})
+
+	if err != nil {
+		if foundUser, _ := repo.FindByIdentifier(ctx, user.Identifier); foundUser != nil {
+			return ErrUserExists
+		}
+		return repo.identityError(ctx, user, err)
+	}
+
+	user.Version = 1
+	user.CreatedAt = createdAt
+	user.UpdatedAt = createdAt
+	return nil
+}
+
+func (repo *SqlUserRepository) Update(ctx context.Context, user *User) error {
+	attributes, err := json.Marshal(user.Attributes)
+	if err != nil {
+		return err
+	}
+
+	updatedAt := timestamp()
+	var affected int64
+
+	err = repo.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE users SET passkey = $2, password_reset_required = $3, sessions_revoked_at = $4, status = $5,
+				suspension_reason = $6, suspended_until = $7, email = $8, email_verified = $9, phone = $10,
+				phone_verified = $11, display_name = $12, locale = $13, timezone = $14, attributes = $15, created_at = $16,
+				updated_at = $17, last_login_at = $18, version = version + 1
+			WHERE identifier = $1 AND version = $19`,
+			user.Identifier, user.Passkey, user.PasswordResetRequired, user.SessionsRevokedAt, string(user.Status), user.SuspensionReason, user.SuspendedUntil, user.Email,
+			user.EmailVerified, user.Phone, user.PhoneVerified, user.DisplayName, user.Locale, user.Timezone,
+			string(attributes), user.CreatedAt, updatedAt, user.LastLoginAt, user.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err = result.RowsAffected()
+		if err != nil || affected == 0 {
+			return err
+		}
+		return saveIdentities(ctx, tx, user)
+	})
+	if err != nil {
+		return repo.identityError(ctx, user, err)
+	}
+
+	if affected == 0 {
+		if _, err := repo.FindByIdentifier(ctx, user.Identifier); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+
+	user.Version++
+	user.UpdatedAt = updatedAt
+	return nil
+}
+
+func (repo *SqlUserRepository) Remove(ctx context.Context, identifier string) error {
+	return repo.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE identifier = $1`, identifier)
+		if err != nil {
+			return err
+		}
+
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM user_identities WHERE identifier = $1`, identifier)
+		return err
+	})
+}
+
+func (repo *SqlUserRepository) inTx(ctx context.Context, apply func(tx *sql.Tx) error) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (repo *SqlUserRepository) identityError(ctx context.Context, user *User, err error) error {
+	for _, identity := range user.Identities {
+		linked, findErr := repo.FindByIdentity(ctx, identity.Provider, identity.Subject)
+		if findErr == nil && linked.Identifier != user.Identifier {
+			return ErrIdentityLinked
+		}
+	}
+	return err
+}
+
+func (repo *SqlUserRepository) loadIdentities(ctx context.Context, users []*User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byIdentifier := make(map[string]*User, len(users))
+	placeholders := make([]string, 0, len(users))
+	args := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		byIdentifier[user.Identifier] = user
+		args = append(args, user.Identifier)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	rows, err := repo.db.QueryContext(
+		ctx,
+		`SELECT identifier, provider, subject, linked_at FROM user_identities
+		WHERE identifier IN (`+strings.Join(placeholders, ", ")+`) ORDER BY linked_at, provider, subject`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var identifier string
+		var identity Identity
+		if err := rows.Scan(&identifier, &identity.Provider, &identity.Subject, &identity.LinkedAt); err != nil {
+			return err
+		}
+
+		identity.LinkedAt = identity.LinkedAt.UTC()
+		user := byIdentifier[identifier]
+		user.Identities = append(user.Identities, identity)
+	}
+	return rows.Err()
+}
+
+func saveIdentities(ctx context.Context, tx *sql.Tx, user *User) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_identities WHERE identifier = $1`, user.Identifier); err != nil {
+		return err
+	}
+
+	for _, identity := range user.Identities {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO user_identities (provider, subject, identifier, linked_at) VALUES ($1, $2, $3, $4)`,
+			identity.Provider, identity.Subject, user.Identifier, identity.LinkedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *SqlUserRepository) findBy(ctx context.Context, query string, value string) ([]*User, error) {
+	rows, err := repo.db.QueryContext(ctx, query, value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, repo.loadIdentities(ctx, users)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	user := new(User)
+	var attributes string
+
+	err := row.Scan(
+		&user.Identifier, &user.Passkey, &user.PasswordResetRequired, &user.SessionsRevokedAt, &user.Status, &user.SuspensionReason, &user.SuspendedUntil, &user.Email,
+		&user.EmailVerified, &user.Phone, &user.PhoneVerified, &user.DisplayName, &user.Locale, &user.Timezone,
+		&attributes, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(attributes), &user.Attributes); err != nil {
+		return nil, err
+	}
+
+	user.SessionsRevokedAt = user.SessionsRevokedAt.UTC()
+	user.SuspendedUntil = user.SuspendedUntil.UTC()
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.UpdatedAt = user.UpdatedAt.UTC()
+	user.LastLoginAt = user.LastLoginAt.UTC()
+	return user, nil
+}
+
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -1,14 +1,114 @@
 package user
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type status string
 
 const (
-	Active   = status("active")
-	Inactive = status("inactive")
+	PendingVerification = status("pending_verification")
+	Active              = status("active")
+	Suspended           = status("suspended")
+	Locked              = status("locked")
+	DeletedPendingPurge = status("deleted_pending_purge")
 )
 
+var statuses = []status{PendingVerification, Active, Suspended, Locked, DeletedPendingPurge}
+
+const legacyInactive = "inactive"
+
+func ParseStatus(value string) (status, error) {
+	for _, candidate := range statuses {
+		if string(candidate) == value {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("unknown status %s", value)
+}
+
+func (value *status) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if raw == legacyInactive {
+		raw = string(PendingVerification)
+	}
+	*value = status(raw)
+	return nil
+}
+
 type User struct {
-	Identifier string
-	Passkey    string
-	Status     status
+	Identifier            string
+	Passkey               string
+	PasswordResetRequired bool
+	SessionsRevokedAt     time.Time
+	Status                status
+	SuspensionReason      string
+	SuspendedUntil        time.Time
+	Email                 string
+	EmailVerified         bool
+	Phone                 string
+	PhoneVerified         bool
+	DisplayName           string
+	Locale                string
+	Timezone              string
+	Attributes            map[string]interface{}
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	LastLoginAt           time.Time
+	Identities            []Identity
+	Version               int64
+}
+
+type Identity struct {
+	Provider string
+	Subject  string
+	LinkedAt time.Time
+}
+
+func (user *User) SuspensionExpired(now time.Time) bool {
+	return user.Status == Suspended && !user.SuspendedUntil.IsZero() && !now.Before(user.SuspendedUntil)
+}
+
+func (user *User) HasIdentity(provider string, subject string) bool {
+	for _, identity := range user.Identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+func (user *User) Link(provider string, subject string) error {
+	if user.HasIdentity(provider, subject) {
+		return ErrIdentityLinked
+	}
+
+	user.Identities = append(user.Identities, Identity{Provider: provider, Subject: subject, LinkedAt: timestamp()})
+	return nil
+}
+
+func (user *User) Unlink(provider string, subject string) error {
+	for i, identity := range user.Identities {
+		if identity.Provider != provider || identity.Subject != subject {
+			continue
+		}
+
+		if user.Passkey == "" && len(user.Identities) == 1 {
+			return ErrLastIdentity
+		}
+
+		user.Identities = append(user.Identities[:i:i], user.Identities[i+1:]...)
+		return nil
+	}
+	return ErrIdentityNotFound
+}
+
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
 }
@@ -1,8 +1,15 @@
 package user
 
+import "context"
+
 type UserRepository interface {
-	FindByIdentifier(identifier string) (*User, error)
-	Create(user *User) error
-	Update(user *User) error
-	Remove(identifier string) error
+	FindByIdentifier(ctx context.Context, identifier string) (*User, error)
+	FindByEmail(ctx context.Context, email string) ([]*User, error)
+	FindByPhone(ctx context.Context, phone string) ([]*User, error)
+	FindByIdentity(ctx context.Context, provider string, subject string) (*User, error)
+	List(ctx context.Context, query *Query) (*Page, error)
+	Search(ctx context.Context, text string, query *Query) (*Page, error)
+	Create(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	Remove(ctx context.Context, identifier string) error
 }
@@ -1,106 +1,450 @@
 package user_test
 
 import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/Untanky/go-id/user"
+	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	bolt "go.etcd.io/bbolt"
+	_ "modernc.org/sqlite"
 )
 
 type UserRepoTestSuite struct {
 	suite.Suite
-	user0 *User
-	user1 *User
-	repo  UserRepository
+	newRepo func() UserRepository
+	user0   *User
+	user1   *User
+	repo    UserRepository
 }
 
 func (suite *UserRepoTestSuite) SetupTest() {
 	suite.user0 = &User{
-		Identifier: "abc",
-		Passkey:    "abc",
-		Status:     Active,
+		Identifier:    "abc",
+		Passkey:       "abc",
+		Status:        Active,
+		Email:         "abc@example.com",
+		EmailVerified: true,
+		Phone:         "+491234",
+		DisplayName:   "Abc",
+		Locale:        "de-DE",
+		Timezone:      "Europe/Berlin",
+		Attributes:    map[string]interface{}{"department": "sales", "seats": float64(3)},
+		LastLoginAt:   time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
 	}
 	suite.user1 = &User{
 		Identifier: "abc2",
 		Passkey:    "abc",
 		Status:     Active,
 	}
-	suite.repo = new(MemoryUserRepository)
+	suite.repo = suite.newRepo()
 }
 
 func (suite *UserRepoTestSuite) TestCreate_CreateTwoThenFindTwo() {
-	err := suite.repo.Create(suite.user0)
+	err := suite.repo.Create(context.Background(), suite.user0)
 	assert.Nil(suite.T(), err)
-	err = suite.repo.Create(suite.user1)
+	err = suite.repo.Create(context.Background(), suite.user1)
 	assert.Nil(suite.T(), err)
 
-	foundUser0, err := suite.repo.FindByIdentifier(suite.user0.Identifier)
+	foundUser0, err := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), suite.user0, foundUser0)
-	foundUser1, err := suite.repo.FindByIdentifier(suite.user1.Identifier)
+	foundUser1, err := suite.repo.FindByIdentifier(context.Background(), suite.user1.Identifier)
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), suite.user1, foundUser1)
 }
 
 func (suite *UserRepoTestSuite) TestCreate_ErrorWhenIdenfifierAlreadyExists() {
-	err := suite.repo.Create(suite.user0)
+	err := suite.repo.Create(context.Background(), suite.user0)
 	assert.Nil(suite.T(), err)
-	err = suite.repo.Create(suite.user0)
-	assert.ErrorContains(suite.T(), err, "already exists")
+	err = suite.repo.Create(context.Background(), suite.user0)
+	assert.ErrorIs(suite.T(), err, ErrUserExists)
 }
 
 func (suite *UserRepoTestSuite) TestUpdate_UpdateUserWhenFound() {
-	err := suite.repo.Create(suite.user0)
+	err := suite.repo.Create(context.Background(), suite.user0)
 	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), suite.user0.Version)
 
-	updatedUser := &User{
-		Identifier: suite.user0.Identifier,
-		Passkey:    "foo",
-		Status:     Inactive,
-	}
+	updatedUser, err := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	assert.Nil(suite.T(), err)
+	updatedUser.Passkey = "foo"
+	updatedUser.PasswordResetRequired = true
+	updatedUser.SessionsRevokedAt = time.Date(2022, 11, 1, 8, 30, 0, 0, time.UTC)
+	updatedUser.Status = Suspended
+	updatedUser.SuspensionReason = "abuse"
+	updatedUser.SuspendedUntil = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	updatedUser.PhoneVerified = true
+	updatedUser.Attributes["department"] = "support"
 
-	err = suite.repo.Update(updatedUser)
+	err = suite.repo.Update(context.Background(), updatedUser)
 	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), updatedUser.Version)
+	assert.False(suite.T(), updatedUser.UpdatedAt.Before(suite.user0.UpdatedAt))
 
-	foundUser0, err := suite.repo.FindByIdentifier(updatedUser.Identifier)
+	foundUser0, err := suite.repo.FindByIdentifier(context.Background(), updatedUser.Identifier)
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), updatedUser, foundUser0)
 }
 
+func (suite *UserRepoTestSuite) TestUpdate_ConflictWhenVersionIsStale() {
+	err := suite.repo.Create(context.Background(), suite.user0)
+	assert.Nil(suite.T(), err)
+
+	first, _ := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	second, _ := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+
+	first.Status = Locked
+	err = suite.repo.Update(context.Background(), first)
+	assert.Nil(suite.T(), err)
+
+	second.Passkey = "foo"
+	err = suite.repo.Update(context.Background(), second)
+	assert.ErrorIs(suite.T(), err, ErrConflict)
+	assert.Equal(suite.T(), int64(1), second.Version)
+
+	foundUser0, err := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), first, foundUser0)
+}
+
 func (suite *UserRepoTestSuite) TestUpdate_ErrorWhenUserNotFound() {
 	updatedUser := &User{
 		Identifier: suite.user0.Identifier,
 		Passkey:    "foo",
-		Status:     Inactive,
+		Status:     Locked,
 	}
 
-	err := suite.repo.Update(updatedUser)
+	err := suite.repo.Update(context.Background(), updatedUser)
 
-	assert.ErrorContains(suite.T(), err, "no user found")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
 }
 
 func (suite *UserRepoTestSuite) TestRemove_ErrorWhenUserNotFound() {
-	err := suite.repo.Remove(suite.user0.Identifier + "foo")
+	err := suite.repo.Remove(context.Background(), suite.user0.Identifier+"foo")
 
-	assert.ErrorContains(suite.T(), err, "no user found")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
 }
 
 func (suite *UserRepoTestSuite) TestRemove_ErrorWhenUserAlreadyRemoved() {
-	err := suite.repo.Create(suite.user0)
+	err := suite.repo.Create(context.Background(), suite.user0)
+	assert.Nil(suite.T(), err)
+
+	err = suite.repo.Remove(context.Background(), suite.user0.Identifier)
+
+	foundUser0, err := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+	assert.Nil(suite.T(), foundUser0)
+
+	err = suite.repo.Remove(context.Background(), suite.user0.Identifier)
+
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func (suite *UserRepoTestSuite) TestCreate_StampTimestamps() {
+	err := suite.repo.Create(context.Background(), suite.user0)
+	assert.Nil(suite.T(), err)
+
+	assert.False(suite.T(), suite.user0.CreatedAt.IsZero())
+	assert.Equal(suite.T(), suite.user0.CreatedAt, suite.user0.UpdatedAt)
+
+	foundUser0, err := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.user0.CreatedAt, foundUser0.CreatedAt)
+	assert.Equal(suite.T(), time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC), foundUser0.LastLoginAt)
+}
+
+func (suite *UserRepoTestSuite) TestFindByEmailAndPhone() {
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), suite.user0))
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), &User{Identifier: "abc1", Status: Active, Email: "abc@example.com"}))
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), suite.user1))
+
+	users, err := suite.repo.FindByEmail(context.Background(), "abc@example.com")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), users, 2)
+	assert.Equal(suite.T(), suite.user0, users[0])
+	assert.Equal(suite.T(), "abc1", users[1].Identifier)
+
+	users, err = suite.repo.FindByPhone(context.Background(), "+491234")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*User{suite.user0}, users)
+
+	users, err = suite.repo.FindByPhone(context.Background(), "+49999")
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), users)
+}
+
+func (suite *UserRepoTestSuite) TestFindByIdentity_ResolveEveryLinkedIdentity() {
+	suite.user0.Link("google", "123")
+	suite.user0.Link("corp", "alice")
+	suite.createUsers(suite.user0, suite.user1)
+
+	for _, identity := range suite.user0.Identities {
+		found, err := suite.repo.FindByIdentity(context.Background(), identity.Provider, identity.Subject)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), suite.user0, found)
+	}
+
+	_, err := suite.repo.FindByIdentity(context.Background(), "google", "alice")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func (suite *UserRepoTestSuite) TestUpdate_LinkAndUnlinkIdentities() {
+	suite.createUsers(suite.user0)
+	found, _ := suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	found.Link("google", "123")
+	assert.Nil(suite.T(), suite.repo.Update(context.Background(), found))
+
+	linked, err := suite.repo.FindByIdentity(context.Background(), "google", "123")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), found.Identities, linked.Identities)
+
+	assert.Nil(suite.T(), linked.Unlink("google", "123"))
+	assert.Nil(suite.T(), suite.repo.Update(context.Background(), linked))
+
+	_, err = suite.repo.FindByIdentity(context.Background(), "google", "123")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+	users, _ := suite.repo.FindByEmail(context.Background(), suite.user0.Email)
+	assert.Empty(suite.T(), users[0].Identities)
+}
+
+func (suite *UserRepoTestSuite) TestCreateAndUpdate_ErrorWhenIdentityLinkedToOtherUser() {
+	suite.user0.Link("google", "123")
+	suite.createUsers(suite.user0)
+
+	suite.user1.Link("google", "123")
+	assert.ErrorIs(suite.T(), suite.repo.Create(context.Background(), suite.user1), ErrIdentityLinked)
+
+	suite.user1.Identities = nil
+	suite.createUsers(suite.user1)
+	found, _ := suite.repo.FindByIdentifier(context.Background(), suite.user1.Identifier)
+	found.Link("google", "123")
+	assert.ErrorIs(suite.T(), suite.repo.Update(context.Background(), found), ErrIdentityLinked)
+
+	linked, err := suite.repo.FindByIdentity(context.Background(), "google", "123")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.user0.Identifier, linked.Identifier)
+}
+
+func (suite *UserRepoTestSuite) TestRemove_ReleaseLinkedIdentities() {
+	suite.user0.Link("google", "123")
+	suite.createUsers(suite.user0)
+
+	assert.Nil(suite.T(), suite.repo.Remove(context.Background(), suite.user0.Identifier))
+
+	_, err := suite.repo.FindByIdentity(context.Background(), "google", "123")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+	suite.user1.Link("google", "123")
+	assert.Nil(suite.T(), suite.repo.Create(context.Background(), suite.user1))
+}
+
+func (suite *UserRepoTestSuite) createUsers(users ...*User) {
+	for _, user := range users {
+		assert.Nil(suite.T(), suite.repo.Create(context.Background(), user))
+	}
+}
+
+func (suite *UserRepoTestSuite) setCreatedAt(identifier string, createdAt time.Time) {
+	user, err := suite.repo.FindByIdentifier(context.Background(), identifier)
+	assert.Nil(suite.T(), err)
+	user.CreatedAt = createdAt
+	assert.Nil(suite.T(), suite.repo.Update(context.Background(), user))
+}
+
+func identifiers(page *Page) []string {
+	result := []string{}
+	for _, user := range page.Users {
+		result = append(result, user.Identifier)
+	}
+	return result
+}
+
+func (suite *UserRepoTestSuite) TestList_PaginateInIdentifierOrder() {
+	suite.createUsers(
+		&User{Identifier: "u3", Status: Active},
+		&User{Identifier: "u1", Status: Active},
+		&User{Identifier: "u4", Status: Active},
+		&User{Identifier: "u0", Status: Active},
+		&User{Identifier: "u2", Status: Active},
+	)
+
+	page, err := suite.repo.List(context.Background(), &Query{Limit: 2})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"u0", "u1"}, identifiers(page))
+	assert.NotEmpty(suite.T(), page.NextCursor)
+
+	page, err = suite.repo.List(context.Background(), &Query{Limit: 2, Cursor: page.NextCursor})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"u2", "u3"}, identifiers(page))
+
+	page, err = suite.repo.List(context.Background(), &Query{Limit: 2, Cursor: page.NextCursor})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"u4"}, identifiers(page))
+	assert.Empty(suite.T(), page.NextCursor)
+}
+
+func (suite *UserRepoTestSuite) TestList_SortByCreatedAtDescending() {
+	suite.createUsers(
+		&User{Identifier: "a", Status: Active},
+		&User{Identifier: "b", Status: Active},
+		&User{Identifier: "c", Status: Active},
+		&User{Identifier: "d", Status: Active},
+	)
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.setCreatedAt("a", base.Add(3*time.Hour))
+	suite.setCreatedAt("b", base.Add(1*time.Hour))
+	suite.setCreatedAt("c", base.Add(3*time.Hour))
+	suite.setCreatedAt("d", base.Add(2*time.Hour))
+
+	query := &Query{SortBy: SortByCreatedAt, Descending: true, Limit: 3}
+	page, err := suite.repo.List(context.Background(), query)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"c", "a", "d"}, identifiers(page))
+
+	query.Cursor = page.NextCursor
+	page, err = suite.repo.List(context.Background(), query)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"b"}, identifiers(page))
+}
+
+func (suite *UserRepoTestSuite) TestList_FilterByStatusCreationDateAndAttributes() {
+	suite.createUsers(
+		&User{Identifier: "a", Status: Active, Attributes: map[string]interface{}{"department": "sales", "seats": float64(3)}},
+		&User{Identifier: "b", Status: Locked, Attributes: map[string]interface{}{"department": "sales"}},
+		&User{Identifier: "c", Status: Active, Attributes: map[string]interface{}{"department": "support"}},
+		&User{Identifier: "d", Status: Active},
+	)
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.setCreatedAt("a", base.Add(1*time.Hour))
+	suite.setCreatedAt("b", base.Add(2*time.Hour))
+	suite.setCreatedAt("c", base.Add(3*time.Hour))
+	suite.setCreatedAt("d", base.Add(4*time.Hour))
+
+	page, err := suite.repo.List(context.Background(), &Query{Status: Active})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"a", "c", "d"}, identifiers(page))
+
+	page, err = suite.repo.List(context.Background(), &Query{CreatedAfter: base.Add(time.Hour), CreatedBefore: base.Add(4 * time.Hour)})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"b", "c"}, identifiers(page))
+
+	page, err = suite.repo.List(context.Background(), &Query{Attributes: map[string]string{"department": "sales"}, Limit: 1})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"a"}, identifiers(page))
+
+	page, err = suite.repo.List(context.Background(), &Query{Attributes: map[string]string{"department": "sales"}, Limit: 1, Cursor: page.NextCursor})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"b"}, identifiers(page))
+	assert.Empty(suite.T(), page.NextCursor)
+
+	page, err = suite.repo.List(context.Background(), &Query{Attributes: map[string]string{"seats": "3"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"a"}, identifiers(page))
+}
+
+func (suite *UserRepoTestSuite) TestSearch_MatchIdentifierEmailAndDisplayName() {
+	suite.createUsers(
+		&User{Identifier: "alice", Status: Active},
+		&User{Identifier: "b", Status: Active, Email: "ALICE@example.com"},
+		&User{Identifier: "c", Status: Active, DisplayName: "Alice Liddell"},
+		&User{Identifier: "d", Status: Active, DisplayName: "Bob"},
+		&User{Identifier: "100%", Status: Active},
+	)
+
+	page, err := suite.repo.Search(context.Background(), "Alice", &Query{})
 	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"alice", "b", "c"}, identifiers(page))
 
-	err = suite.repo.Remove(suite.user0.Identifier)
+	page, err = suite.repo.Search(context.Background(), "0%", &Query{})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"100%"}, identifiers(page))
 
-	foundUser0, err := suite.repo.FindByIdentifier(suite.user0.Identifier)
-	assert.ErrorContains(suite.T(), err, "no user found")
+	page, err = suite.repo.Search(context.Background(), "a_i", &Query{})
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), page.Users)
+}
+
+func (suite *UserRepoTestSuite) TestList_ErrorWithInvalidCursorOrSort() {
+	_, err := suite.repo.List(context.Background(), &Query{Cursor: "not a cursor"})
+	assert.ErrorIs(suite.T(), err, ErrInvalidCursor)
+
+	_, err = suite.repo.List(context.Background(), &Query{SortBy: "passkey"})
+	assert.ErrorContains(suite.T(), err, "cannot sort by passkey")
+}
+
+func (suite *UserRepoTestSuite) TestCancelledContext_ErrorWithoutTouchingStore() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := suite.repo.Create(ctx, suite.user0)
+	assert.ErrorIs(suite.T(), err, context.Canceled)
+
+	foundUser0, err := suite.repo.FindByIdentifier(ctx, suite.user0.Identifier)
+	assert.ErrorIs(suite.T(), err, context.Canceled)
 	assert.Nil(suite.T(), foundUser0)
 
-	err = suite.repo.Remove(suite.user0.Identifier)
+	_, err = suite.repo.FindByIdentifier(context.Background(), suite.user0.Identifier)
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func newSqlUserRepository(t *testing.T, driver string, dsn string) func() UserRepository {
+	return func() UserRepository {
+		db, err := sql.Open(driver, dsn)
+		assert.Nil(t, err)
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(`DROP TABLE IF EXISTS users`)
+		assert.Nil(t, err)
+		_, err = db.Exec(`DROP TABLE IF EXISTS user_identities`)
+		assert.Nil(t, err)
+		_, err = db.Exec(`DROP TABLE IF EXISTS schema_migrations`)
+		assert.Nil(t, err)
+		assert.Nil(t, Migrate(db))
+
+		repo := new(SqlUserRepository)
+		repo.Init(db)
+		return repo
+	}
+}
+
+func newBoltUserRepository(t *testing.T) func() UserRepository {
+	return func() UserRepository {
+		db, err := bolt.Open(filepath.Join(t.TempDir(), "users.db"), 0600, nil)
+		assert.Nil(t, err)
+		t.Cleanup(func() { db.Close() })
 
-	assert.ErrorContains(suite.T(), err, "no user found")
+		repo := new(BoltUserRepository)
+		assert.Nil(t, repo.Init(db))
+		return repo
+	}
 }
 
 func TestUserRepository(t *testing.T) {
-	suite.Run(t, new(UserRepoTestSuite))
+	suite.Run(t, &UserRepoTestSuite{newRepo: func() UserRepository {
+		return new(MemoryUserRepository)
+	}})
+}
+
+func TestSqlUserRepository_Sqlite(t *testing.T) {
+	suite.Run(t, &UserRepoTestSuite{newRepo: newSqlUserRepository(t, "sqlite", "file::memory:")})
+}
+
+func TestBoltUserRepository(t *testing.T) {
+	suite.Run(t, &UserRepoTestSuite{newRepo: newBoltUserRepository(t)})
+}
+
+func TestSqlUserRepository_Postgres(t *testing.T) {
+	dsn := os.Getenv("GO_ID_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GO_ID_POSTGRES_DSN not set")
+	}
+
+	suite.Run(t, &UserRepoTestSuite{newRepo: newSqlUserRepository(t, "postgres", dsn)})
 }
@@ -1,9 +1,18 @@
 package user
 
 import (
-	"errors"
+	"context"
+	"time"
 )
 
+var transitions = map[status][]status{
+	PendingVerification: {Active, DeletedPendingPurge},
+	Active:              {Suspended, Locked, DeletedPendingPurge},
+	Suspended:           {Active, Suspended, Locked, DeletedPendingPurge},
+	Locked:              {Active, DeletedPendingPurge},
+	DeletedPendingPurge: {Active},
+}
+
 type UserService struct {
 	userRepo UserRepository
 }
@@ -12,40 +21,104 @@ func (service *UserService) Init(userRepo UserRepository) {
 	service.userRepo = userRepo
 }
 
-func (service *UserService) Activate(identifier string) error {
-	user, err := service.userRepo.FindByIdentifier(identifier)
+func (service *UserService) Activate(ctx context.Context, identifier string) error {
+	return service.transition(ctx, identifier, Active, func(user *User) {
+		user.SuspensionReason = ""
+		user.SuspendedUntil = time.Time{}
+	})
+}
+
+func (service *UserService) Suspend(ctx context.Context, identifier string, reason string, until time.Time) error {
+	return service.transition(ctx, identifier, Suspended, func(user *User) {
+		user.SuspensionReason = reason
+		user.SuspendedUntil = until.UTC().Truncate(time.Microsecond)
+	})
+}
+
+func (service *UserService) Lock(ctx context.Context, identifier string) error {
+	return service.transition(ctx, identifier, Locked, func(user *User) {
+		user.SuspensionReason = ""
+		user.SuspendedUntil = time.Time{}
+	})
+}
+
+func (service *UserService) Delete(ctx context.Context, identifier string) error {
+	return service.transition(ctx, identifier, DeletedPendingPurge, func(user *User) {
+		user.SuspensionReason = ""
+		user.SuspendedUntil = time.Time{}
+	})
+}
 
+func (service *UserService) Purge(ctx context.Context, identifier string) error {
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
 	if err != nil {
 		return err
 	}
 
-	if user.Status == Active {
-		return errors.New("user is already active")
+	if user.Status != DeletedPendingPurge {
+		return &TransitionError{From: user.Status, To: "purged"}
 	}
-	service.userRepo.Update(user)
 
-	user.Status = Active
-	return nil
+	return service.userRepo.Remove(ctx, identifier)
+}
+
+func (service *UserService) RequirePasswordReset(ctx context.Context, identifier string) error {
+	return service.modify(ctx, identifier, func(user *User) {
+		user.PasswordResetRequired = true
+	})
 }
 
-func (service *UserService) Inactivate(identifier string) error {
-	user, err := service.userRepo.FindByIdentifier(identifier)
+func (service *UserService) RevokeSessions(ctx context.Context, identifier string) error {
+	return service.modify(ctx, identifier, func(user *User) {
+		user.SessionsRevokedAt = timestamp()
+	})
+}
+
+func (service *UserService) UnlinkIdentity(ctx context.Context, identifier string, provider string, subject string) error {
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return err
+	}
+
+	if err := user.Unlink(provider, subject); err != nil {
+		return err
+	}
+
+	return service.userRepo.Update(ctx, user)
+}
 
+func (service *UserService) modify(ctx context.Context, identifier string, apply func(user *User)) error {
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
 	if err != nil {
 		return err
 	}
 
-	if user.Status == Inactive {
-		return errors.New("user is already inactive")
+	apply(user)
+
+	return service.userRepo.Update(ctx, user)
+}
+
+func (service *UserService) transition(ctx context.Context, identifier string, to status, apply func(user *User)) error {
+	user, err := service.userRepo.FindByIdentifier(ctx, identifier)
+	if err != nil {
+		return err
 	}
 
-	user.Status = Inactive
+	if !canTransition(user.Status, to) {
+		return &TransitionError{From: user.Status, To: to}
+	}
 
-	service.userRepo.Update(user)
+	user.Status = to
+	apply(user)
 
-	return nil
+	return service.userRepo.Update(ctx, user)
 }
 
-func (service *UserService) Delete(identifier string) error {
-	return service.userRepo.Remove(identifier)
+func canTransition(from status, to status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
 }
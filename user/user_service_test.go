@@ -1,7 +1,9 @@
 package user_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	. "github.com/Untanky/go-id/user"
 	"github.com/stretchr/testify/assert"
@@ -31,80 +33,192 @@ func (suite *UserServiceTestSuite) SetupTest() {
 	suite.knownUsers = []*User{
 		{Identifier: knownUserId + "0", Passkey: knownUserKey + "0", Status: Active},
 		{Identifier: knownUserId + "1", Passkey: knownUserKey + "1", Status: Active},
-		{Identifier: knownUserId + "2", Passkey: knownUserKey + "2", Status: Inactive},
+		{Identifier: knownUserId + "2", Passkey: knownUserKey + "2", Status: PendingVerification},
 	}
 
 	for _, user := range suite.knownUsers {
-		suite.userRepo.Create(user)
+		suite.userRepo.Create(context.Background(), user)
 	}
 }
 
-func (suite *UserServiceTestSuite) TestInactivate_SetStatusToDeactivated() {
-	user0 := suite.knownUsers[0]
+func (suite *UserServiceTestSuite) findStatus(identifier string) interface{} {
+	user, err := suite.userRepo.FindByIdentifier(context.Background(), identifier)
+	assert.Nil(suite.T(), err)
+	return user.Status
+}
+
+func (suite *UserServiceTestSuite) TestActivate_VerifyPendingUser() {
+	user2 := suite.knownUsers[2]
 
-	err := suite.service.Inactivate(user0.Identifier)
+	err := suite.service.Activate(context.Background(), user2.Identifier)
 
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), user0.Status, Inactive)
+	assert.Equal(suite.T(), suite.findStatus(user2.Identifier), Active)
+}
+
+func (suite *UserServiceTestSuite) TestActivate_ErrorWhenStatusIsAlreadyActive() {
+	user0 := suite.knownUsers[0]
+	err := suite.service.Activate(context.Background(), user0.Identifier)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidTransition)
+	assert.ErrorContains(suite.T(), err, "cannot transition user from active to active")
+}
+
+func (suite *UserServiceTestSuite) TestActivate_ErrorWhenUserNotFound() {
+	err := suite.service.Activate(context.Background(), unknownUserId)
+
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
 }
 
-func (suite *UserServiceTestSuite) TestInactivate_ErrWhenAlreadyDeactivated() {
+func (suite *UserServiceTestSuite) TestSuspend_StoreReasonAndUntil() {
 	user0 := suite.knownUsers[0]
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	err := suite.service.Inactivate(user0.Identifier)
+	err := suite.service.Suspend(context.Background(), user0.Identifier, "abuse", until)
 
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), user0.Status, Inactive)
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
+	assert.Equal(suite.T(), Suspended, found.Status)
+	assert.Equal(suite.T(), "abuse", found.SuspensionReason)
+	assert.Equal(suite.T(), until, found.SuspendedUntil)
+}
+
+func (suite *UserServiceTestSuite) TestActivate_ClearSuspension() {
+	user0 := suite.knownUsers[0]
+	suite.service.Suspend(context.Background(), user0.Identifier, "abuse", time.Now().Add(time.Hour))
 
-	err = suite.service.Inactivate(user0.Identifier)
-	assert.ErrorContains(suite.T(), err, "user is already inactive")
+	err := suite.service.Activate(context.Background(), user0.Identifier)
+
+	assert.Nil(suite.T(), err)
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
+	assert.Equal(suite.T(), Active, found.Status)
+	assert.Empty(suite.T(), found.SuspensionReason)
+	assert.True(suite.T(), found.SuspendedUntil.IsZero())
 }
 
-func (suite *UserServiceTestSuite) TestInactivate_ErrorWhenUserNotfound() {
-	err := suite.service.Inactivate(unknownUserId)
+func (suite *UserServiceTestSuite) TestSuspend_ErrorWhenPendingVerification() {
+	user2 := suite.knownUsers[2]
 
-	assert.ErrorContains(suite.T(), err, "no user found")
+	err := suite.service.Suspend(context.Background(), user2.Identifier, "abuse", time.Time{})
+
+	var transitionErr *TransitionError
+	assert.ErrorAs(suite.T(), err, &transitionErr)
+	assert.Equal(suite.T(), PendingVerification, transitionErr.From)
+	assert.Equal(suite.T(), Suspended, transitionErr.To)
 }
 
-func (suite *UserServiceTestSuite) TestActivate_SetStatusToActiveWhenStatusIsDeactivated() {
+func (suite *UserServiceTestSuite) TestLock_LockAndUnlock() {
 	user0 := suite.knownUsers[0]
-	err := suite.service.Inactivate(user0.Identifier)
 
-	err = suite.service.Activate(user0.Identifier)
+	err := suite.service.Lock(context.Background(), user0.Identifier)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.findStatus(user0.Identifier), Locked)
+
+	err = suite.service.Lock(context.Background(), user0.Identifier)
+	assert.ErrorIs(suite.T(), err, ErrInvalidTransition)
 
+	err = suite.service.Activate(context.Background(), user0.Identifier)
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), user0.Status, Active)
+	assert.Equal(suite.T(), suite.findStatus(user0.Identifier), Active)
 }
 
-func (suite *UserServiceTestSuite) TestActivate_ErrorWhenStatusIsAlreadyActive() {
+func (suite *UserServiceTestSuite) TestDelete_MarkUserForPurge() {
 	user0 := suite.knownUsers[0]
-	err := suite.service.Activate(user0.Identifier)
 
-	assert.ErrorContains(suite.T(), err, "user is already active")
+	err := suite.service.Delete(context.Background(), user0.Identifier)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.findStatus(user0.Identifier), DeletedPendingPurge)
 }
 
-func (suite *UserServiceTestSuite) TestActivate_ErrorWhenUserNotFound() {
-	err := suite.service.Activate(unknownUserId)
+func (suite *UserServiceTestSuite) TestDelete_ErrorWhenUserNotFound() {
+	err := suite.service.Delete(context.Background(), unknownUserId)
 
-	assert.ErrorContains(suite.T(), err, "no user found")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
 }
 
-func (suite *UserServiceTestSuite) TestDelete_RemoveUserFromKnownUsers() {
+func (suite *UserServiceTestSuite) TestActivate_RestoreDeletedUser() {
 	user0 := suite.knownUsers[0]
+	suite.service.Delete(context.Background(), user0.Identifier)
 
-	err := suite.service.Delete(user0.Identifier)
+	err := suite.service.Activate(context.Background(), user0.Identifier)
 
 	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.findStatus(user0.Identifier), Active)
+}
 
-	foundUser, err := suite.userRepo.FindByIdentifier(user0.Identifier)
-	assert.Error(suite.T(), err, "no user found")
+func (suite *UserServiceTestSuite) TestPurge_RemoveDeletedUser() {
+	user0 := suite.knownUsers[0]
+	suite.service.Delete(context.Background(), user0.Identifier)
+
+	err := suite.service.Purge(context.Background(), user0.Identifier)
+
+	assert.Nil(suite.T(), err)
+	foundUser, err := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
 	assert.Nil(suite.T(), foundUser)
 }
 
-func (suite *UserServiceTestSuite) TestDelete_ErrorWhenUserNotFound() {
-	err := suite.service.Delete(unknownUserId)
+func (suite *UserServiceTestSuite) TestPurge_ErrorWhenNotDeleted() {
+	user0 := suite.knownUsers[0]
+
+	err := suite.service.Purge(context.Background(), user0.Identifier)
+
+	assert.ErrorIs(suite.T(), err, ErrInvalidTransition)
+	assert.Equal(suite.T(), suite.findStatus(user0.Identifier), Active)
+}
+
+func (suite *UserServiceTestSuite) TestRequirePasswordReset_FlagUser() {
+	user0 := suite.knownUsers[0]
+
+	err := suite.service.RequirePasswordReset(context.Background(), user0.Identifier)
 
-	assert.ErrorContains(suite.T(), err, "no user found")
+	assert.Nil(suite.T(), err)
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
+	assert.True(suite.T(), found.PasswordResetRequired)
+}
+
+func (suite *UserServiceTestSuite) TestRevokeSessions_StampRevocationTime() {
+	user0 := suite.knownUsers[0]
+	before := time.Now().UTC().Truncate(time.Microsecond)
+
+	err := suite.service.RevokeSessions(context.Background(), user0.Identifier)
+
+	assert.Nil(suite.T(), err)
+	found, _ := suite.userRepo.FindByIdentifier(context.Background(), user0.Identifier)
+	assert.False(suite.T(), found.SessionsRevokedAt.Before(before))
+}
+
+func (suite *UserServiceTestSuite) TestRevokeSessions_ErrorWhenUserNotFound() {
+	err := suite.service.RevokeSessions(context.Background(), unknownUserId)
+
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func (suite *UserServiceTestSuite) TestUnlinkIdentity_RemoveIdentity() {
+	suite.userRepo.Create(context.Background(), &User{Identifier: "linked", Passkey: knownUserKey, Status: Active, Identities: []Identity{{Provider: "google", Subject: "123"}}})
+
+	err := suite.service.UnlinkIdentity(context.Background(), "linked", "google", "123")
+
+	assert.Nil(suite.T(), err)
+	_, err = suite.userRepo.FindByIdentity(context.Background(), "google", "123")
+	assert.ErrorIs(suite.T(), err, ErrUserNotFound)
+}
+
+func (suite *UserServiceTestSuite) TestUnlinkIdentity_ErrorWhenNotLinked() {
+	err := suite.service.UnlinkIdentity(context.Background(), suite.knownUsers[0].Identifier, "google", "123")
+
+	assert.ErrorIs(suite.T(), err, ErrIdentityNotFound)
+}
+
+func (suite *UserServiceTestSuite) TestUnlinkIdentity_KeepOnlySignInMethod() {
+	suite.userRepo.Create(context.Background(), &User{Identifier: "federated", Status: Active, Identities: []Identity{{Provider: "google", Subject: "123"}}})
+
+	err := suite.service.UnlinkIdentity(context.Background(), "federated", "google", "123")
+
+	assert.ErrorIs(suite.T(), err, ErrLastIdentity)
+	_, err = suite.userRepo.FindByIdentity(context.Background(), "google", "123")
+	assert.Nil(suite.T(), err)
 }
 
 func TestUserService(t *testing.T) {
@@ -0,0 +1,81 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	NoneAttestation   = "none"
+	PackedAttestation = "packed"
+)
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AuthData []byte          `cbor:"authData"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+}
+
+type packedStatement struct {
+	Alg int64    `cbor:"alg"`
+	Sig []byte   `cbor:"sig"`
+	X5c [][]byte `cbor:"x5c,omitempty"`
+}
+
+func verifyAttestation(object *attestationObject, data *authenticatorData, clientDataHash []byte) error {
+	switch object.Fmt {
+	case NoneAttestation:
+		return nil
+	case PackedAttestation:
+		return verifyPackedAttestation(object, data, clientDataHash)
+	}
+	return errors.New("unsupported attestation format")
+}
+
+func verifyPackedAttestation(object *attestationObject, data *authenticatorData, clientDataHash []byte) error {
+	var statement packedStatement
+	if err := cbor.Unmarshal(object.AttStmt, &statement); err != nil {
+		return errors.New("cannot decode attestation statement")
+	}
+
+	signed := append(append([]byte{}, object.AuthData...), clientDataHash...)
+
+	if len(statement.X5c) > 0 {
+		certificate, err := x509.ParseCertificate(statement.X5c[0])
+		if err != nil {
+			return errors.New("cannot parse attestation certificate")
+		}
+
+		if !certificateMatches(certificate, statement.Alg) {
+			return errors.New("attestation certificate does not match algorithm")
+		}
+		return verifySignature(statement.Alg, certificate.PublicKey, signed, statement.Sig)
+	}
+
+	alg, publicKey, err := parsePublicKey(data.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	if alg != statement.Alg {
+		return errors.New("attestation algorithm does not match credential")
+	}
+	return verifySignature(alg, publicKey, signed, statement.Sig)
+}
+
+func certificateMatches(certificate *x509.Certificate, alg int64) bool {
+	switch alg {
+	case coseAlgES256:
+		key, ok := certificate.PublicKey.(*ecdsa.PublicKey)
+		return ok && key.Curve == elliptic.P256()
+	case coseAlgRS256:
+		_, ok := certificate.PublicKey.(*rsa.PublicKey)
+		return ok
+	}
+	return false
+}
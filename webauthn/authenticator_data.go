@@ -0,0 +1,68 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	flagUserPresent  byte = 0x01
+	flagUserVerified byte = 0x04
+	flagAttestedData byte = 0x40
+)
+
+type authenticatorData struct {
+	RpIdHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialId []byte
+	PublicKey    []byte
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+
+	data := &authenticatorData{
+		RpIdHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if data.Flags&flagAttestedData == 0 {
+		return data, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("attested credential data too short")
+	}
+
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, errors.New("attested credential data too short")
+	}
+	data.CredentialId = rest[:idLength]
+
+	var publicKey cbor.RawMessage
+	decoder := cbor.NewDecoder(bytes.NewReader(rest[idLength:]))
+	if err := decoder.Decode(&publicKey); err != nil {
+		return nil, errors.New("cannot decode credential public key")
+	}
+	data.PublicKey = publicKey
+
+	return data, nil
+}
+
+func (data *authenticatorData) UserPresent() bool {
+	return data.Flags&flagUserPresent != 0
+}
+
+func (data *authenticatorData) UserVerified() bool {
+	return data.Flags&flagUserVerified != 0
+}
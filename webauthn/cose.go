@@ -0,0 +1,97 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseAlgES256 = -7
+	coseAlgRS256 = -257
+
+	coseCurveP256 = 1
+)
+
+type coseKey struct {
+	Kty   int64  `cbor:"1,keyasint"`
+	Alg   int64  `cbor:"3,keyasint"`
+	Curve int64  `cbor:"-1,keyasint,omitempty"`
+	X     []byte `cbor:"-2,keyasint,omitempty"`
+	Y     []byte `cbor:"-3,keyasint,omitempty"`
+}
+
+type coseRsaKey struct {
+	Kty int64  `cbor:"1,keyasint"`
+	Alg int64  `cbor:"3,keyasint"`
+	N   []byte `cbor:"-1,keyasint"`
+	E   []byte `cbor:"-2,keyasint"`
+}
+
+func parsePublicKey(raw []byte) (int64, crypto.PublicKey, error) {
+	var key coseKey
+	if err := cbor.Unmarshal(raw, &key); err != nil {
+		return 0, nil, errors.New("cannot decode public key")
+	}
+
+	switch key.Kty {
+	case coseKeyTypeEC2:
+		if key.Alg != coseAlgES256 || key.Curve != coseCurveP256 {
+			return 0, nil, errors.New("unsupported public key algorithm")
+		}
+
+		publicKey := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(key.X),
+			Y:     new(big.Int).SetBytes(key.Y),
+		}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return 0, nil, errors.New("invalid public key")
+		}
+		return key.Alg, publicKey, nil
+	case coseKeyTypeRSA:
+		var rsaKey coseRsaKey
+		if err := cbor.Unmarshal(raw, &rsaKey); err != nil {
+			return 0, nil, errors.New("cannot decode public key")
+		}
+
+		if rsaKey.Alg != coseAlgRS256 {
+			return 0, nil, errors.New("unsupported public key algorithm")
+		}
+
+		return rsaKey.Alg, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(rsaKey.N),
+			E: int(new(big.Int).SetBytes(rsaKey.E).Int64()),
+		}, nil
+	}
+	return 0, nil, errors.New("unsupported public key type")
+}
+
+func verifySignature(alg int64, publicKey crypto.PublicKey, data []byte, signature []byte) error {
+	digest := sha256.Sum256(data)
+
+	switch alg {
+	case coseAlgES256:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case coseAlgRS256:
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.New("unsupported signature algorithm")
+}
@@ -0,0 +1,9 @@
+package webauthn
+
+type Credential struct {
+	Id                []byte
+	UserId            string
+	PublicKey         []byte
+	SignCount         uint32
+	AttestationFormat string
+}
@@ -0,0 +1,8 @@
+package webauthn
+
+type CredentialRepository interface {
+	FindById(id []byte) (*Credential, error)
+	FindByUser(userId string) ([]*Credential, error)
+	Create(credential *Credential) error
+	Update(credential *Credential) error
+}
@@ -0,0 +1,66 @@
+package webauthn_test
+
+import (
+	"testing"
+
+	. "github.com/Untanky/go-id/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CredentialRepoTestSuite struct {
+	suite.Suite
+	credential *Credential
+	repo       CredentialRepository
+}
+
+func (suite *CredentialRepoTestSuite) SetupTest() {
+	suite.credential = &Credential{
+		Id:        []byte{1, 2, 3},
+		UserId:    "abc",
+		PublicKey: []byte{4, 5, 6},
+	}
+	suite.repo = new(MemoryCredentialRepository)
+}
+
+func (suite *CredentialRepoTestSuite) TestCreate_FindByIdAndUser() {
+	err := suite.repo.Create(suite.credential)
+	assert.Nil(suite.T(), err)
+
+	found, err := suite.repo.FindById([]byte{1, 2, 3})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.credential, found)
+
+	credentials, err := suite.repo.FindByUser("abc")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*Credential{suite.credential}, credentials)
+}
+
+func (suite *CredentialRepoTestSuite) TestCreate_ErrorWhenIdAlreadyExists() {
+	suite.repo.Create(suite.credential)
+
+	err := suite.repo.Create(suite.credential)
+
+	assert.ErrorContains(suite.T(), err, "already exists")
+}
+
+func (suite *CredentialRepoTestSuite) TestUpdate_StoreSignCount() {
+	suite.repo.Create(suite.credential)
+
+	updated := &Credential{Id: suite.credential.Id, UserId: "abc", SignCount: 5}
+	err := suite.repo.Update(updated)
+	assert.Nil(suite.T(), err)
+
+	found, _ := suite.repo.FindById(suite.credential.Id)
+	assert.Equal(suite.T(), uint32(5), found.SignCount)
+}
+
+func (suite *CredentialRepoTestSuite) TestUpdate_ErrorWhenCredentialNotFound() {
+	err := suite.repo.Update(suite.credential)
+
+	assert.ErrorContains(suite.T(), err, "no credential found")
+}
+
+func TestCredentialRepository(t *testing.T) {
+	suite.Run(t, new(CredentialRepoTestSuite))
+}
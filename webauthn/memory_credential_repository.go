@@ -0,0 +1,49 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+)
+
+type MemoryCredentialRepository struct {
+	credentials []*Credential
+}
+
+func (repo *MemoryCredentialRepository) FindById(id []byte) (*Credential, error) {
+	for _, credential := range repo.credentials {
+		if bytes.Equal(credential.Id, id) {
+			return credential, nil
+		}
+	}
+	return nil, errors.New("no credential found")
+}
+
+func (repo *MemoryCredentialRepository) FindByUser(userId string) ([]*Credential, error) {
+	credentials := []*Credential{}
+
+	for _, credential := range repo.credentials {
+		if credential.UserId == userId {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (repo *MemoryCredentialRepository) Create(credential *Credential) error {
+	if found, _ := repo.FindById(credential.Id); found != nil {
+		return errors.New("credential already exists")
+	}
+
+	repo.credentials = append(repo.credentials, credential)
+	return nil
+}
+
+func (repo *MemoryCredentialRepository) Update(credential *Credential) error {
+	for index, c := range repo.credentials {
+		if bytes.Equal(c.Id, credential.Id) {
+			repo.credentials[index] = credential
+			return nil
+		}
+	}
+	return errors.New("no credential found")
+}
@@ -0,0 +1,88 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+type Base64URL []byte
+
+func (value Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(value))
+}
+
+func (value *Base64URL) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return err
+	}
+
+	*value = decoded
+	return nil
+}
+
+type RelyingParty struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Origin string `json:"-"`
+}
+
+type UserEntity struct {
+	Id          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	Id   Base64URL `json:"id"`
+}
+
+type CreationOptions struct {
+	Challenge          Base64URL              `json:"challenge"`
+	RelyingParty       RelyingParty           `json:"rp"`
+	User               UserEntity             `json:"user"`
+	Parameters         []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout            int64                  `json:"timeout"`
+	ExcludeCredentials []CredentialDescriptor `json:"excludeCredentials"`
+	Attestation        string                 `json:"attestation"`
+}
+
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	RelyingPartyId   string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type AttestationResponse struct {
+	Id                Base64URL `json:"rawId"`
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AttestationObject Base64URL `json:"attestationObject"`
+}
+
+type AssertionResponse struct {
+	Id                Base64URL `json:"rawId"`
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AuthenticatorData Base64URL `json:"authenticatorData"`
+	Signature         Base64URL `json:"signature"`
+	UserHandle        Base64URL `json:"userHandle"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
@@ -0,0 +1,276 @@
+package webauthn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+type ceremony string
+
+const (
+	registrationCeremony ceremony = "webauthn.create"
+	loginCeremony        ceremony = "webauthn.get"
+
+	ceremonyTimeout = 5 * time.Minute
+)
+
+type session struct {
+	UserId   string
+	Ceremony ceremony
+	Expires  time.Time
+}
+
+type Assertion struct {
+	Credential   *Credential
+	UserVerified bool
+}
+
+type WebAuthnService struct {
+	relyingParty   RelyingParty
+	credentialRepo CredentialRepository
+	sessions       map[string]session
+	lock           sync.Mutex
+}
+
+func (service *WebAuthnService) Init(relyingParty RelyingParty, credentialRepo CredentialRepository) {
+	service.relyingParty = relyingParty
+	service.credentialRepo = credentialRepo
+	service.sessions = map[string]session{}
+}
+
+func (service *WebAuthnService) BeginRegistration(userId string) (*CreationOptions, error) {
+	challenge, err := service.newSession(userId, registrationCeremony)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := service.credentialRepo.FindByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreationOptions{
+		Challenge:    challenge,
+		RelyingParty: service.relyingParty,
+		User: UserEntity{
+			Id:          Base64URL(userId),
+			Name:        userId,
+			DisplayName: userId,
+		},
+		Parameters: []CredentialParameter{
+			{Type: "public-key", Alg: coseAlgES256},
+			{Type: "public-key", Alg: coseAlgRS256},
+		},
+		Timeout:            ceremonyTimeout.Milliseconds(),
+		ExcludeCredentials: descriptors(existing),
+		Attestation:        "direct",
+	}, nil
+}
+
+func (service *WebAuthnService) FinishRegistration(userId string, response *AttestationResponse) (*Credential, error) {
+	clientDataHash, err := service.verifyClientData(response.ClientDataJSON, registrationCeremony, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var object attestationObject
+	if err := cbor.Unmarshal(response.AttestationObject, &object); err != nil {
+		return nil, errors.New("cannot decode attestation object")
+	}
+
+	data, err := service.verifyAuthenticatorData(object.AuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.CredentialId == nil {
+		return nil, errors.New("missing attested credential data")
+	}
+
+	if _, _, err := parsePublicKey(data.PublicKey); err != nil {
+		return nil, err
+	}
+
+	if err := verifyAttestation(&object, data, clientDataHash); err != nil {
+		return nil, err
+	}
+
+	credential := &Credential{
+		Id:                data.CredentialId,
+		UserId:            userId,
+		PublicKey:         data.PublicKey,
+		SignCount:         data.SignCount,
+		AttestationFormat: object.Fmt,
+	}
+
+	if err := service.credentialRepo.Create(credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (service *WebAuthnService) BeginLogin(userId string) (*RequestOptions, error) {
+	challenge, err := service.newSession(userId, loginCeremony)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []CredentialDescriptor{}
+	if userId != "" {
+		credentials, err := service.credentialRepo.FindByUser(userId)
+		if err != nil {
+			return nil, err
+		}
+		allowed = descriptors(credentials)
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		RelyingPartyId:   service.relyingParty.Id,
+		Timeout:          ceremonyTimeout.Milliseconds(),
+		AllowCredentials: allowed,
+		UserVerification: "preferred",
+	}, nil
+}
+
+func (service *WebAuthnService) FinishLogin(response *AssertionResponse) (*Assertion, error) {
+	credential, err := service.credentialRepo.FindById(response.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.UserHandle) > 0 && string(response.UserHandle) != credential.UserId {
+		return nil, errors.New("user handle does not match credential")
+	}
+
+	clientDataHash, err := service.verifyClientData(response.ClientDataJSON, loginCeremony, credential.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := service.verifyAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	alg, publicKey, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := append(append([]byte{}, response.AuthenticatorData...), clientDataHash...)
+	if err := verifySignature(alg, publicKey, signed, response.Signature); err != nil {
+		return nil, err
+	}
+
+	if (data.SignCount != 0 || credential.SignCount != 0) && data.SignCount <= credential.SignCount {
+		return nil, errors.New("sign count did not increase")
+	}
+
+	credential.SignCount = data.SignCount
+	if err := service.credentialRepo.Update(credential); err != nil {
+		return nil, err
+	}
+
+	return &Assertion{
+		Credential:   credential,
+		UserVerified: data.UserVerified(),
+	}, nil
+}
+
+func (service *WebAuthnService) newSession(userId string, ceremony ceremony) (Base64URL, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	now := time.Now()
+	for pending, s := range service.sessions {
+		if !now.Before(s.Expires) {
+			delete(service.sessions, pending)
+		}
+	}
+
+	service.sessions[base64.RawURLEncoding.EncodeToString(challenge)] = session{
+		UserId:   userId,
+		Ceremony: ceremony,
+		Expires:  now.Add(ceremonyTimeout),
+	}
+	return challenge, nil
+}
+
+func (service *WebAuthnService) consumeSession(challenge string) (session, bool) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	s, ok := service.sessions[challenge]
+	delete(service.sessions, challenge)
+
+	return s, ok && time.Now().Before(s.Expires)
+}
+
+func (service *WebAuthnService) verifyClientData(raw []byte, ceremony ceremony, userId string) ([]byte, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, errors.New("cannot decode client data")
+	}
+
+	if data.Type != string(ceremony) {
+		return nil, errors.New("unexpected ceremony type")
+	}
+
+	if data.Origin != service.relyingParty.Origin {
+		return nil, errors.New("unexpected origin")
+	}
+
+	s, ok := service.consumeSession(data.Challenge)
+	if !ok || s.Ceremony != ceremony {
+		return nil, errors.New("unknown or expired challenge")
+	}
+
+	if s.UserId != "" && s.UserId != userId {
+		return nil, errors.New("challenge was issued for another user")
+	}
+
+	hash := sha256.Sum256(raw)
+	return hash[:], nil
+}
+
+func (service *WebAuthnService) verifyAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	data, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIdHash := sha256.Sum256([]byte(service.relyingParty.Id))
+	if subtle.ConstantTimeCompare(data.RpIdHash, rpIdHash[:]) != 1 {
+		return nil, errors.New("unexpected relying party")
+	}
+
+	if !data.UserPresent() {
+		return nil, errors.New("user not present")
+	}
+	return data, nil
+}
+
+func descriptors(credentials []*Credential) []CredentialDescriptor {
+	result := make([]CredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		result[i] = CredentialDescriptor{
+			Type: "public-key",
+			Id:   Base64URL(append([]byte{}, credential.Id...)),
+		}
+	}
+	return result
+}
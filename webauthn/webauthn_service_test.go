@@ -0,0 +1,197 @@
+package webauthn_test
+
+import (
+	"crypto/elliptic"
+	"testing"
+
+	. "github.com/Untanky/go-id/webauthn"
+	"github.com/Untanky/go-id/webauthn/webauthntest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	rpId   = "id.example.com"
+	origin = "https://id.example.com"
+)
+
+type WebAuthnServiceTestSuite struct {
+	suite.Suite
+	credentialRepo CredentialRepository
+	service        *WebAuthnService
+}
+
+func (suite *WebAuthnServiceTestSuite) SetupTest() {
+	suite.credentialRepo = new(MemoryCredentialRepository)
+	suite.service = new(WebAuthnService)
+	suite.service.Init(RelyingParty{Id: rpId, Name: "go-id", Origin: origin}, suite.credentialRepo)
+}
+
+func (suite *WebAuthnServiceTestSuite) register(authenticator *webauthntest.SoftwareAuthenticator, userId string) (*Credential, error) {
+	options, err := suite.service.BeginRegistration(userId)
+	assert.Nil(suite.T(), err)
+
+	return suite.service.FinishRegistration(userId, authenticator.Create(options))
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_WithNoneAttestation() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+
+	credential, err := suite.register(authenticator, "abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "abc", credential.UserId)
+	assert.Equal(suite.T(), NoneAttestation, credential.AttestationFormat)
+	credentials, _ := suite.credentialRepo.FindByUser("abc")
+	assert.Len(suite.T(), credentials, 1)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_WithPackedSelfAttestation() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, PackedAttestation)
+
+	credential, err := suite.register(authenticator, "abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), PackedAttestation, credential.AttestationFormat)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_WithPackedCertificateAttestation() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, PackedAttestation)
+	authenticator.UseAttestationCertificate(elliptic.P256())
+
+	credential, err := suite.register(authenticator, "abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), PackedAttestation, credential.AttestationFormat)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_ErrorWhenCertificateDoesNotMatchAlgorithm() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, PackedAttestation)
+	authenticator.UseAttestationCertificate(elliptic.P384())
+
+	_, err := suite.register(authenticator, "abc")
+
+	assert.ErrorContains(suite.T(), err, "does not match algorithm")
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_ExcludeExistingCredentials() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	suite.register(authenticator, "abc")
+
+	options, err := suite.service.BeginRegistration("abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), options.ExcludeCredentials, 1)
+	assert.Equal(suite.T(), Base64URL(authenticator.CredentialId), options.ExcludeCredentials[0].Id)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_ErrorWhenOriginDoesNotMatch() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, "https://evil.example.com", NoneAttestation)
+
+	credential, err := suite.register(authenticator, "abc")
+
+	assert.ErrorContains(suite.T(), err, "unexpected origin")
+	assert.Nil(suite.T(), credential)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_ErrorWhenRelyingPartyDoesNotMatch() {
+	authenticator := webauthntest.NewSoftwareAuthenticator("evil.example.com", origin, NoneAttestation)
+
+	credential, err := suite.register(authenticator, "abc")
+
+	assert.ErrorContains(suite.T(), err, "unexpected relying party")
+	assert.Nil(suite.T(), credential)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_ErrorWhenChallengeReused() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	options, _ := suite.service.BeginRegistration("abc")
+	response := authenticator.Create(options)
+
+	_, err := suite.service.FinishRegistration("abc", response)
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.service.FinishRegistration("abc", response)
+	assert.ErrorContains(suite.T(), err, "unknown or expired challenge")
+}
+
+func (suite *WebAuthnServiceTestSuite) TestRegistration_ErrorWhenChallengeIssuedForOtherUser() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	options, _ := suite.service.BeginRegistration("abc")
+
+	credential, err := suite.service.FinishRegistration("def", authenticator.Create(options))
+
+	assert.ErrorContains(suite.T(), err, "another user")
+	assert.Nil(suite.T(), credential)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestLogin_PasswordlessWithDiscoverableCredential() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, PackedAttestation)
+	suite.register(authenticator, "abc")
+
+	options, err := suite.service.BeginLogin("")
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), options.AllowCredentials, 0)
+
+	assertion, err := suite.service.FinishLogin(authenticator.Get(options))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "abc", assertion.Credential.UserId)
+	assert.True(suite.T(), assertion.UserVerified)
+	assert.Equal(suite.T(), uint32(1), assertion.Credential.SignCount)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestLogin_AllowCredentialsOfUser() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	suite.register(authenticator, "abc")
+
+	options, err := suite.service.BeginLogin("abc")
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), options.AllowCredentials, 1)
+	assert.Equal(suite.T(), rpId, options.RelyingPartyId)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestLogin_ErrorWhenSignCountDoesNotIncrease() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	suite.register(authenticator, "abc")
+
+	options, _ := suite.service.BeginLogin("abc")
+	_, err := suite.service.FinishLogin(authenticator.Get(options))
+	assert.Nil(suite.T(), err)
+
+	authenticator.SignCount--
+	options, _ = suite.service.BeginLogin("abc")
+	assertion, err := suite.service.FinishLogin(authenticator.Get(options))
+
+	assert.ErrorContains(suite.T(), err, "sign count did not increase")
+	assert.Nil(suite.T(), assertion)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestLogin_ErrorWhenSignedByOtherKey() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	suite.register(authenticator, "abc")
+	impostor := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+	impostor.CredentialId = authenticator.CredentialId
+	impostor.UserId = "abc"
+
+	options, _ := suite.service.BeginLogin("")
+	assertion, err := suite.service.FinishLogin(impostor.Get(options))
+
+	assert.ErrorContains(suite.T(), err, "invalid signature")
+	assert.Nil(suite.T(), assertion)
+}
+
+func (suite *WebAuthnServiceTestSuite) TestLogin_ErrorWhenCredentialUnknown() {
+	authenticator := webauthntest.NewSoftwareAuthenticator(rpId, origin, NoneAttestation)
+
+	options, _ := suite.service.BeginLogin("")
+	assertion, err := suite.service.FinishLogin(authenticator.Get(options))
+
+	assert.ErrorContains(suite.T(), err, "no credential found")
+	assert.Nil(suite.T(), assertion)
+}
+
+func TestWebAuthnService(t *testing.T) {
+	suite.Run(t, new(WebAuthnServiceTestSuite))
+}
@@ -0,0 +1,159 @@
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/Untanky/go-id/webauthn"
+	"github.com/fxamacker/cbor/v2"
+)
+
+type SoftwareAuthenticator struct {
+	RpId                 string
+	Origin               string
+	CredentialId         []byte
+	UserId               string
+	Key                  *ecdsa.PrivateKey
+	SignCount            uint32
+	Format               string
+	SkipUserVerification bool
+	AttestationKey       *ecdsa.PrivateKey
+	Certificate          []byte
+}
+
+func NewSoftwareAuthenticator(rpId string, origin string, format string) *SoftwareAuthenticator {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	credentialId := make([]byte, 16)
+	rand.Read(credentialId)
+
+	return &SoftwareAuthenticator{
+		RpId:         rpId,
+		Origin:       origin,
+		CredentialId: credentialId,
+		Key:          key,
+		Format:       format,
+	}
+}
+
+func (authenticator *SoftwareAuthenticator) UseAttestationCertificate(curve elliptic.Curve) {
+	key, _ := ecdsa.GenerateKey(curve, rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Software Authenticator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	authenticator.AttestationKey = key
+	authenticator.Certificate = certificate
+}
+
+func (authenticator *SoftwareAuthenticator) publicKey() []byte {
+	key, _ := cbor.Marshal(map[int]interface{}{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: authenticator.Key.X.FillBytes(make([]byte, 32)),
+		-3: authenticator.Key.Y.FillBytes(make([]byte, 32)),
+	})
+	return key
+}
+
+func (authenticator *SoftwareAuthenticator) authenticatorData(attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(authenticator.RpId))
+	data := append([]byte{}, rpIdHash[:]...)
+
+	flags := byte(0x01 | 0x04)
+	if authenticator.SkipUserVerification {
+		flags &^= 0x04
+	}
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	signCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(signCount, authenticator.SignCount)
+	data = append(data, signCount...)
+
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		idLength := make([]byte, 2)
+		binary.BigEndian.PutUint16(idLength, uint16(len(authenticator.CredentialId)))
+		data = append(data, idLength...)
+		data = append(data, authenticator.CredentialId...)
+		data = append(data, authenticator.publicKey()...)
+	}
+	return data
+}
+
+func (authenticator *SoftwareAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    authenticator.Origin,
+	})
+	return data
+}
+
+func (authenticator *SoftwareAuthenticator) sign(data []byte, clientData []byte) []byte {
+	return signWith(authenticator.Key, data, clientData)
+}
+
+func signWith(key *ecdsa.PrivateKey, data []byte, clientData []byte) []byte {
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, data...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	return signature
+}
+
+func (authenticator *SoftwareAuthenticator) Create(options *webauthn.CreationOptions) *webauthn.AttestationResponse {
+	authenticator.UserId = string(options.User.Id)
+	clientData := authenticator.clientData("webauthn.create", options.Challenge)
+	authData := authenticator.authenticatorData(true)
+
+	statement := map[string]interface{}{}
+	if authenticator.Format == webauthn.PackedAttestation {
+		statement["alg"] = -7
+		statement["sig"] = authenticator.sign(authData, clientData)
+		if authenticator.Certificate != nil {
+			statement["sig"] = signWith(authenticator.AttestationKey, authData, clientData)
+			statement["x5c"] = [][]byte{authenticator.Certificate}
+		}
+	}
+
+	object, _ := cbor.Marshal(map[string]interface{}{
+		"fmt":      authenticator.Format,
+		"authData": authData,
+		"attStmt":  statement,
+	})
+
+	return &webauthn.AttestationResponse{
+		Id:                authenticator.CredentialId,
+		ClientDataJSON:    clientData,
+		AttestationObject: object,
+	}
+}
+
+func (authenticator *SoftwareAuthenticator) Get(options *webauthn.RequestOptions) *webauthn.AssertionResponse {
+	authenticator.SignCount++
+	clientData := authenticator.clientData("webauthn.get", options.Challenge)
+	authData := authenticator.authenticatorData(false)
+
+	return &webauthn.AssertionResponse{
+		Id:                authenticator.CredentialId,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         authenticator.sign(authData, clientData),
+		UserHandle:        webauthn.Base64URL(authenticator.UserId),
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/webauthn"
+	"github.com/gin-gonic/gin"
+)
+
+type WebAuthnController struct {
+	authController      *AuthController
+	webAuthnService     *webauthn.WebAuthnService
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+}
+
+func (controller *WebAuthnController) Init(
+	authController *AuthController,
+	webAuthnService *webauthn.WebAuthnService,
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload],
+) {
+	controller.authController = authController
+	controller.webAuthnService = webAuthnService
+	controller.sessionTokenService = sessionTokenService
+}
+
+func (controller *WebAuthnController) BeginRegistration(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	options, err := controller.webAuthnService.BeginRegistration(payload.Sub)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "registration could not be started",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
+
+func (controller *WebAuthnController) FinishRegistration(c *gin.Context) {
+	payload, shouldReturn := authenticateSession(c, controller.sessionTokenService)
+	if shouldReturn {
+		return
+	}
+
+	var response webauthn.AttestationResponse
+	if err := c.ShouldBindJSON(&response); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid attestation response",
+		})
+		return
+	}
+
+	credential, err := controller.webAuthnService.FinishRegistration(payload.Sub, &response)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "credential could not be registered",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"credentialId": webauthn.Base64URL(credential.Id),
+	})
+}
+
+func (controller *WebAuthnController) BeginLogin(c *gin.Context) {
+	options, err := controller.webAuthnService.BeginLogin(c.Query("identifier"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "login could not be started",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
+
+func (controller *WebAuthnController) FinishLogin(c *gin.Context) {
+	var response webauthn.AssertionResponse
+	if err := c.ShouldBindJSON(&response); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid assertion response",
+		})
+		return
+	}
+
+	assertion, err := controller.webAuthnService.FinishLogin(&response)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+		})
+		return
+	}
+
+	authController := controller.authController
+	loggedInUser, err := authController.authService.LoginPasswordless(c.Request.Context(), assertion.Credential.UserId)
+	if err != nil {
+		respondAuthFailure(c, err)
+		return
+	}
+
+	if !assertion.UserVerified {
+		authController.completeLogin(c, loggedInUser.Identifier, []string{"hwk"})
+		return
+	}
+
+	amr := []string{"hwk", "user", "mfa"}
+	issueRefreshToken(c, authController.authService, authController.refreshTokenService, loggedInUser.Identifier, amr)
+}
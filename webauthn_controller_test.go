@@ -0,0 +1,232 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/Untanky/go-id"
+	"github.com/Untanky/go-id/auth"
+	"github.com/Untanky/go-id/jwt"
+	"github.com/Untanky/go-id/mfa"
+	"github.com/Untanky/go-id/secret"
+	"github.com/Untanky/go-id/totp"
+	"github.com/Untanky/go-id/user"
+	"github.com/Untanky/go-id/webauthn"
+	"github.com/Untanky/go-id/webauthn/webauthntest"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebAuthnControllerSuite struct {
+	suite.Suite
+
+	sessionTokenService auth.TokenService[*auth.RefreshTokenPayload]
+	authenticator       *webauthntest.SoftwareAuthenticator
+	authController      *AuthController
+	controller          *WebAuthnController
+}
+
+func (suite *WebAuthnControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := new(user.MemoryUserRepository)
+	userRepo.Create(context.Background(), &user.User{Identifier: "user", Status: user.Active})
+	userRepo.Create(context.Background(), &user.User{Identifier: "inactiveUser", Status: user.PendingVerification})
+	userRepo.Create(context.Background(), &user.User{Identifier: "mfaUser", Status: user.Active})
+	authService := new(auth.LoginService)
+	authService.Init(userRepo, auth.NewArgon2Encrypter())
+
+	jwtService := new(jwt.JwtService[secret.SecretString])
+	jwtService.Init(jwt.HS256, secret.NewSecretValue("secret"))
+	tokenService := new(auth.RefreshTokenService)
+	tokenService.Init(jwtService)
+	suite.sessionTokenService = tokenService
+	challengeTokenService := new(auth.ChallengeTokenService)
+	challengeTokenService.Init(jwtService)
+
+	otpService := new(totp.OtpService)
+	otpService.Init(30)
+	factorRepo := new(mfa.MemoryFactorRepository)
+	factorRepo.Create(&mfa.Factor{
+		Identifier: "totp",
+		UserId:     "mfaUser",
+		Challenge: totp.Challenge{
+			ChallengeType: totp.MFA_CHALLENGE,
+			Secret:        secret.NewSecretValue("secret"),
+		},
+	})
+	mfaService := new(mfa.MfaService)
+	mfaService.Init(factorRepo, new(mfa.MemoryRecoveryCodeRepository), otpService, noopSender{})
+
+	suite.authController = new(AuthController)
+	suite.authController.Init(authService, mfaService, nil, nil, tokenService, challengeTokenService)
+
+	webAuthnService := new(webauthn.WebAuthnService)
+	webAuthnService.Init(webauthn.RelyingParty{
+		Id:     "id.example.com",
+		Name:   "go-id",
+		Origin: "https://id.example.com",
+	}, new(webauthn.MemoryCredentialRepository))
+
+	suite.authenticator = webauthntest.NewSoftwareAuthenticator("id.example.com", "https://id.example.com", webauthn.PackedAttestation)
+
+	suite.controller = new(WebAuthnController)
+	suite.controller.Init(suite.authController, webAuthnService, tokenService)
+}
+
+func (suite *WebAuthnControllerSuite) authorize(context *gin.Context, sub string) {
+	token, _ := suite.sessionTokenService.Create(context.Request.Context(), &auth.RefreshTokenPayload{Sid: "123", Sub: sub})
+	context.Request.Header.Set(AuthorizationHeader, "Bearer "+string(token))
+}
+
+func (suite *WebAuthnControllerSuite) register(sub string) {
+	w, context := buildContext()
+	suite.authorize(context, sub)
+	suite.controller.BeginRegistration(context)
+
+	var options struct {
+		PublicKey webauthn.CreationOptions `json:"publicKey"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&options)
+	response, _ := json.Marshal(suite.authenticator.Create(&options.PublicKey))
+
+	w, context = buildContext()
+	suite.authorize(context, sub)
+	context.Request.Body = io.NopCloser(bytes.NewReader(response))
+	suite.controller.FinishRegistration(context)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Result().StatusCode)
+}
+
+func (suite *WebAuthnControllerSuite) login() *http.Response {
+	w, context := buildContext()
+	context.Request.URL = &url.URL{}
+	suite.controller.BeginLogin(context)
+
+	var options struct {
+		PublicKey webauthn.RequestOptions `json:"publicKey"`
+	}
+	json.NewDecoder(w.Result().Body).Decode(&options)
+	response, _ := json.Marshal(suite.authenticator.Get(&options.PublicKey))
+
+	w, context = buildContext()
+	context.Request.Body = io.NopCloser(bytes.NewReader(response))
+	suite.controller.FinishLogin(context)
+
+	return w.Result()
+}
+
+func (suite *WebAuthnControllerSuite) TestLogin_IssueRefreshTokenForPasskey() {
+	suite.register("user")
+
+	result := suite.login()
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	body, _ := io.ReadAll(result.Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	token := jwt.Jwt(regex.FindStringSubmatch(string(body))[1])
+	assert.Nil(suite.T(), token.Validate(jwt.HS256, "secret"))
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), "user", payload["sub"])
+	assert.Equal(suite.T(), []interface{}{"hwk", "user", "mfa"}, payload["amr"])
+}
+
+func (suite *WebAuthnControllerSuite) TestLogin_IssueSingleFactorTokenWithoutUserVerification() {
+	suite.authenticator.SkipUserVerification = true
+	suite.register("user")
+
+	result := suite.login()
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	body, _ := io.ReadAll(result.Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	token := jwt.Jwt(regex.FindStringSubmatch(string(body))[1])
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), []interface{}{"hwk"}, payload["amr"])
+}
+
+func (suite *WebAuthnControllerSuite) TestLogin_RequireMfaWithoutUserVerification() {
+	suite.authenticator.SkipUserVerification = true
+	suite.register("mfaUser")
+
+	result := suite.login()
+
+	assert.Equal(suite.T(), 202, result.StatusCode)
+	var body map[string]string
+	json.NewDecoder(result.Body).Decode(&body)
+	assert.NotContains(suite.T(), body, "refreshToken")
+
+	w, context := buildContext()
+	context.Request.Header.Set(ChallengeHeader, body["mfaToken"])
+	context.Request.Body = io.NopCloser(strings.NewReader(totp.GenerateTotp("secret", 30)))
+	suite.authController.Mfa(context)
+
+	assert.Equal(suite.T(), 200, w.Result().StatusCode)
+	response, _ := io.ReadAll(w.Result().Body)
+	regex := regexp.MustCompile(`"refreshToken"[:]"(?P<Token>.*)"`)
+	token := jwt.Jwt(regex.FindStringSubmatch(string(response))[1])
+	payload, _ := token.Payload()
+	assert.Equal(suite.T(), "mfaUser", payload["sub"])
+	assert.Equal(suite.T(), []interface{}{"hwk", "mfa", "otp"}, payload["amr"])
+}
+
+func (suite *WebAuthnControllerSuite) TestLogin_SkipMfaWithUserVerification() {
+	suite.register("mfaUser")
+
+	result := suite.login()
+
+	assert.Equal(suite.T(), 200, result.StatusCode)
+	body, _ := io.ReadAll(result.Body)
+	assert.Contains(suite.T(), string(body), "refreshToken")
+}
+
+func (suite *WebAuthnControllerSuite) TestLogin_FailForInactiveUser() {
+	suite.register("inactiveUser")
+
+	result := suite.login()
+
+	assert.Equal(suite.T(), 403, result.StatusCode)
+	assert.Equal(suite.T(), ProblemContentType, result.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(result.Body)
+	assert.Contains(suite.T(), string(body), "/problems/pending-verification")
+}
+
+func (suite *WebAuthnControllerSuite) TestLogin_FailForUnknownCredential() {
+	result := suite.login()
+
+	assert.Equal(suite.T(), 401, result.StatusCode)
+	body, _ := io.ReadAll(result.Body)
+	assert.Contains(suite.T(), string(body), "unauthorized")
+}
+
+func (suite *WebAuthnControllerSuite) TestFinishRegistration_FailWithInvalidBody() {
+	w, context := buildContext()
+	suite.authorize(context, "user")
+	context.Request.Body = io.NopCloser(bytes.NewReader([]byte("{")))
+
+	suite.controller.FinishRegistration(context)
+
+	assert.Equal(suite.T(), 400, w.Result().StatusCode)
+	body, _ := io.ReadAll(w.Result().Body)
+	assert.Contains(suite.T(), string(body), "invalid attestation response")
+}
+
+func (suite *WebAuthnControllerSuite) TestBeginRegistration_FailWithoutBearerToken() {
+	w, context := buildContext()
+
+	suite.controller.BeginRegistration(context)
+
+	assert.Equal(suite.T(), 401, w.Result().StatusCode)
+}
+
+func TestWebAuthnController(t *testing.T) {
+	suite.Run(t, new(WebAuthnControllerSuite))
+}